@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// envPrefix prefixes the environment variable for every flag: --state-file reads ST_MIGRATE_STATE_FILE.
+const envPrefix = "ST_MIGRATE_"
+
+// envVarName returns the environment variable backing a flag.
+func envVarName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// commandLineOnly lists the flags that acknowledge a destructive or protected operation. They
+// are never read from the environment, so an exported variable cannot acknowledge an
+// operation run in the wrong terminal.
+var commandLineOnly = map[string]bool{
+	"yes":             true,
+	"confirm-env":     true,
+	"allow-protected": true,
+	"force":           true,
+}
+
+// applySettings fills flags the user did not set, in precedence order:
+// command-line flag > ST_MIGRATE_* environment variable > selected config environment >
+// top-level config file values > built-in defaults.
+func applySettings(cmd *cobra.Command, opts *cliOpts) error {
+	flags := cmd.Flags()
+	// --config and --env decide which settings to load, so resolve them from the environment first.
+	for _, name := range []string{"config", "env"} {
+		if err := applyEnv(flags.Lookup(name)); err != nil {
+			return err
+		}
+	}
+
+	settings, err := loadSettings(opts)
+	if err != nil {
+		return err
+	}
+	opts.protected = settings.Protected
+	opts.protection = settings.Protection
+	fromConfig := map[string]string{
+		"source":              settings.Source,
+		"database":            settings.Database,
+		"state-file":          settings.StateFile,
+		"migrations-table":    settings.MigrationsTable,
+		"supertokens-uri":     settings.SuperTokens.ConnectionURI,
+		"supertokens-api-key": settings.SuperTokens.APIKey,
+	}
+	for name, val := range fromConfig {
+		f := flags.Lookup(name)
+		if f == nil || f.Changed || val == "" {
+			continue
+		}
+		if err := f.Value.Set(val); err != nil {
+			return fmt.Errorf("config value for %s: %w", name, err)
+		}
+	}
+
+	var envErr error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if envErr == nil && f.Name != "config" && f.Name != "env" && f.Name != "var" && !commandLineOnly[f.Name] {
+			envErr = applyEnv(f)
+		}
+	})
+	if envErr != nil {
+		return envErr
+	}
+	opts.strictVars = opts.strictVars || settings.StrictVars
+	opts.vars, err = resolveVars(settings.Vars, os.Environ(), opts.varFlags)
+	return err
+}
+
+// varEnvPrefix prefixes environment variables that set template variables: ST_MIGRATE_VAR_tenant sets ${tenant}.
+const varEnvPrefix = envPrefix + "VAR_"
+
+// resolveVars merges template variables in precedence order: --var > ST_MIGRATE_VAR_*
+// environment variables > config file.
+func resolveVars(fromConfig map[string]string, environ, flags []string) (map[string]string, error) {
+	vars := make(map[string]string, len(fromConfig))
+	for k, v := range fromConfig {
+		vars[k] = v
+	}
+	for _, kv := range environ {
+		if name, val, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(name, varEnvPrefix) && len(name) > len(varEnvPrefix) {
+			vars[strings.TrimPrefix(name, varEnvPrefix)] = val
+		}
+	}
+	for _, kv := range flags {
+		name, val, ok := strings.Cut(kv, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q: want key=value", kv)
+		}
+		vars[name] = val
+	}
+	return vars, nil
+}
+
+// applyEnv sets an unset flag from its ST_MIGRATE_* environment variable.
+func applyEnv(f *pflag.Flag) error {
+	if f == nil || f.Changed || f.Name == "help" {
+		return nil
+	}
+	val, ok := os.LookupEnv(envVarName(f.Name))
+	if !ok {
+		return nil
+	}
+	if err := f.Value.Set(val); err != nil {
+		return fmt.Errorf("invalid %s: %w", envVarName(f.Name), err)
+	}
+	return nil
+}
+
+// loadSettings reads the project config file (explicit or discovered) and resolves the environment.
+// A missing file is only an error when --config or --env asked for one.
+func loadSettings(opts *cliOpts) (stmigrate.Settings, error) {
+	path := opts.configPath
+	if path == "" {
+		found, err := stmigrate.FindConfigFile(".")
+		switch {
+		case errors.Is(err, stmigrate.ErrConfigNotFound):
+			if opts.env != "" {
+				return stmigrate.Settings{}, fmt.Errorf("--env %s: %w", opts.env, err)
+			}
+			return stmigrate.Settings{}, nil
+		case err != nil:
+			return stmigrate.Settings{}, err
+		}
+		path = found
+	}
+	project, err := stmigrate.LoadProjectConfig(path)
+	if err != nil {
+		return stmigrate.Settings{}, err
+	}
+	return project.Resolve(opts.env)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate"
+	"github.com/spf13/cobra"
+)
+
+// errNotConfirmed is returned when a destructive operation was not confirmed.
+var errNotConfirmed = errors.New("destructive operation not confirmed")
+
+// stdinIsTerminal reports whether the CLI can prompt; swapped in tests.
+var stdinIsTerminal = func() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// operation is a state-changing command: plan previews it for confirmation and run executes it.
+type operation struct {
+	name string
+	// plan returns the simulated outcome; a nil plan means there is nothing to confirm.
+	plan func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.Plan, error)
+	// alwaysDestructive marks operations that roll back even when the plan looks additive (redo).
+	alwaysDestructive bool
+	run               func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.RunReport, error)
+}
+
+// confirm asks for confirmation when the planned operation deletes roles, removes permissions
+// or rolls back migrations. Without a terminal only protected environments refuse to run
+// unconfirmed. Dry runs change nothing and are never prompted.
+func confirm(cmd *cobra.Command, opts *cliOpts, op operation, plan *stmigrate.Plan) error {
+	if opts.dryRun || !(op.alwaysDestructive || isDestructive(plan)) {
+		return nil
+	}
+	logger := getLogger(opts)
+	env := envName(opts)
+	if opts.protected && opts.confirmEnv != env {
+		logger.Warn("refusing destructive operation on protected environment", slog.String("operation", op.name), slog.String("env", env))
+		return fmt.Errorf("%w: environment %q is protected; re-run with --confirm-env %s", errNotConfirmed, env, env)
+	}
+	if opts.yes {
+		return nil
+	}
+	if !stdinIsTerminal() {
+		if !opts.protected {
+			logger.Warn("running destructive operation without a terminal to confirm it", slog.String("operation", op.name))
+			return nil
+		}
+		logger.Warn("refusing destructive operation without confirmation", slog.String("operation", op.name), slog.String("env", env))
+		return fmt.Errorf("%w: %s is destructive; re-run with --yes (or --dry-run to preview)", errNotConfirmed, op.name)
+	}
+
+	w := cmd.ErrOrStderr()
+	if plan != nil {
+		printPlan(w, plan)
+	}
+	expected := "yes"
+	if opts.env != "" {
+		expected = opts.env
+	}
+	fmt.Fprintf(w, "\n%s is destructive. Type %q to continue: ", op.name, expected)
+	answer, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("read confirmation: %w", err)
+	}
+	if strings.TrimSpace(answer) != expected {
+		return fmt.Errorf("%w: %s aborted", errNotConfirmed, op.name)
+	}
+	return nil
+}
+
+// isDestructive reports whether a plan rolls back, deletes or renames roles, removes (or may remove, via
+// an exact set) permissions or unassigns users, directly or through a seed file.
+func isDestructive(plan *stmigrate.Plan) bool {
+	if plan == nil {
+		return false
+	}
+	for _, step := range plan.Steps {
+		if step.Direction == stmigrate.DirectionDown {
+			return true
+		}
+		for _, action := range step.Actions {
+			if action.Ensure == "absent" || len(action.Remove) > 0 || action.HasSet() || len(action.Unassign) > 0 || (action.Seed != nil && action.Seed.Unassign) || action.Rename != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// envName is the environment a protected-environment confirmation must name.
+func envName(opts *cliOpts) string {
+	if opts.env == "" {
+		return "default"
+	}
+	return opts.env
+}
+
+// planTo previews migrating to target.
+func planTo(target uint) func(context.Context, *stmigrate.Runner) (*stmigrate.Plan, error) {
+	return func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.Plan, error) {
+		return runner.Plan(ctx, &target)
+	}
+}
+
+// planUpSteps previews applying the next steps pending migrations.
+func planUpSteps(steps int) func(context.Context, *stmigrate.Runner) (*stmigrate.Plan, error) {
+	return func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.Plan, error) {
+		status, err := runner.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pending := status.Pending()
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		target := pending[min(steps, len(pending))-1]
+		return runner.Plan(ctx, &target)
+	}
+}
+
+// planDownSteps previews rolling back steps applied migrations.
+func planDownSteps(steps int) func(context.Context, *stmigrate.Runner) (*stmigrate.Plan, error) {
+	return func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.Plan, error) {
+		status, err := runner.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		applied := make([]uint, 0)
+		for _, m := range status.Migrations {
+			if m.State == stmigrate.StateApplied {
+				applied = append(applied, m.Version)
+			}
+		}
+		var target uint
+		if len(applied) > steps {
+			target = applied[len(applied)-steps-1]
+		}
+		return runner.Plan(ctx, &target)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/golang-migrate/migrate/v4/database"
@@ -17,8 +18,14 @@ type stubDB struct {
 	locked  bool
 }
 
-func (s *stubDB) Open(url string) (database.Driver, error) { return &stubDB{}, nil }
-func (s *stubDB) Close() error                             { return nil }
+// stubOpenedURL records the URL the stub driver was last opened with.
+var stubOpenedURL string
+
+func (s *stubDB) Open(url string) (database.Driver, error) {
+	stubOpenedURL = url
+	return &stubDB{}, nil
+}
+func (s *stubDB) Close() error { return nil }
 func (s *stubDB) Lock() error {
 	if s.locked {
 		return database.ErrLocked
@@ -58,3 +65,15 @@ func TestCLIDatabaseFlagUsesDriver(t *testing.T) {
 	_, err = os.Stat(stateFile)
 	require.True(t, errors.Is(err, os.ErrNotExist))
 }
+
+func TestCLIDatabasePassesMigrationsTable(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".st-migrate.yaml"), []byte("source: file://"+dir+"\ndatabase: stub://local\nmigrations_table: auth_roles_state\n"), 0o644))
+	t.Chdir(dir)
+
+	require.NoError(t, run([]string{"status"}, io.Discard, io.Discard))
+	require.Equal(t, "stub://local?x-migrations-table=auth_roles_state", stubOpenedURL)
+
+	require.NoError(t, run([]string{"--migrations-table", "other", "status"}, io.Discard, io.Discard))
+	require.Equal(t, "stub://local?x-migrations-table=other", stubOpenedURL)
+}
@@ -1,16 +1,37 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
+
+	stmigrate "github.com/BeardedWonderDev/st-migrate-go/st-migrate"
 )
 
 var exit = os.Exit
 
+// Process exit codes, one per class of failure. They are part of the CLI contract
+// (see the exit codes section in the README) and must not be renumbered.
+const (
+	exitError           = 1
+	exitDirty           = 2
+	exitLocked          = 3
+	exitVersionNotFound = 4
+	exitInvalidSchema   = 5
+	exitExecutor        = 6
+	exitPending         = 7
+	exitDrift           = 8
+	exitRoundTrip       = 9
+	exitProtected       = 10
+	exitRoleInUse       = 11
+)
+
 func run(args []string, stdout io.Writer, stderr io.Writer) error {
 	root := newRootCmd(stdout)
 	root.SetArgs(args)
+	root.SetOut(stdout)
+	root.SetErr(stderr)
 	if err := root.Execute(); err != nil {
 		fmt.Fprintf(stderr, "error: %v\n", err)
 		return err
@@ -18,8 +39,36 @@ func run(args []string, stdout io.Writer, stderr io.Writer) error {
 	return nil
 }
 
+// exitCode maps an error to the process exit code for its class.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, stmigrate.ErrDirty):
+		return exitDirty
+	case errors.Is(err, stmigrate.ErrLocked):
+		return exitLocked
+	case errors.Is(err, stmigrate.ErrVersionNotFound):
+		return exitVersionNotFound
+	case errors.Is(err, stmigrate.ErrParse), errors.Is(err, stmigrate.ErrUnsupportedSchema):
+		return exitInvalidSchema
+	case errors.Is(err, stmigrate.ErrProtected):
+		return exitProtected
+	case errors.Is(err, stmigrate.ErrRoleInUse):
+		return exitRoleInUse
+	case errors.Is(err, stmigrate.ErrExecutor):
+		return exitExecutor
+	case errors.Is(err, errPendingMigrations):
+		return exitPending
+	case errors.Is(err, errDriftDetected):
+		return exitDrift
+	case errors.Is(err, errRoundTripFailed):
+		return exitRoundTrip
+	default:
+		return exitError
+	}
+}
+
 func main() {
 	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
-		exit(1)
+		exit(exitCode(err))
 	}
 }
@@ -2,12 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
 	stmigrate "github.com/BeardedWonderDev/st-migrate-go/st-migrate"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	filestore "github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/file"
 	"github.com/stretchr/testify/require"
 )
 
@@ -66,7 +72,7 @@ func TestCLIDownAndDryRun(t *testing.T) {
 
 	out.Reset()
 	cmd = newRootCmd(&out)
-	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "down", "1"})
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "down", "1", "--yes"})
 	require.NoError(t, cmd.Execute())
 
 	out.Reset()
@@ -80,6 +86,15 @@ func TestCLIDownAndDryRun(t *testing.T) {
 	cmd = newRootCmd(&out)
 	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "--dry-run", "up"})
 	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "resulting state:\n  app:admin: [app:read app:write]\n  app:support: [app:read]\n  app:user: [app:read]\n")
+
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "--output", "json", "--dry-run", "up"})
+	require.NoError(t, cmd.Execute())
+	var report stmigrate.RunReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Equal(t, map[string][]string{"app:admin": {"app:read", "app:write"}, "app:support": {"app:read"}, "app:user": {"app:read"}}, report.Result)
 
 	// version should remain at 1 after dry-run
 	out.Reset()
@@ -120,7 +135,7 @@ func TestCLIMigrateCommandUpAndDown(t *testing.T) {
 
 	out.Reset()
 	cmd = newRootCmd(&out)
-	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "migrate", "1"})
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "migrate", "1", "--yes"})
 	require.NoError(t, cmd.Execute())
 
 	out.Reset()
@@ -142,7 +157,8 @@ func TestRunStatusSuccess(t *testing.T) {
 	err := run([]string{"--source", source, "--state-file", stateFile, "status"}, &out, &errOut)
 	require.NoError(t, err)
 	require.Contains(t, out.String(), "current version: 0")
-	require.Empty(t, errOut.String())
+	require.NotContains(t, out.String(), "level=INFO")
+	require.Contains(t, errOut.String(), `msg="command: status"`)
 }
 
 func TestMainExitsOnError(t *testing.T) {
@@ -182,3 +198,658 @@ func TestCLIMigrateRejectsInvalidTarget(t *testing.T) {
 	cmd.SetArgs([]string{"migrate", "nope"})
 	require.Error(t, cmd.Execute())
 }
+
+func TestCLIDriftReportsAndFails(t *testing.T) {
+	exec := executor.NewMock()
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return exec })
+
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+
+	var out bytes.Buffer
+	cmd := newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "up"})
+	require.NoError(t, cmd.Execute())
+
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "drift"})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "drift: none")
+
+	exec.Live["app:admin"] = append(exec.Live["app:admin"], "app:delete")
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "--output", "json", "drift"})
+	require.ErrorIs(t, cmd.Execute(), errDriftDetected)
+	require.Contains(t, out.String(), `"extra_permissions"`)
+	require.Contains(t, out.String(), `"app:delete"`)
+}
+
+func TestCLICreateFromDrift(t *testing.T) {
+	exec := executor.NewMock()
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return exec })
+
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	migrations := filepath.Join(tmpDir, "migrations")
+	require.NoError(t, os.MkdirAll(migrations, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(migrations, "0001_roles.up.yaml"), []byte("version: 1\nactions:\n  - role: app:admin\n    add: [app:read]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(migrations, "0001_roles.down.yaml"), []byte("version: 1\nactions:\n  - role: app:admin\n    ensure: absent\n"), 0o644))
+	source := "file://" + migrations
+
+	var out bytes.Buffer
+	cmd := newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "up"})
+	require.NoError(t, cmd.Execute())
+
+	exec.Live["app:admin"] = append(exec.Live["app:admin"], "app:write")
+
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "create", "--from-drift", "adopt dashboard"})
+	require.NoError(t, cmd.Execute())
+
+	up, err := os.ReadFile(filepath.Join(migrations, "0002_adopt_dashboard.up.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(up), "app:write")
+	down, err := os.ReadFile(filepath.Join(migrations, "0002_adopt_dashboard.down.yaml"))
+	require.NoError(t, err)
+	require.Contains(t, string(down), "remove:")
+
+	// applying the adopted migration leaves no drift behind
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "up"})
+	require.NoError(t, cmd.Execute())
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "drift"})
+	require.NoError(t, cmd.Execute())
+}
+
+func TestCLIPlanShowsSimulatedState(t *testing.T) {
+	exec := executor.NewMock()
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return exec })
+
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+
+	var out bytes.Buffer
+	cmd := newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "plan"})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "target version: 2")
+	require.Contains(t, out.String(), "2 support (up)")
+	require.Contains(t, out.String(), "app:support: [app:read]")
+	require.Empty(t, exec.RolesEnsured)
+}
+
+func TestCLITestCommandRoundTrips(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+
+	var out bytes.Buffer
+	cmd := newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", "file://" + filepath.Join("..", "..", "testdata", "migrations"), "--state-file", stateFile, "test"})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "ok   2 support")
+
+	migrations := filepath.Join(tmpDir, "migrations")
+	require.NoError(t, os.MkdirAll(migrations, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(migrations, "0001_grant.up.yaml"), []byte("version: 1\nactions:\n  - role: admin\n    add: [a]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(migrations, "0001_grant.down.yaml"), []byte("version: 1\nactions: []\n"), 0o644))
+
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", "file://" + migrations, "--state-file", stateFile, "test"})
+	require.ErrorIs(t, cmd.Execute(), errRoundTripFailed)
+	require.Contains(t, out.String(), "FAIL 1 grant")
+}
+
+func TestCLIStatusShowsTableAndJSON(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+
+	var out bytes.Buffer
+	cmd := newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "up", "1"})
+	require.NoError(t, cmd.Execute())
+
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "status"})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "pending: [2]")
+	require.Regexp(t, `1\s+roles\s+applied\s+yes\s+\d{4}-`, out.String())
+	require.Regexp(t, `2\s+support\s+pending\s+yes\s+-`, out.String())
+
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "--output", "json", "status"})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), `"state": "pending"`)
+	require.Contains(t, out.String(), `"applied_at"`)
+}
+
+func TestCLIUpPrintsRunReport(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+
+	var out bytes.Buffer
+	cmd := newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "up", "1"})
+	require.NoError(t, cmd.Execute())
+	require.Contains(t, out.String(), "VERSION  IDENTIFIER")
+	require.Contains(t, out.String(), "applied: 1, rolled back: 0, failed: 0")
+	require.Contains(t, out.String(), "version: 0 -> 1")
+
+	out.Reset()
+	cmd = newRootCmd(&out)
+	cmd.SetArgs([]string{"--source", source, "--state-file", stateFile, "--output", "json", "down", "--yes"})
+	require.NoError(t, cmd.Execute())
+	var report stmigrate.RunReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	require.Equal(t, 1, report.StartVersion)
+	require.Equal(t, 0, report.FinalVersion)
+	require.Equal(t, 1, report.RolledBack)
+	require.Len(t, report.Entries, 1)
+	require.Equal(t, stmigrate.DirectionDown, report.Entries[0].Direction)
+}
+
+func TestExitCodeMapsTypedErrors(t *testing.T) {
+	require.Equal(t, exitError, exitCode(errors.New("boom")))
+	require.Equal(t, exitDirty, exitCode(&stmigrate.DirtyError{Version: 2}))
+	require.Equal(t, exitLocked, exitCode(fmt.Errorf("lock state store: %w", stmigrate.ErrLocked)))
+	require.Equal(t, exitVersionNotFound, exitCode(fmt.Errorf("%w: target 9", stmigrate.ErrVersionNotFound)))
+	require.Equal(t, exitInvalidSchema, exitCode(&stmigrate.ParseError{File: "roles", Version: 1, Err: stmigrate.ErrUnsupportedSchema}))
+	require.Equal(t, exitExecutor, exitCode(&stmigrate.ExecutorError{Role: "admin", Op: "ensure_role", Err: errors.New("down")}))
+	require.Equal(t, exitProtected, exitCode(&stmigrate.ProtectedError{Version: 2, Role: "admin"}))
+	require.Equal(t, exitRoleInUse, exitCode(&stmigrate.RoleInUseError{Version: 2, Role: "admin", Users: 3}))
+}
+
+func TestCLIStatusFailsOnPendingAndDirty(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+
+	var out, errOut bytes.Buffer
+	err := run([]string{"--source", source, "--state-file", stateFile, "status", "--fail-on-pending"}, &out, &errOut)
+	require.ErrorIs(t, err, errPendingMigrations)
+	require.Equal(t, exitPending, exitCode(err))
+
+	require.NoError(t, run([]string{"--source", source, "--state-file", stateFile, "up"}, &out, &errOut))
+	require.NoError(t, run([]string{"--source", source, "--state-file", stateFile, "status", "--fail-on-pending", "--fail-on-dirty"}, &out, &errOut))
+
+	store, err := filestore.New(stateFile)
+	require.NoError(t, err)
+	require.NoError(t, store.SetVersion(context.Background(), 2, true))
+	err = run([]string{"--source", source, "--state-file", stateFile, "status", "--fail-on-dirty"}, &out, &errOut)
+	require.Equal(t, exitDirty, exitCode(err))
+}
+
+func TestCLIOutputFormatsAndHistory(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run([]string{"--source", source, "--state-file", stateFile, "--log-format", "json", "up"}, &out, &errOut))
+	require.Contains(t, errOut.String(), `"msg":"command: up"`)
+
+	out.Reset()
+	require.NoError(t, run([]string{"--source", source, "--state-file", stateFile, "-o", "json", "history"}, &out, &errOut))
+	var history []stmigrate.HistoryEntry
+	require.NoError(t, json.Unmarshal(out.Bytes(), &history))
+	require.Len(t, history, 2)
+	require.Equal(t, "roles", history[0].Identifier)
+
+	out.Reset()
+	require.NoError(t, run([]string{"--source", source, "--state-file", stateFile, "-o", "yaml", "status"}, &out, &errOut))
+	require.Contains(t, out.String(), "current: 2\n")
+	require.Contains(t, out.String(), "state: applied")
+
+	out.Reset()
+	require.NoError(t, run([]string{"--source", source, "--state-file", stateFile, "history"}, &out, &errOut))
+	require.Regexp(t, `1\s+roles\s+\d{4}-`, out.String())
+
+	err := run([]string{"--source", source, "--state-file", stateFile, "-o", "xml", "status"}, &out, &errOut)
+	require.ErrorContains(t, err, "invalid --output")
+}
+
+func TestCLIConfigFileEnvironmentsAndEnvVars(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	var initialized []stmigrate.SuperTokensSettings
+	prevInit := initSuperTokens
+	initSuperTokens = func(s stmigrate.SuperTokensSettings) error {
+		initialized = append(initialized, s)
+		return nil
+	}
+	t.Cleanup(func() { initSuperTokens = prevInit })
+
+	source, err := filepath.Abs(filepath.Join("..", "..", "testdata", "migrations"))
+	require.NoError(t, err)
+	root := t.TempDir()
+	config := "source: file://" + source + "\n" +
+		"state_file: dev.json\n" +
+		"environments:\n" +
+		"  staging:\n" +
+		"    state_file: staging.json\n" +
+		"    supertokens:\n" +
+		"      connection_uri: http://core:3567\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".st-migrate.yaml"), []byte(config), 0o644))
+	nested := filepath.Join(root, "services", "api")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+	t.Chdir(nested)
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run([]string{"--env", "staging", "up", "1"}, &out, &errOut))
+	require.FileExists(t, filepath.Join(root, "staging.json"))
+	require.NoFileExists(t, filepath.Join(root, "dev.json"))
+	require.Equal(t, "http://core:3567", initialized[0].ConnectionURI)
+
+	// environment variables override the config file, flags override environment variables
+	t.Setenv("ST_MIGRATE_STATE_FILE", filepath.Join(root, "from-env.json"))
+	require.NoError(t, run([]string{"up", "1"}, &out, &errOut))
+	require.FileExists(t, filepath.Join(root, "from-env.json"))
+	require.NoError(t, run([]string{"--state-file", filepath.Join(root, "from-flag.json"), "up", "1"}, &out, &errOut))
+	require.FileExists(t, filepath.Join(root, "from-flag.json"))
+
+	t.Setenv("ST_MIGRATE_ENV", "qa")
+	require.ErrorContains(t, run([]string{"status"}, &out, &errOut), `environment "qa" not defined`)
+}
+
+func TestCLINavigationCommands(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+	base := []string{"--source", source, "--state-file", stateFile, "--output", "json"}
+	runReport := func(args ...string) (stmigrate.RunReport, error) {
+		var out, errOut bytes.Buffer
+		err := run(append(append([]string{}, base...), args...), &out, &errOut)
+		var report stmigrate.RunReport
+		if out.Len() > 0 {
+			require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+		}
+		return report, err
+	}
+
+	report, err := runReport("up", "--steps", "1")
+	require.NoError(t, err)
+	require.Equal(t, 1, report.FinalVersion)
+
+	report, err = runReport("redo", "--yes")
+	require.NoError(t, err)
+	require.Equal(t, 1, report.RolledBack)
+	require.Equal(t, 1, report.Applied)
+
+	report, err = runReport("goto", "2")
+	require.NoError(t, err)
+	require.Equal(t, 2, report.FinalVersion)
+
+	report, err = runReport("--dry-run", "reset")
+	require.NoError(t, err)
+	require.True(t, report.DryRun)
+	require.Equal(t, 0, report.FinalVersion)
+
+	// without a terminal, an unprotected environment runs destructive operations unprompted
+	prevTTY := stdinIsTerminal
+	t.Cleanup(func() { stdinIsTerminal = prevTTY })
+	stdinIsTerminal = func() bool { return false }
+	report, err = runReport("down", "--all")
+	require.NoError(t, err)
+	require.Equal(t, 2, report.RolledBack)
+	require.Equal(t, 0, report.FinalVersion)
+}
+
+func TestCLIConfirmsDestructiveOperations(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	prevTTY := stdinIsTerminal
+	t.Cleanup(func() { stdinIsTerminal = prevTTY })
+
+	root := t.TempDir()
+	source, err := filepath.Abs(filepath.Join("..", "..", "testdata", "migrations"))
+	require.NoError(t, err)
+	config := "source: file://" + source + "\n" +
+		"state_file: state.json\n" +
+		"environments:\n" +
+		"  prod:\n" +
+		"    protected: true\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".st-migrate.yaml"), []byte(config), 0o644))
+	t.Chdir(root)
+
+	execute := func(stdin string, args ...string) (string, error) {
+		var out, errOut bytes.Buffer
+		cmd := newRootCmd(&out)
+		cmd.SetErr(&errOut)
+		cmd.SetIn(strings.NewReader(stdin))
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return errOut.String(), err
+	}
+
+	// applying additive migrations needs no confirmation
+	stdinIsTerminal = func() bool { return false }
+	_, err = execute("", "--env", "prod", "up")
+	require.NoError(t, err)
+
+	// without a TTY, a protected environment needs --confirm-env and --yes
+	_, err = execute("", "--env", "prod", "down", "--yes")
+	require.ErrorContains(t, err, "--confirm-env prod")
+	_, err = execute("", "--env", "prod", "--confirm-env", "staging", "down", "--yes")
+	require.ErrorIs(t, err, errNotConfirmed)
+	_, err = execute("", "--env", "prod", "--confirm-env", "prod", "down")
+	require.ErrorContains(t, err, "re-run with --yes")
+
+	// the acknowledgements are never read from the environment
+	t.Setenv("ST_MIGRATE_YES", "true")
+	t.Setenv("ST_MIGRATE_CONFIRM_ENV", "prod")
+	_, err = execute("", "--env", "prod", "down")
+	require.ErrorContains(t, err, "--confirm-env prod")
+	_, err = execute("", "--env", "prod", "--confirm-env", "prod", "down")
+	require.ErrorContains(t, err, "re-run with --yes")
+
+	// on a TTY the plan is shown and the typed answer must match
+	stdinIsTerminal = func() bool { return true }
+	stderr, err := execute("no\n", "down")
+	require.ErrorIs(t, err, errNotConfirmed)
+	require.Contains(t, stderr, "2 support (down)")
+	require.Contains(t, stderr, `Type "yes" to continue`)
+
+	_, err = execute("prod\n", "--env", "prod", "--confirm-env", "prod", "down")
+	require.NoError(t, err)
+
+	// an unprotected environment without a TTY runs unprompted, as scripts expect
+	stdinIsTerminal = func() bool { return false }
+	_, err = execute("", "down")
+	require.NoError(t, err)
+
+	_, err = execute("", "--dry-run", "--env", "prod", "reset")
+	require.NoError(t, err)
+}
+
+func TestCLIConfirmsUnassigningUsers(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	prevTTY := stdinIsTerminal
+	t.Cleanup(func() { stdinIsTerminal = prevTTY })
+	stdinIsTerminal = func() bool { return true }
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_support.up.yaml"), []byte("version: 1\nactions:\n  - role: support\n    assign: [u1]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_offboard.up.yaml"), []byte("version: 1\nactions:\n  - role: support\n    unassign: [u1]\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+	execute := func(stdin string, args ...string) (string, error) {
+		var out, errOut bytes.Buffer
+		cmd := newRootCmd(&out)
+		cmd.SetErr(&errOut)
+		cmd.SetIn(strings.NewReader(stdin))
+		cmd.SetArgs(append(append([]string{}, base...), args...))
+		err := cmd.Execute()
+		return errOut.String(), err
+	}
+
+	_, err := execute("", "up", "1")
+	require.NoError(t, err, "assigning users is not destructive")
+
+	stderr, err := execute("no\n", "up")
+	require.ErrorIs(t, err, errNotConfirmed)
+	require.Contains(t, stderr, "Type \"yes\" to continue")
+	_, err = execute("yes\n", "up")
+	require.NoError(t, err)
+}
+
+func TestCLIRefusesProtectedRoleDeletion(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	root := t.TempDir()
+	source, err := filepath.Abs(filepath.Join("..", "..", "testdata", "migrations"))
+	require.NoError(t, err)
+	config := "source: file://" + source + "\n" +
+		"state_file: state.json\n" +
+		"protection:\n" +
+		"  roles: [\"app:support\"]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".st-migrate.yaml"), []byte(config), 0o644))
+	t.Chdir(root)
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run([]string{"up"}, &out, &errOut))
+
+	for _, args := range [][]string{{"plan", "0"}, {"--dry-run", "down"}, {"down", "--yes"}} {
+		err := run(args, &out, &errOut)
+		require.ErrorIs(t, err, stmigrate.ErrProtected, args)
+		require.Equal(t, exitProtected, exitCode(err))
+	}
+
+	// the refusal happens before anything runs, so the state stays clean
+	require.NoError(t, run([]string{"status", "--fail-on-dirty"}, &out, &errOut))
+}
+
+func TestCLIAllowProtectedOnlyFromCommandLine(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "0001_admin.up.yaml"), []byte("version: 1\nactions:\n  - role: admin\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "0001_admin.down.yaml"), []byte("version: 2\nallow_protected: true\nactions:\n  - role: admin\n    ensure: absent\n"), 0o644))
+	config := "source: file://" + root + "\n" +
+		"state_file: state.json\n" +
+		"protection:\n" +
+		"  roles: [admin]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, ".st-migrate.yaml"), []byte(config), 0o644))
+	t.Chdir(root)
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run([]string{"up"}, &out, &errOut))
+
+	t.Setenv("ST_MIGRATE_ALLOW_PROTECTED", "1")
+	err := run([]string{"down", "--yes"}, &out, &errOut)
+	require.ErrorIs(t, err, stmigrate.ErrProtected, "the override is not acknowledged by the environment")
+
+	require.NoError(t, run([]string{"down", "--yes", "--allow-protected", "1"}, &out, &errOut))
+}
+
+func TestCLIRefusesDeletingRoleHeldByUsers(t *testing.T) {
+	mock := executor.NewMock()
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return mock })
+	tmpDir := t.TempDir()
+	stateFile := filepath.Join(tmpDir, "state.json")
+	source := "file://" + filepath.Join("..", "..", "testdata", "migrations")
+	base := []string{"--source", source, "--state-file", stateFile}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "up"), &out, &errOut))
+	mock.RoleUsers["app:support"] = []string{"u1", "u2"}
+
+	out.Reset()
+	require.NoError(t, run(append(base, "plan", "1"), &out, &errOut))
+	require.Contains(t, out.String(), "app:support absent (2 users affected)")
+
+	err := run(append(base, "down", "--yes"), &out, &errOut)
+	require.ErrorIs(t, err, stmigrate.ErrRoleInUse)
+	require.Equal(t, exitRoleInUse, exitCode(err))
+	require.Empty(t, mock.RolesDeleted)
+
+	t.Setenv("ST_MIGRATE_FORCE", "true")
+	err = run(append(base, "down", "--yes"), &out, &errOut)
+	require.ErrorIs(t, err, stmigrate.ErrRoleInUse, "--force is not read from the environment")
+
+	require.NoError(t, run(append(base, "--force", "down", "--yes"), &out, &errOut))
+	require.Equal(t, []string{"app:support"}, mock.RolesDeleted)
+}
+
+func TestCLIShowsSchemaV2Metadata(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	dir := t.TempDir()
+	up := "version: 2\ndescription: Introduce support role\nauthor: jane\nticket: AUTH-12\nactions:\n  - role: app:support\n    description: Customer support agents\n    add:\n      - name: ticket:read\n        description: Read support tickets\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_support.up.yaml"), []byte(up), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_support.down.yaml"), []byte("version: 2\nactions:\n  - role: app:support\n    ensure: absent\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "plan"), &out, &errOut))
+	require.Contains(t, out.String(), "  description: Introduce support role\n  author: jane\n  ticket: AUTH-12\n")
+	require.Contains(t, out.String(), "      Customer support agents\n")
+
+	out.Reset()
+	require.NoError(t, run(append(base, "status"), &out, &errOut))
+	require.Contains(t, out.String(), "DESCRIPTION")
+	require.Contains(t, out.String(), "Introduce support role")
+}
+
+func TestCLIPlanShowsAssignments(t *testing.T) {
+	mock := executor.NewMock()
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return mock })
+	dir := t.TempDir()
+	up := "version: 1\nactions:\n  - role: admin\n    tenant: tenant-a\n    assign: [u1, u2]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_admins.up.yaml"), []byte(up), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_admins.down.yaml"), []byte("version: 1\nactions:\n  - role: admin\n    tenant: tenant-a\n    unassign: [u1, u2]\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "plan"), &out, &errOut))
+	require.Contains(t, out.String(), "admin present tenant=tenant-a assign=[u1 u2]")
+
+	require.NoError(t, run(append(base, "up"), &out, &errOut))
+	require.Len(t, mock.Assignments, 2)
+	require.NoError(t, run(append(base, "down", "--yes"), &out, &errOut))
+	require.Empty(t, mock.Assignments)
+}
+
+func TestCLISeedsRolesFromSourceDirectory(t *testing.T) {
+	mock := executor.NewMock()
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return mock })
+	dir := t.TempDir()
+	up := "version: 1\nactions:\n  - role: legacy\n  - seed: {file: seeds/legacy.csv, batch_size: 1}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_legacy.up.yaml"), []byte(up), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "seeds"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "seeds", "legacy.csv"), []byte("user_id,role\nu1,legacy\nu2,legacy\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "plan"), &out, &errOut))
+	require.Contains(t, out.String(), "seed seeds/legacy.csv (assign csv, batch_size=1)")
+
+	require.NoError(t, run(append(base, "up"), &out, &errOut))
+	require.Len(t, mock.Assignments, 2)
+}
+
+func TestCLIPlanShowsExactSets(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_support.up.yaml"), []byte("version: 1\nactions:\n  - role: support\n    set: [ticket:read]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_support.down.yaml"), []byte("version: 1\nactions:\n  - role: support\n    restore: true\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "plan"), &out, &errOut))
+	require.Contains(t, out.String(), "support present set=[ticket:read]")
+
+	// set may strip permissions, so up asks for confirmation
+	require.NoError(t, run(append(base, "up", "--yes"), &out, &errOut))
+	out.Reset()
+	require.NoError(t, run(append(base, "plan", "0"), &out, &errOut))
+	require.Contains(t, out.String(), "support present restore")
+}
+
+func TestCLIPlanShowsRenames(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_admin.up.yaml"), []byte("version: 1\nactions:\n  - role: admin\n    add: [users:read]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_admin.down.yaml"), []byte("version: 1\nactions:\n  - role: admin\n    ensure: absent\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_rename.up.yaml"), []byte("version: 1\nactions:\n  - rename: {from: admin, to: administrator, tenants: [public, t1]}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_rename.down.yaml"), []byte("version: 1\ninverse: true\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "plan"), &out, &errOut))
+	require.Contains(t, out.String(), "rename admin -> administrator (tenants=[public t1])")
+	require.Contains(t, out.String(), "administrator: [users:read]")
+
+	require.NoError(t, run(append(base, "up", "--yes"), &out, &errOut))
+	out.Reset()
+	require.NoError(t, run(append(base, "plan", "1"), &out, &errOut))
+	require.Contains(t, out.String(), "rename administrator -> admin")
+}
+
+func TestCLIPlanShowsIncludes(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_roles.up.yaml"), []byte("version: 1\nactions:\n  - role: viewer\n    add: [docs:read]\n  - role: editor\n    includes: [viewer]\n    add: [docs:write]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_export.up.yaml"), []byte("version: 1\nactions:\n  - role: viewer\n    add: [docs:export]\n    propagate: true\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "plan"), &out, &errOut))
+	require.Contains(t, out.String(), "editor present add=[docs:write docs:read] includes=[viewer]")
+	require.Contains(t, out.String(), "viewer present add=[docs:export] propagate")
+	require.Contains(t, out.String(), "editor present add=[docs:export]\n")
+}
+
+func TestCLIRendersTemplateVariables(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_tenant.up.yaml"), []byte("version: 1\nactions:\n  - role: ${tenant}:admin\n    add: [${region}:read]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".st-migrate.yaml"), []byte("source: file://"+dir+"\nstate_file: state.json\nvars:\n  tenant: acme\n  region: eu\n"), 0o644))
+	t.Chdir(dir)
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run([]string{"plan"}, &out, &errOut))
+	require.Contains(t, out.String(), "acme:admin present add=[eu:read]")
+
+	// environment variables override the config file, --var overrides both
+	t.Setenv("ST_MIGRATE_VAR_region", "us")
+	out.Reset()
+	require.NoError(t, run([]string{"plan"}, &out, &errOut))
+	require.Contains(t, out.String(), "acme:admin present add=[us:read]")
+	out.Reset()
+	require.NoError(t, run([]string{"--var", "tenant=globex", "plan"}, &out, &errOut))
+	require.Contains(t, out.String(), "globex:admin present add=[us:read]")
+
+	require.ErrorContains(t, run([]string{"--var", "tenant", "plan"}, &out, &errOut), `invalid --var "tenant": want key=value`)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_more.up.yaml"), []byte("version: 1\nactions:\n  - role: ${team}:viewer\n"), 0o644))
+	err := run([]string{"--strict-vars", "plan"}, &out, &errOut)
+	require.ErrorIs(t, err, stmigrate.ErrParse)
+	require.ErrorContains(t, err, "undefined variable ${team} (line 3)")
+}
+
+func TestCLIPlanShowsForEachExpansion(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_resources.up.yaml"), []byte("version: 1\nactions:\n  - for_each:\n      resource: [projects, invoices]\n      level: [viewer, editor]\n    role: ${tenant}:${each.resource}:${each.level}\n    add: [\"${each.resource}:read\"]\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json"), "--var", "tenant=acme"}
+
+	var out, errOut bytes.Buffer
+	require.NoError(t, run(append(base, "plan"), &out, &errOut))
+	for _, want := range []string{
+		"acme:projects:viewer present add=[projects:read]",
+		"acme:projects:editor present add=[projects:read]",
+		"acme:invoices:viewer present add=[invoices:read]",
+		"acme:invoices:editor present add=[invoices:read]",
+	} {
+		require.Contains(t, out.String(), want)
+	}
+}
+
+func TestCLIReportsDocumentProblemsWithPositions(t *testing.T) {
+	stmigrate.SetDefaultExecutorFactory(func() executor.Executor { return executor.NewMock() })
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0003_add_support.up.yaml"), []byte("version: 1\nactions:\n  - role: app:viewer\n    ensrue: absent\n  - role: app:viewer\n    ensure: maybe\n"), 0o644))
+	base := []string{"--source", "file://" + dir, "--state-file", filepath.Join(dir, "state.json")}
+
+	var out, errOut bytes.Buffer
+	err := run(append(base, "plan"), &out, &errOut)
+	require.ErrorIs(t, err, stmigrate.ErrParse)
+	require.ErrorIs(t, err, stmigrate.ErrUnknownField)
+	require.ErrorContains(t, err, `parse migration 3 (add_support, up): schema v1: 2 problems: line 4, column 5: unknown field "ensrue" in action (did you mean "ensure"?); line 6, column 13: action app:viewer has invalid ensure "maybe"`)
+	var docErr *stmigrate.DocumentError
+	require.True(t, errors.As(err, &docErr))
+	require.Equal(t, stmigrate.DocumentProblem{Line: 6, Column: 13, Err: docErr.Problems[1].Err}, docErr.Problems[1])
+}
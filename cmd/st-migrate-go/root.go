@@ -2,15 +2,19 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"sort"
 	"strconv"
+	"text/tabwriter"
+	"time"
 
 	"github.com/BeardedWonderDev/st-migrate-go/internal/create"
-	filestore "github.com/BeardedWonderDev/st-migrate-go/internal/state/file"
 	"github.com/BeardedWonderDev/st-migrate-go/st-migrate"
-	"github.com/golang-migrate/migrate/v4/database"
+	filestore "github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/file"
 	// common database drivers registered for CLI
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
@@ -22,12 +26,30 @@ type cliOpts struct {
 	sourceURL string
 	database  string
 	stateFile string
-	dryRun    bool
-	verbose   bool
-	width     int
-	schemaVer int
-	output    io.Writer
-	logger    *slog.Logger
+	// migrationsTable names the table a --database store keeps its state in.
+	migrationsTable string
+	dryRun          bool
+	verbose         bool
+	yes             bool
+	width           int
+	schemaVer       int
+	format          string
+	logFormat       string
+	// configPath and env select the project config file and environment.
+	configPath     string
+	env            string
+	confirmEnv     string
+	protected      bool
+	protection     stmigrate.Protection
+	allowProtected []uint
+	force          bool
+	varFlags       []string
+	vars           map[string]string
+	strictVars     bool
+	superTokens    stmigrate.SuperTokensSettings
+	output         io.Writer
+	logOutput      io.Writer
+	logger         *slog.Logger
 }
 
 func newRootCmd(out io.Writer) *cobra.Command {
@@ -36,25 +58,76 @@ func newRootCmd(out io.Writer) *cobra.Command {
 		stateFile: ".st-migrate/state.json",
 		width:     4,
 		schemaVer: 1,
+		format:    formatText,
+		logFormat: formatText,
 		output:    out,
 	}
 
 	rootCmd := &cobra.Command{
 		Use:   "st-migrate-go",
 		Short: "Role/permission migration runner for SuperTokens",
+		Long: `Role/permission migration runner for SuperTokens.
+
+Exit codes:
+  0  success
+  1  other error (invalid arguments, configuration, I/O)
+  2  state is dirty
+  3  state store lock is held
+  4  target or recorded version not found in the source
+  5  migration could not be parsed or uses an unsupported schema
+  6  executor (SuperTokens) operation failed
+  7  pending migrations (status --fail-on-pending)
+  8  drift detected (drift)
+  9  round-trip test failed (test)
+  10 migration would remove a protected role or permission
+  11 migration would delete (or rename out of a tenant) a role still assigned to users`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := applySettings(cmd, &opts); err != nil {
+				return err
+			}
+			if err := validateFormat("--output", opts.format, formatText, formatJSON, formatYAML); err != nil {
+				return err
+			}
+			if err := validateFormat("--log-format", opts.logFormat, formatText, formatJSON); err != nil {
+				return err
+			}
+			opts.logOutput = cmd.ErrOrStderr()
+			return nil
+		},
 	}
 
 	rootCmd.PersistentFlags().StringVar(&opts.sourceURL, "source", opts.sourceURL, "migration source URL (golang-migrate style)")
 	rootCmd.PersistentFlags().StringVar(&opts.database, "database", "", "state database URL (golang-migrate driver)")
+	rootCmd.PersistentFlags().StringVar(&opts.migrationsTable, "migrations-table", "", "table the --database store keeps migration state in (default: the driver's schema_migrations)")
 	rootCmd.PersistentFlags().StringVar(&opts.stateFile, "state-file", opts.stateFile, "path to file-based state store (used when --database is empty)")
 	rootCmd.PersistentFlags().BoolVar(&opts.dryRun, "dry-run", false, "print actions without executing")
+	rootCmd.PersistentFlags().BoolVar(&opts.force, "force", false, "delete or rename roles still assigned to users, logging a warning instead of refusing")
 	rootCmd.PersistentFlags().BoolVar(&opts.verbose, "verbose", false, "enable verbose logging")
+	rootCmd.PersistentFlags().BoolVarP(&opts.yes, "yes", "y", false, "skip the confirmation prompt for destructive operations")
+	rootCmd.PersistentFlags().StringVar(&opts.confirmEnv, "confirm-env", "", "name of the protected environment a destructive operation is meant for")
+	rootCmd.PersistentFlags().StringVarP(&opts.format, "output", "o", opts.format, "result format written to stdout: text, json or yaml")
+	rootCmd.PersistentFlags().StringVar(&opts.logFormat, "log-format", opts.logFormat, "log format written to stderr: text or json")
+	rootCmd.PersistentFlags().StringVar(&opts.configPath, "config", "", "project config file (default: "+stmigrate.ConfigFileName+" found in the working directory or a parent)")
+	rootCmd.PersistentFlags().StringVar(&opts.env, "env", "", "named environment from the config file (default: its default_env)")
+	rootCmd.PersistentFlags().UintSliceVar(&opts.allowProtected, "allow-protected", nil, "migration versions whose allow_protected override is acknowledged for this run")
+	rootCmd.PersistentFlags().StringArrayVar(&opts.varFlags, "var", nil, "template variable substituted for ${key} in migrations, as key=value (repeatable)")
+	rootCmd.PersistentFlags().BoolVar(&opts.strictVars, "strict-vars", false, "fail when a migration references an undefined variable")
+	rootCmd.PersistentFlags().StringVar(&opts.superTokens.ConnectionURI, "supertokens-uri", "", "SuperTokens core connection URI; initializes the SuperTokens SDK when set")
+	rootCmd.PersistentFlags().StringVar(&opts.superTokens.APIKey, "supertokens-api-key", "", "SuperTokens core API key")
 
 	rootCmd.AddCommand(upCmd(&opts))
 	rootCmd.AddCommand(downCmd(&opts))
 	rootCmd.AddCommand(statusCmd(&opts))
 	rootCmd.AddCommand(createCmd(&opts))
 	rootCmd.AddCommand(migrateCmd(&opts))
+	rootCmd.AddCommand(driftCmd(&opts))
+	rootCmd.AddCommand(planCmd(&opts))
+	rootCmd.AddCommand(testCmd(&opts))
+	rootCmd.AddCommand(historyCmd(&opts))
+	rootCmd.AddCommand(redoCmd(&opts))
+	rootCmd.AddCommand(resetCmd(&opts))
 
 	return rootCmd
 }
@@ -62,18 +135,27 @@ func newRootCmd(out io.Writer) *cobra.Command {
 func buildRunner(opts *cliOpts) (*stmigrate.Runner, error) {
 	logger := getLogger(opts)
 
+	if err := initSuperTokens(opts.superTokens); err != nil {
+		return nil, err
+	}
+
 	cfg := stmigrate.Config{
-		SourceURL: opts.sourceURL,
-		DryRun:    opts.dryRun,
-		Logger:    logger,
+		SourceURL:      opts.sourceURL,
+		DryRun:         opts.dryRun,
+		Logger:         logger,
+		Protection:     opts.protection,
+		AllowProtected: opts.allowProtected,
+		Force:          opts.force,
+		Vars:           opts.vars,
+		StrictVars:     opts.strictVars,
 	}
 
 	if opts.database != "" {
-		drv, err := database.Open(opts.database)
+		st, err := stmigrate.OpenDatabaseStore(opts.database, opts.migrationsTable, logger)
 		if err != nil {
-			return nil, fmt.Errorf("open database driver: %w", err)
+			return nil, err
 		}
-		return stmigrate.NewWithWrappedDriver(cfg, drv)
+		cfg.Store = st
 	} else {
 		fs, err := filestore.New(opts.stateFile)
 		if err != nil {
@@ -86,6 +168,9 @@ func buildRunner(opts *cliOpts) (*stmigrate.Runner, error) {
 	return stmigrate.New(cfg)
 }
 
+// initSuperTokens is swapped in tests; the SuperTokens SDK can only be initialized once per process.
+var initSuperTokens = stmigrate.InitSuperTokens
+
 func getLogger(opts *cliOpts) *slog.Logger {
 	if opts.logger != nil {
 		return opts.logger
@@ -94,12 +179,24 @@ func getLogger(opts *cliOpts) *slog.Logger {
 	if opts.verbose {
 		level = slog.LevelDebug
 	}
-	opts.logger = slog.New(slog.NewTextHandler(opts.output, &slog.HandlerOptions{Level: level}))
+	w := opts.logOutput
+	if w == nil {
+		w = os.Stderr
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if opts.logFormat == formatJSON {
+		opts.logger = slog.New(slog.NewJSONHandler(w, handlerOpts))
+	} else {
+		opts.logger = slog.New(slog.NewTextHandler(w, handlerOpts))
+	}
+	// stores log through the default logger; keep them on the same stream and format
+	slog.SetDefault(opts.logger)
 	return opts.logger
 }
 
 func upCmd(opts *cliOpts) *cobra.Command {
-	return &cobra.Command{
+	var steps int
+	cmd := &cobra.Command{
 		Use:   "up [target]",
 		Short: "Apply pending migrations",
 		Args:  cobra.MaximumNArgs(1),
@@ -107,6 +204,9 @@ func upCmd(opts *cliOpts) *cobra.Command {
 			logger := getLogger(opts)
 			var target *uint
 			if len(args) == 1 {
+				if steps > 0 {
+					return fmt.Errorf("up accepts either a target version or --steps, not both")
+				}
 				n, err := strconv.ParseUint(args[0], 10, 64)
 				if err != nil {
 					logger.Error("invalid target version", slog.String("input", args[0]), slog.Any("err", err))
@@ -115,29 +215,43 @@ func upCmd(opts *cliOpts) *cobra.Command {
 				val := uint(n)
 				target = &val
 			}
-			logger.Info("command: up", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile), slog.Bool("dry_run", opts.dryRun), slog.Any("target", target))
-			runner, err := buildRunner(opts)
-			if err != nil {
-				logger.Error("build runner", slog.Any("err", err))
-				return err
+			logger.Info("command: up", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile), slog.Bool("dry_run", opts.dryRun), slog.Any("target", target), slog.Int("steps", steps))
+			op := operation{
+				name: "up",
+				plan: func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.Plan, error) {
+					return runner.Plan(ctx, target)
+				},
+				run: func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.RunReport, error) {
+					return runner.Up(ctx, target)
+				},
 			}
-			defer runner.Close()
-			if err := runner.Up(context.Background(), target); err != nil {
-				logger.Error("up failed", slog.Any("err", err))
-				return err
+			if steps > 0 {
+				op.plan = planUpSteps(steps)
+				op.run = func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.RunReport, error) {
+					return runner.UpSteps(ctx, steps)
+				}
 			}
-			return nil
+			return runAndReport(cmd, opts, op)
 		},
 	}
+	cmd.Flags().IntVar(&steps, "steps", 0, "apply only the next N pending migrations")
+	return cmd
 }
 
 func downCmd(opts *cliOpts) *cobra.Command {
-	return &cobra.Command{
+	var all bool
+	cmd := &cobra.Command{
 		Use:   "down [steps]",
 		Short: "Roll back applied migrations",
 		Args:  cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger := getLogger(opts)
+			if all {
+				if len(args) == 1 {
+					return fmt.Errorf("down accepts either a step count or --all, not both")
+				}
+				return runReset(cmd, opts, "down --all")
+			}
 			steps := 1
 			if len(args) == 1 {
 				n, err := strconv.Atoi(args[0])
@@ -148,23 +262,147 @@ func downCmd(opts *cliOpts) *cobra.Command {
 				steps = n
 			}
 			logger.Info("command: down", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile), slog.Bool("dry_run", opts.dryRun), slog.Int("steps", steps))
+			return runAndReport(cmd, opts, operation{
+				name: "down",
+				plan: planDownSteps(max(steps, 1)),
+				run: func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.RunReport, error) {
+					return runner.Down(ctx, steps)
+				},
+			})
+		},
+	}
+	cmd.Flags().BoolVar(&all, "all", false, "roll back every applied migration (same as reset)")
+	return cmd
+}
+
+func redoCmd(opts *cliOpts) *cobra.Command {
+	return &cobra.Command{
+		Use:   "redo",
+		Short: "Roll back and re-apply the latest migration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger(opts)
+			logger.Info("command: redo", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile), slog.Bool("dry_run", opts.dryRun))
+			return runAndReport(cmd, opts, operation{
+				name:              "redo",
+				plan:              planDownSteps(1),
+				alwaysDestructive: true,
+				run: func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.RunReport, error) {
+					return runner.Redo(ctx)
+				},
+			})
+		},
+	}
+}
+
+func resetCmd(opts *cliOpts) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reset",
+		Short: "Roll back every applied migration to version 0",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runReset(cmd, opts, "reset")
+		},
+	}
+}
+
+func runReset(cmd *cobra.Command, opts *cliOpts, name string) error {
+	logger := getLogger(opts)
+	logger.Info("command: "+name, slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile), slog.Bool("dry_run", opts.dryRun))
+	return runAndReport(cmd, opts, operation{
+		name: name,
+		plan: planTo(0),
+		run: func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.RunReport, error) {
+			return runner.Reset(ctx)
+		},
+	})
+}
+
+// runAndReport builds a runner, confirms the operation if it is destructive, runs it and
+// prints its run report, even when the run fails.
+func runAndReport(cmd *cobra.Command, opts *cliOpts, op operation) error {
+	logger := getLogger(opts)
+	runner, err := buildRunner(opts)
+	if err != nil {
+		logger.Error("build runner", slog.Any("err", err))
+		return err
+	}
+	defer runner.Close()
+	ctx := context.Background()
+	if !opts.dryRun && op.plan != nil {
+		plan, err := op.plan(ctx, runner)
+		if err != nil {
+			logger.Error(op.name+" plan failed", slog.Any("err", err))
+			return err
+		}
+		if err := confirm(cmd, opts, op, plan); err != nil {
+			return err
+		}
+	}
+	report, err := op.run(ctx, runner)
+	if err != nil {
+		logger.Error(op.name+" failed", slog.Any("err", err))
+	}
+	if outErr := writeRunReport(opts, report); outErr != nil && err == nil {
+		return outErr
+	}
+	return err
+}
+
+func historyCmd(opts *cliOpts) *cobra.Command {
+	return &cobra.Command{
+		Use:   "history",
+		Short: "List applied migrations with when they were applied",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger(opts)
+			logger.Info("command: history", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile))
 			runner, err := buildRunner(opts)
 			if err != nil {
 				logger.Error("build runner", slog.Any("err", err))
 				return err
 			}
 			defer runner.Close()
-			if err := runner.Down(context.Background(), steps); err != nil {
-				logger.Error("down failed", slog.Any("err", err))
+			history, err := runner.History(context.Background())
+			if err != nil {
+				logger.Error("history failed", slog.Any("err", err))
 				return err
 			}
-			return nil
+			return writeOutput(opts, history, func(w io.Writer) { printHistory(w, history) })
 		},
 	}
 }
 
+func printHistory(w io.Writer, history []stmigrate.HistoryEntry) {
+	if len(history) == 0 {
+		fmt.Fprintln(w, "no applied migrations recorded")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tIDENTIFIER\tAPPLIED AT\tCHECKSUM")
+	for _, h := range history {
+		ident := h.Identifier
+		if ident == "" {
+			ident = "(missing from source)"
+		}
+		appliedAt := "-"
+		if !h.AppliedAt.IsZero() {
+			appliedAt = h.AppliedAt.Format(time.RFC3339)
+		}
+		checksum := shortChecksum(h.Checksum)
+		if h.Modified {
+			checksum += " (modified)"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", h.Version, ident, appliedAt, checksum)
+	}
+	tw.Flush()
+}
+
+// errPendingMigrations is returned by status --fail-on-pending when migrations are not yet applied.
+var errPendingMigrations = errors.New("pending migrations")
+
 func statusCmd(opts *cliOpts) *cobra.Command {
-	return &cobra.Command{
+	var failOnPending, failOnDirty bool
+	cmd := &cobra.Command{
 		Use:   "status",
 		Short: "Show current and pending migrations",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -176,27 +414,117 @@ func statusCmd(opts *cliOpts) *cobra.Command {
 				return err
 			}
 			defer runner.Close()
-			current, pending, err := runner.Status(context.Background())
+			report, err := runner.Status(context.Background())
 			if err != nil {
 				logger.Error("status failed", slog.Any("err", err))
 				return err
 			}
-			fmt.Fprintf(opts.output, "current version: %d\n", current)
-			if len(pending) == 0 {
-				fmt.Fprintln(opts.output, "pending: none")
-			} else {
-				fmt.Fprintf(opts.output, "pending: %v\n", pending)
+			if err := writeOutput(opts, report, func(w io.Writer) { printStatus(w, report) }); err != nil {
+				return err
+			}
+			if failOnDirty && report.Dirty {
+				return &stmigrate.DirtyError{Version: report.Current}
+			}
+			if pending := report.Pending(); failOnPending && len(pending) > 0 {
+				return fmt.Errorf("%w: %v", errPendingMigrations, pending)
 			}
 			return nil
 		},
 	}
+	cmd.Flags().BoolVar(&failOnPending, "fail-on-pending", false, "exit non-zero (code 7) when migrations are pending")
+	cmd.Flags().BoolVar(&failOnDirty, "fail-on-dirty", false, "exit non-zero (code 2) when the state is dirty")
+	return cmd
+}
+
+func printStatus(w io.Writer, report *stmigrate.StatusReport) {
+	fmt.Fprintf(w, "current version: %d\n", report.Current)
+	fmt.Fprintf(w, "dirty: %t\n", report.Dirty)
+	if pending := report.Pending(); len(pending) == 0 {
+		fmt.Fprintln(w, "pending: none")
+	} else {
+		fmt.Fprintf(w, "pending: %v\n", pending)
+	}
+	if len(report.Migrations) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tIDENTIFIER\tSTATE\tDOWN\tAPPLIED AT\tCHECKSUM\tDESCRIPTION")
+	for _, m := range report.Migrations {
+		down := "yes"
+		if m.MissingDown {
+			down = "missing"
+		}
+		if m.State == stmigrate.StateMissingFromSource {
+			down = "-"
+		}
+		appliedAt := "-"
+		if m.AppliedAt != nil {
+			appliedAt = m.AppliedAt.Format(time.RFC3339)
+		}
+		checksum := shortChecksum(m.Checksum)
+		if m.Checksum == "" {
+			checksum = shortChecksum(m.AppliedChecksum)
+		}
+		if m.Modified {
+			checksum += " (modified)"
+		}
+		description := "-"
+		if m.Header != nil && m.Header.Description != "" {
+			description = m.Header.Description
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", m.Version, m.Identifier, m.State, down, appliedAt, checksum, description)
+	}
+	tw.Flush()
+}
+
+// writeRunReport prints the outcome of an up, down or migrate run.
+func writeRunReport(opts *cliOpts, report *stmigrate.RunReport) error {
+	if report == nil {
+		return nil
+	}
+	return writeOutput(opts, report, func(w io.Writer) { printRunReport(w, report) })
+}
+
+func printRunReport(w io.Writer, report *stmigrate.RunReport) {
+	if report.DryRun {
+		fmt.Fprintln(w, "dry run: no changes were made")
+	}
+	if len(report.Entries) > 0 {
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tIDENTIFIER\tDIRECTION\tACTIONS\tDURATION\tRESULT")
+		for _, e := range report.Entries {
+			result := "ok"
+			if e.Error != "" {
+				result = "error: " + e.Error
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d\t%s\t%s\n", e.Version, e.Identifier, e.Direction, e.Actions, e.Duration.Round(time.Microsecond), result)
+		}
+		tw.Flush()
+	}
+	fmt.Fprintf(w, "applied: %d, rolled back: %d, failed: %d\n", report.Applied, report.RolledBack, report.Failed)
+	fmt.Fprintf(w, "version: %d -> %d (%s)\n", report.StartVersion, report.FinalVersion, report.Duration.Round(time.Microsecond))
+	if report.DryRun {
+		printRolePermissions(w, "resulting state", report.Result)
+	}
+}
+
+func shortChecksum(sum string) string {
+	if sum == "" {
+		return "-"
+	}
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
 }
 
 func migrateCmd(opts *cliOpts) *cobra.Command {
 	return &cobra.Command{
-		Use:   "migrate <version>",
-		Short: "Migrate up or down to the target version",
-		Args:  cobra.ExactArgs(1),
+		Use:     "migrate <version>",
+		Aliases: []string{"goto"},
+		Short:   "Migrate up or down to the target version",
+		Args:    cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger := getLogger(opts)
 			n, err := strconv.ParseUint(args[0], 10, 64)
@@ -206,22 +534,255 @@ func migrateCmd(opts *cliOpts) *cobra.Command {
 			}
 			target := uint(n)
 			logger.Info("command: migrate", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile), slog.Bool("dry_run", opts.dryRun), slog.Uint64("target", uint64(target)))
+			return runAndReport(cmd, opts, operation{
+				name: "migrate",
+				plan: planTo(target),
+				run: func(ctx context.Context, runner *stmigrate.Runner) (*stmigrate.RunReport, error) {
+					return runner.Migrate(ctx, target)
+				},
+			})
+		},
+	}
+}
+
+func planCmd(opts *cliOpts) *cobra.Command {
+	return &cobra.Command{
+		Use:   "plan [target]",
+		Short: "Simulate migrating to a target version and show the resulting roles",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger(opts)
+			var target *uint
+			if len(args) == 1 {
+				n, err := strconv.ParseUint(args[0], 10, 64)
+				if err != nil {
+					logger.Error("invalid target version", slog.String("input", args[0]), slog.Any("err", err))
+					return fmt.Errorf("invalid target version: %w", err)
+				}
+				val := uint(n)
+				target = &val
+			}
+			logger.Info("command: plan", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile), slog.Any("target", target))
+			runner, err := buildRunner(opts)
+			if err != nil {
+				logger.Error("build runner", slog.Any("err", err))
+				return err
+			}
+			defer runner.Close()
+			plan, err := runner.Plan(context.Background(), target)
+			if err != nil {
+				logger.Error("plan failed", slog.Any("err", err))
+				return err
+			}
+			return writeOutput(opts, plan, func(w io.Writer) { printPlan(w, plan) })
+		},
+	}
+}
+
+func printPlan(w io.Writer, plan *stmigrate.Plan) {
+	fmt.Fprintf(w, "current version: %d\n", plan.Current)
+	fmt.Fprintf(w, "target version: %d\n", plan.Target)
+	if len(plan.Steps) == 0 {
+		fmt.Fprintln(w, "steps: none")
+	}
+	for _, step := range plan.Steps {
+		fmt.Fprintf(w, "%d %s (%s)\n", step.Version, step.Identifier, step.Direction)
+		printHeader(w, step.Header)
+		for _, action := range step.Actions {
+			if action.Seed != nil {
+				printSeed(w, action.Seed)
+				continue
+			}
+			if action.Rename != nil {
+				printRename(w, action.Rename)
+				continue
+			}
+			fmt.Fprintf(w, "  - %s %s", action.Role, action.Ensure)
+			if users, ok := step.AffectedUsers[action.Role]; ok && action.Ensure == "absent" {
+				fmt.Fprintf(w, " (%d users affected)", users)
+			}
+			if len(action.Add) > 0 {
+				fmt.Fprintf(w, " add=%v", action.Add)
+			}
+			if len(action.Remove) > 0 {
+				fmt.Fprintf(w, " remove=%v", action.Remove)
+			}
+			if action.Set != nil {
+				fmt.Fprintf(w, " set=%v", action.Set)
+			}
+			if action.Restore {
+				fmt.Fprint(w, " restore")
+			}
+			if len(action.Includes) > 0 {
+				fmt.Fprintf(w, " includes=%v", action.Includes)
+			}
+			if action.Propagate {
+				fmt.Fprint(w, " propagate")
+			}
+			if action.Tenant != "" && action.HasAssignments() {
+				fmt.Fprintf(w, " tenant=%s", action.Tenant)
+			}
+			if len(action.Assign) > 0 {
+				fmt.Fprintf(w, " assign=%v", action.Assign)
+			}
+			if len(action.Unassign) > 0 {
+				fmt.Fprintf(w, " unassign=%v", action.Unassign)
+			}
+			fmt.Fprintln(w)
+			if action.Description != "" {
+				fmt.Fprintf(w, "      %s\n", action.Description)
+			}
+		}
+		for _, warning := range step.Warnings {
+			fmt.Fprintf(w, "  warning: %s: %s\n", warning.Role, warning.Message)
+		}
+	}
+	printRolePermissions(w, "resulting state", plan.Result)
+}
+
+// printSeed prints a seed action; its rows are not listed.
+func printSeed(w io.Writer, seed *stmigrate.Seed) {
+	op := "assign"
+	if seed.Unassign {
+		op = "unassign"
+	}
+	fmt.Fprintf(w, "  - seed %s (%s %s, batch_size=%d)\n", seed.File, op, seed.Format, seed.BatchSize)
+}
+
+// printRename prints a rename action and, when listed, the tenants whose users move.
+func printRename(w io.Writer, rename *stmigrate.Rename) {
+	fmt.Fprintf(w, "  - rename %s -> %s", rename.From, rename.To)
+	if len(rename.Tenants) > 0 {
+		fmt.Fprintf(w, " (tenants=%v)", rename.Tenants)
+	}
+	fmt.Fprintln(w)
+}
+
+// printHeader prints the schema v2 header fields that are set.
+func printHeader(w io.Writer, header *stmigrate.Header) {
+	if header == nil {
+		return
+	}
+	if header.Description != "" {
+		fmt.Fprintf(w, "  description: %s\n", header.Description)
+	}
+	if header.Author != "" {
+		fmt.Fprintf(w, "  author: %s\n", header.Author)
+	}
+	if header.Ticket != "" {
+		fmt.Fprintf(w, "  ticket: %s\n", header.Ticket)
+	}
+}
+
+// errRoundTripFailed is returned by the test command when any migration fails to round-trip.
+var errRoundTripFailed = errors.New("round-trip test failed")
+
+func testCmd(opts *cliOpts) *cobra.Command {
+	return &cobra.Command{
+		Use:   "test",
+		Short: "Verify every down migration inverts its up migration using a simulated backend",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger(opts)
+			logger.Info("command: test", slog.String("source", opts.sourceURL))
+			runner, err := buildRunner(opts)
+			if err != nil {
+				logger.Error("build runner", slog.Any("err", err))
+				return err
+			}
+			defer runner.Close()
+			report, err := runner.RoundTrip(context.Background())
+			if err != nil {
+				logger.Error("round trip failed", slog.Any("err", err))
+				return err
+			}
+			if err := writeOutput(opts, report, func(w io.Writer) { printRoundTrip(w, report) }); err != nil {
+				return err
+			}
+			if len(report.Failed()) > 0 {
+				return errRoundTripFailed
+			}
+			return nil
+		},
+	}
+}
+
+func printRoundTrip(w io.Writer, report *stmigrate.RoundTripReport) {
+	for _, res := range report.Results {
+		if res.OK {
+			fmt.Fprintf(w, "ok   %d %s\n", res.Version, res.Identifier)
+			continue
+		}
+		fmt.Fprintf(w, "FAIL %d %s: %s\n", res.Version, res.Identifier, res.Error)
+		if res.Diff != nil {
+			printRolePermissions(w, "  left behind by down", res.Diff.ExtraPermissions)
+			printRolePermissions(w, "  lost by down", res.Diff.MissingPermissions)
+		}
+	}
+}
+
+// errDriftDetected is returned by the drift command so the process exits non-zero when drift exists.
+var errDriftDetected = errors.New("drift detected between migrations and live backend")
+
+func driftCmd(opts *cliOpts) *cobra.Command {
+	return &cobra.Command{
+		Use:   "drift",
+		Short: "Compare applied migrations with live SuperTokens roles/permissions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			logger := getLogger(opts)
+			logger.Info("command: drift", slog.String("source", opts.sourceURL), slog.String("database", opts.database), slog.String("state_file", opts.stateFile))
 			runner, err := buildRunner(opts)
 			if err != nil {
 				logger.Error("build runner", slog.Any("err", err))
 				return err
 			}
 			defer runner.Close()
-			if err := runner.Migrate(context.Background(), target); err != nil {
-				logger.Error("migrate failed", slog.Any("err", err))
+			report, err := runner.Drift(context.Background())
+			if err != nil {
+				logger.Error("drift failed", slog.Any("err", err))
+				return err
+			}
+			if err := writeOutput(opts, report, func(w io.Writer) { printDrift(w, report) }); err != nil {
 				return err
 			}
+			if report.HasDrift() {
+				return errDriftDetected
+			}
 			return nil
 		},
 	}
 }
 
+func printDrift(w io.Writer, report *stmigrate.DriftReport) {
+	fmt.Fprintf(w, "current version: %d\n", report.Version)
+	if !report.HasDrift() {
+		fmt.Fprintln(w, "drift: none")
+		return
+	}
+	fmt.Fprintf(w, "extra roles: %v\n", report.ExtraRoles)
+	fmt.Fprintf(w, "missing roles: %v\n", report.MissingRoles)
+	printRolePermissions(w, "extra permissions", report.ExtraPermissions)
+	printRolePermissions(w, "missing permissions", report.MissingPermissions)
+}
+
+func printRolePermissions(w io.Writer, label string, perms map[string][]string) {
+	if len(perms) == 0 {
+		fmt.Fprintf(w, "%s: none\n", label)
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", label)
+	roles := make([]string, 0, len(perms))
+	for role := range perms {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		fmt.Fprintf(w, "  %s: %v\n", role, perms[role])
+	}
+}
+
 func createCmd(opts *cliOpts) *cobra.Command {
+	var fromDrift bool
+	var strategy string
 	cmd := &cobra.Command{
 		Use:   "create <name>",
 		Short: "Create paired up/down migration files",
@@ -229,27 +790,65 @@ func createCmd(opts *cliOpts) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			logger := getLogger(opts)
 			dir := sourceURLToPath(opts.sourceURL)
-			opts := create.Options{
+			createOpts := create.Options{
 				Dir:           dir,
 				Name:          args[0],
 				Width:         opts.width,
 				SchemaVersion: opts.schemaVer,
 			}
-			logger.Info("command: create", slog.String("dir", dir), slog.String("name", args[0]), slog.Int("width", opts.Width), slog.Int("schema_version", opts.SchemaVersion))
-			up, down, err := create.Scaffold(opts)
+			logger.Info("command: create", slog.String("dir", dir), slog.String("name", args[0]), slog.Int("width", createOpts.Width), slog.Int("schema_version", createOpts.SchemaVersion), slog.Bool("from_drift", fromDrift))
+			if fromDrift {
+				if err := driftContent(opts, &createOpts, strategy); err != nil {
+					logger.Error("generate drift migration failed", slog.Any("err", err))
+					return err
+				}
+			}
+			up, down, err := create.Scaffold(createOpts)
 			if err != nil {
 				logger.Error("create scaffold failed", slog.Any("err", err))
 				return err
 			}
-			fmt.Fprintf(cmd.OutOrStdout(), "created %s\ncreated %s\n", up, down)
-			return nil
+			result := createResult{Up: up, Down: down}
+			return writeOutput(opts, result, func(w io.Writer) { fmt.Fprintf(w, "created %s\ncreated %s\n", up, down) })
 		},
 	}
 	cmd.Flags().IntVar(&opts.width, "digits", opts.width, "zero-pad width for version numbers")
 	cmd.Flags().IntVar(&opts.schemaVer, "schema-version", opts.schemaVer, "schema version to use in generated files")
+	cmd.Flags().BoolVar(&fromDrift, "from-drift", false, "generate the migration from detected drift against the live backend")
+	cmd.Flags().StringVar(&strategy, "strategy", create.StrategyAdopt, "drift reconciliation: adopt (record live changes) or revert (restore the expected model)")
 	return cmd
 }
 
+// createResult is the document printed by the create command.
+type createResult struct {
+	Up   string `json:"up"`
+	Down string `json:"down"`
+}
+
+// driftContent fills the create options with a migration pair reconciling detected drift.
+func driftContent(opts *cliOpts, createOpts *create.Options, strategy string) error {
+	runner, err := buildRunner(opts)
+	if err != nil {
+		return err
+	}
+	defer runner.Close()
+	report, err := runner.Drift(context.Background())
+	if err != nil {
+		return err
+	}
+	up, down, err := create.DriftSpecs(report.Diff, strategy)
+	if err != nil {
+		return err
+	}
+	if createOpts.UpContent, err = create.RenderSpec(up); err != nil {
+		return err
+	}
+	if createOpts.DownContent, err = create.RenderSpec(down); err != nil {
+		return err
+	}
+	return nil
+}
+
 // sourceURLToPath converts a file:// URL to a local path for create scaffolding.
 func sourceURLToPath(url string) string {
 	const prefix = "file://"
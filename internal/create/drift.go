@@ -0,0 +1,76 @@
+package create
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Drift reconciliation strategies.
+const (
+	// StrategyAdopt writes a migration that records live changes, bringing the expected model in line with live.
+	StrategyAdopt = "adopt"
+	// StrategyRevert writes a migration that undoes live changes, bringing live in line with the expected model.
+	StrategyRevert = "revert"
+)
+
+// DriftSpecs builds an up/down spec pair that reconciles a drift diff using the given strategy.
+// The down spec is the exact inverse of the up spec.
+func DriftSpecs(d model.Diff, strategy string) (up, down *schema.Spec, err error) {
+	if d.Empty() {
+		return nil, nil, fmt.Errorf("no drift to reconcile")
+	}
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "", StrategyAdopt:
+		return reconcileSpec(d, true), reconcileSpec(d, false), nil
+	case StrategyRevert:
+		return reconcileSpec(d, false), reconcileSpec(d, true), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown drift strategy %q (want %s or %s)", strategy, StrategyAdopt, StrategyRevert)
+	}
+}
+
+// reconcileSpec returns the actions moving from expected to live (toLive) or from live to expected.
+func reconcileSpec(d model.Diff, toLive bool) *schema.Spec {
+	created, deleted := d.ExtraRoles, d.MissingRoles
+	add, remove := d.ExtraPermissions, d.MissingPermissions
+	if !toLive {
+		created, deleted = d.MissingRoles, d.ExtraRoles
+		add, remove = d.MissingPermissions, d.ExtraPermissions
+	}
+	isCreated, isDeleted := toSet(created), toSet(deleted)
+
+	spec := &schema.Spec{Version: 1}
+	for _, role := range d.Roles() {
+		if _, ok := isDeleted[role]; ok {
+			spec.Actions = append(spec.Actions, schema.Action{Role: role, Ensure: "absent"})
+			continue
+		}
+		_, created := isCreated[role]
+		if !created && len(add[role]) == 0 && len(remove[role]) == 0 {
+			continue
+		}
+		spec.Actions = append(spec.Actions, schema.Action{Role: role, Ensure: "present", Add: add[role], Remove: remove[role]})
+	}
+	return spec
+}
+
+// RenderSpec encodes a spec as migration YAML.
+func RenderSpec(spec *schema.Spec) ([]byte, error) {
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("render spec: %w", err)
+	}
+	return data, nil
+}
+
+func toSet(vals []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(vals))
+	for _, v := range vals {
+		out[v] = struct{}{}
+	}
+	return out
+}
@@ -0,0 +1,80 @@
+package create
+
+import (
+	"os"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func driftFixture() (*model.State, *model.State) {
+	expected := model.FromMap(map[string][]string{
+		"admin":  {"a", "b"},
+		"editor": {"write"},
+	})
+	live := model.FromMap(map[string][]string{
+		"admin": {"a", "c"},
+		"rogue": {"x"},
+	})
+	return expected, live
+}
+
+func TestDriftSpecsAdoptRoundTrips(t *testing.T) {
+	expected, live := driftFixture()
+	up, down, err := DriftSpecs(model.Compare(expected, live), StrategyAdopt)
+	require.NoError(t, err)
+
+	state := expected.Clone()
+	state.Apply(up)
+	require.Equal(t, live.Map(), state.Map())
+	state.Apply(down)
+	require.Equal(t, expected.Map(), state.Map())
+}
+
+func TestDriftSpecsRevertRoundTrips(t *testing.T) {
+	expected, live := driftFixture()
+	up, down, err := DriftSpecs(model.Compare(expected, live), StrategyRevert)
+	require.NoError(t, err)
+
+	state := live.Clone()
+	state.Apply(up)
+	require.Equal(t, expected.Map(), state.Map())
+	state.Apply(down)
+	require.Equal(t, live.Map(), state.Map())
+}
+
+func TestDriftSpecsRejectsEmptyAndUnknownStrategy(t *testing.T) {
+	expected, live := driftFixture()
+	_, _, err := DriftSpecs(model.Compare(expected, expected), StrategyAdopt)
+	require.Error(t, err)
+	_, _, err = DriftSpecs(model.Compare(expected, live), "sideways")
+	require.Error(t, err)
+}
+
+func TestScaffoldWritesRenderedDriftSpecs(t *testing.T) {
+	expected, live := driftFixture()
+	up, down, err := DriftSpecs(model.Compare(expected, live), StrategyAdopt)
+	require.NoError(t, err)
+	upData, err := RenderSpec(up)
+	require.NoError(t, err)
+	downData, err := RenderSpec(down)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	upPath, downPath, err := Scaffold(Options{Dir: dir, Name: "drift", UpContent: upData, DownContent: downData})
+	require.NoError(t, err)
+
+	written, err := os.ReadFile(upPath)
+	require.NoError(t, err)
+	parsed, err := schema.DefaultRegistry().Parse(written)
+	require.NoError(t, err)
+	state := expected.Clone()
+	state.Apply(parsed)
+	require.Equal(t, live.Map(), state.Map())
+
+	written, err = os.ReadFile(downPath)
+	require.NoError(t, err)
+	require.Equal(t, downData, written)
+}
@@ -17,6 +17,9 @@ type Options struct {
 	Name          string
 	Width         int // zero -> default 4
 	SchemaVersion int // defaults to 1
+	// UpContent and DownContent replace the generated templates when set.
+	UpContent   []byte
+	DownContent []byte
 }
 
 // Scaffold writes paired up/down YAML files with the next sequential version.
@@ -59,11 +62,38 @@ actions:
     ensure: absent
 `, opts.SchemaVersion)
 
-	if err := os.WriteFile(upPath, []byte(upContent), 0o644); err != nil {
+	if opts.SchemaVersion >= 2 {
+		header := fmt.Sprintf("description: %q\nauthor: \"\"\nticket: \"\"\n", opts.Name)
+		upContent = fmt.Sprintf(`version: %d
+%sactions:
+  - role: example:role
+    ensure: present
+    description: ""
+    owner: ""
+    tags: []
+    add: []
+    remove: []
+`, opts.SchemaVersion, header)
+		downContent = fmt.Sprintf(`version: %d
+%sactions:
+  - role: example:role
+    ensure: absent
+`, opts.SchemaVersion, header)
+	}
+
+	upData, downData := []byte(upContent), []byte(downContent)
+	if opts.UpContent != nil {
+		upData = opts.UpContent
+	}
+	if opts.DownContent != nil {
+		downData = opts.DownContent
+	}
+
+	if err := os.WriteFile(upPath, upData, 0o644); err != nil {
 		slog.Error("write up migration", slog.String("path", upPath), slog.Any("err", err))
 		return "", "", err
 	}
-	if err := os.WriteFile(downPath, []byte(downContent), 0o644); err != nil {
+	if err := os.WriteFile(downPath, downData, 0o644); err != nil {
 		slog.Error("write down migration", slog.String("path", downPath), slog.Any("err", err))
 		return "", "", err
 	}
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,6 +22,9 @@ func TestScaffoldCreatesPairedFiles(t *testing.T) {
 	data, err := os.ReadFile(up)
 	require.NoError(t, err)
 	require.Contains(t, string(data), "version: 2")
+	spec, err := schema.DefaultRegistry().Parse(data)
+	require.NoError(t, err)
+	require.Equal(t, "Add Logs", spec.Header.Description)
 }
 
 func TestScaffoldIncrementsVersion(t *testing.T) {
@@ -0,0 +1,38 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// applyAssignments gives and removes the action's role for its listed users. It needs an
+// executor implementing executor.Assigner.
+func (r *Runner) applyAssignments(ctx context.Context, exec executor.Executor, action schema.Action) error {
+	if !action.HasAssignments() {
+		return nil
+	}
+	assigner, ok := exec.(executor.Assigner)
+	if !ok {
+		err := fmt.Errorf("executor %T cannot assign roles to users", exec)
+		r.logger.Error("assign role", slog.String("role", action.Role), slog.Any("err", err))
+		return &executor.Error{Role: action.Role, Op: executor.OpAssignRole, Err: err}
+	}
+	for _, a := range model.Assignments(action, action.Assign) {
+		if err := assigner.AssignRole(ctx, a.Tenant, a.User, a.Role); err != nil {
+			r.logger.Error("assign role", slog.String("role", a.Role), slog.String("tenant", a.Tenant), slog.String("user", a.User), slog.Any("err", err))
+			return &executor.Error{Role: a.Role, Op: executor.OpAssignRole, Err: err}
+		}
+	}
+	for _, a := range model.Assignments(action, action.Unassign) {
+		if err := assigner.UnassignRole(ctx, a.Tenant, a.User, a.Role); err != nil {
+			r.logger.Error("unassign role", slog.String("role", a.Role), slog.String("tenant", a.Tenant), slog.String("user", a.User), slog.Any("err", err))
+			return &executor.Error{Role: a.Role, Op: executor.OpUnassignRole, Err: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func assignMigrations(down string) []Migration {
+	return []Migration{{
+		Version:    1,
+		Identifier: "bootstrap_admins",
+		Up:         []byte("version: 1\nactions:\n  - role: admin\n    add: [all]\n    assign: [u1]\n  - role: admin\n    tenant: tenant-a\n    assign: [u2]\n"),
+		Down:       []byte(down),
+	}}
+}
+
+const assignDown = "version: 1\nactions:\n  - role: admin\n    unassign: [u1]\n  - role: admin\n    tenant: tenant-a\n    unassign: [u2]\n  - role: admin\n    ensure: absent\n"
+
+func TestRunnerAssignsAndUnassignsRoles(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, assignMigrations(assignDown))
+
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []executor.Assignment{
+		{Tenant: executor.DefaultTenant, User: "u1", Role: "admin"},
+		{Tenant: "tenant-a", User: "u2", Role: "admin"},
+	}, exec.Assignments)
+
+	// the down file unassigns u1 before deleting admin, so the role is no longer in use
+	_, err = r.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Empty(t, exec.Assignments)
+	require.Equal(t, []string{"admin"}, exec.RolesDeleted)
+}
+
+type plainExecutor struct{ executor.Executor }
+
+func TestRunnerAssignRequiresAssigner(t *testing.T) {
+	r := NewRunner(memory.New(), plainExecutor{executor.NewMock()}, schema.DefaultRegistry(), nil, false, assignMigrations(assignDown))
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, executor.ErrExecutor)
+	require.ErrorContains(t, err, "cannot assign roles to users")
+}
+
+func TestRoundTripDetectsMissingUnassign(t *testing.T) {
+	ms := []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: admin\n"), Down: []byte("version: 1\nactions:\n  - role: admin\n    ensure: absent\n")},
+		{Version: 2, Up: []byte("version: 1\nactions:\n  - role: admin\n    assign: [u1]\n"), Down: []byte("version: 1\nactions: []\n")},
+	}
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Failed(), 1)
+	require.Equal(t, uint(2), report.Failed()[0].Version)
+	require.Contains(t, report.Failed()[0].Error, "user role assignments")
+
+	r = NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, assignMigrations(assignDown))
+	report, err = r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Failed())
+}
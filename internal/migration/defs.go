@@ -0,0 +1,241 @@
+package migration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefsFile is the shared definitions file read from the migrations directory.
+const DefsFile = "_defs.yaml"
+
+// Defs holds the shared definitions migrations may reference. PermissionSets maps a name to
+// its permissions; migrations reference a set as $name in add, remove and set lists, and a
+// set may itself list other sets.
+type Defs struct {
+	PermissionSets map[string][]string `yaml:"permission_sets"`
+}
+
+// LoadDefs reads DefsFile from fsys and checks that every permission set resolves. A nil fsys
+// or a missing file yields empty definitions.
+func LoadDefs(fsys fs.FS) (*Defs, error) {
+	defs := &Defs{PermissionSets: map[string][]string{}}
+	if fsys == nil {
+		return defs, nil
+	}
+	data, err := fs.ReadFile(fsys, DefsFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return defs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", DefsFile, err)
+	}
+	if err := yaml.Unmarshal(data, defs); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", DefsFile, err)
+	}
+	if defs.PermissionSets == nil {
+		defs.PermissionSets = map[string][]string{}
+	}
+	var errs error
+	for _, name := range defs.names() {
+		if _, err := defs.PermissionSet(name); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: permission set %s: %w", DefsFile, name, err))
+		}
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return defs, nil
+}
+
+// PermissionSet returns the permissions of the named set in declaration order, with nested
+// sets expanded in place and duplicates dropped.
+func (d *Defs) PermissionSet(name string) ([]string, error) {
+	var out []string
+	seen := map[string]struct{}{}
+	if err := d.collect(name, nil, seen, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (d *Defs) collect(name string, stack []string, seen map[string]struct{}, out *[]string) error {
+	for _, s := range stack {
+		if s == name {
+			return fmt.Errorf("permission sets form a cycle: $%s", strings.Join(append(stack, name), " -> $"))
+		}
+	}
+	perms, ok := d.PermissionSets[name]
+	if !ok {
+		return d.undefined(name)
+	}
+	for _, p := range perms {
+		p = strings.TrimSpace(p)
+		if ref, isRef := setRef(p); isRef {
+			if err := d.collect(ref, append(stack, name), seen, out); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, dup := seen[p]; dup || p == "" {
+			continue
+		}
+		seen[p] = struct{}{}
+		*out = append(*out, p)
+	}
+	return nil
+}
+
+func (d *Defs) undefined(name string) error {
+	names := d.names()
+	if len(names) == 0 {
+		return fmt.Errorf("undefined permission set $%s (no %s in the source)", name, DefsFile)
+	}
+	return fmt.Errorf("undefined permission set $%s (%s defines $%s)", name, DefsFile, strings.Join(names, ", $"))
+}
+
+func (d *Defs) names() []string {
+	names := make([]string, 0, len(d.PermissionSets))
+	for name := range d.PermissionSets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandDefs makes every migration resolve its permission set references when it is parsed.
+// The documents keep their bytes; the sets a migration's up document references are
+// checksummed with it, so editing a set marks it as modified. All undefined references are
+// reported as ParseErrors.
+func ExpandDefs(migrations []Migration, defs *Defs, logger *slog.Logger) error {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	var errs error
+	for i := range migrations {
+		m := &migrations[i]
+		found := false
+		for _, direction := range []string{DirectionUp, DirectionDown} {
+			refs, err := defs.references(m.Document(direction))
+			if err != nil {
+				logger.Error("expand permission sets", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.Any("err", err))
+				errs = errors.Join(errs, &ParseError{File: m.Identifier, Version: m.Version, Direction: direction, Err: err})
+				continue
+			}
+			if len(refs) == 0 {
+				continue
+			}
+			found = true
+			if direction == DirectionUp {
+				m.resolved = append(m.resolved, defs.describe(refs)...)
+			}
+		}
+		if found {
+			m.defs = defs
+			logger.Debug("migration references permission sets", slog.Uint64("version", uint64(m.Version)))
+		}
+	}
+	return errs
+}
+
+// permissionKeys are the mapping keys whose lists may reference permission sets.
+var permissionKeys = map[string]bool{"add": true, "remove": true, "set": true}
+
+// references lists the sets doc references, in document order. Documents that do not decode
+// have none, so the schema parser reports the problem.
+func (d *Defs) references(doc []byte) ([]string, error) {
+	if !bytes.Contains(doc, []byte("$")) {
+		return nil, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(maskVars(doc), &root); err != nil {
+		return nil, nil
+	}
+	return d.expandNode(&root)
+}
+
+// expand replaces the references in a decoded document with the sets' permissions.
+func (d *Defs) expand(root *yaml.Node) error {
+	_, err := d.expandNode(root)
+	return err
+}
+
+// describe renders the permissions of the referenced sets, for checksumming.
+func (d *Defs) describe(refs []string) []byte {
+	var buf bytes.Buffer
+	for _, name := range refs {
+		perms, _ := d.PermissionSet(name)
+		fmt.Fprintf(&buf, "$%s: %s\n", name, strings.Join(perms, ", "))
+	}
+	return buf.Bytes()
+}
+
+// expandNode replaces the references under n and returns the names it replaced.
+func (d *Defs) expandNode(n *yaml.Node) ([]string, error) {
+	var refs []string
+	var errs error
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range n.Content {
+			r, err := d.expandNode(child)
+			refs = append(refs, r...)
+			errs = errors.Join(errs, err)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			var r []string
+			var err error
+			if permissionKeys[key.Value] && val.Kind == yaml.SequenceNode {
+				r, err = d.expandList(val)
+			} else {
+				r, err = d.expandNode(val)
+			}
+			refs = append(refs, r...)
+			errs = errors.Join(errs, err)
+		}
+	}
+	return refs, errs
+}
+
+// setRef returns the set name of a $name reference; ${...} templates and $${ escapes are not
+// references.
+func setRef(item string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(item), "$")
+	if !ok || strings.HasPrefix(name, "{") || strings.HasPrefix(name, "${") {
+		return "", false
+	}
+	return name, true
+}
+
+// expandList replaces $name items of a permission list with the set's permissions, placed at
+// the reference's position so problems with them are reported there.
+func (d *Defs) expandList(seq *yaml.Node) ([]string, error) {
+	items := make([]*yaml.Node, 0, len(seq.Content))
+	var refs []string
+	var errs error
+	for _, item := range seq.Content {
+		name, isRef := setRef(item.Value)
+		if item.Kind != yaml.ScalarNode || !isRef {
+			items = append(items, item)
+			continue
+		}
+		perms, err := d.PermissionSet(name)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("line %d: %w", item.Line, err))
+			continue
+		}
+		for _, p := range perms {
+			items = append(items, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: p, Line: item.Line, Column: item.Column})
+		}
+		refs = append(refs, name)
+	}
+	seq.Content = items
+	return refs, errs
+}
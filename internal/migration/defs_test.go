@@ -0,0 +1,102 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"testing/fstest"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/stretchr/testify/require"
+)
+
+const defsYAML = "permission_sets:\n  billing_read: [billing:read, billing:export]\n  billing_all: [$billing_read, billing:write, billing:read]\n"
+
+func TestExpandDefsResolvesPermissionSets(t *testing.T) {
+	defs, err := LoadDefs(fstest.MapFS{DefsFile: {Data: []byte(defsYAML)}})
+	require.NoError(t, err)
+	plain := []byte("version: 1\nactions:\n  - role: viewer\n    add: [docs:read]\n")
+	migrations := []Migration{
+		{Version: 1, Up: plain},
+		{
+			Version: 2,
+			Up:      []byte("version: 2\nactions:\n  - role: billing\n    add:\n      - $billing_all\n      - name: billing:refund\n        description: Refund invoices\n"),
+			Down:    []byte("version: 1\nactions:\n  - role: billing\n    remove: [$billing_read]\n"),
+		},
+	}
+
+	require.NoError(t, ExpandDefs(migrations, defs, nil))
+	require.Equal(t, plain, migrations[0].Up, "documents keep their bytes")
+	require.Equal(t, sha256Hex(plain), migrations[0].Checksum(), "documents without references keep their checksums")
+	spec, err := migrations[1].Parse(schema.DefaultRegistry(), DirectionUp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"billing:read", "billing:export", "billing:write", "billing:refund"}, spec.Actions[0].Add)
+	require.Equal(t, []schema.Permission{{Name: "billing:refund", Description: "Refund invoices"}}, spec.Actions[0].Permissions)
+	spec, err = migrations[1].Parse(schema.DefaultRegistry(), DirectionDown)
+	require.NoError(t, err)
+	require.Equal(t, []string{"billing:read", "billing:export"}, spec.Actions[0].Remove)
+
+	checksum := migrations[1].Checksum()
+	again := []Migration{{Version: 2, Up: []byte("version: 2\nactions:\n  - role: billing\n    add:\n      - $billing_all\n      - name: billing:refund\n        description: Refund invoices\n")}}
+	require.NoError(t, ExpandDefs(again, defs, nil))
+	require.Equal(t, checksum, again[0].Checksum(), "expansion is deterministic")
+
+	edited, err := LoadDefs(fstest.MapFS{DefsFile: {Data: []byte(defsYAML + "  unused: [x]\n")}})
+	require.NoError(t, err)
+	again = []Migration{{Version: 2, Up: again[0].Up}}
+	require.NoError(t, ExpandDefs(again, edited, nil))
+	require.Equal(t, checksum, again[0].Checksum(), "sets the document does not reference do not count")
+	edited.PermissionSets["billing_read"] = []string{"billing:read"}
+	again = []Migration{{Version: 2, Up: again[0].Up}}
+	require.NoError(t, ExpandDefs(again, edited, nil))
+	require.NotEqual(t, checksum, again[0].Checksum(), "editing a referenced set changes the checksum")
+}
+
+func TestExpandDefsKeepsProblemPositions(t *testing.T) {
+	defs, err := LoadDefs(fstest.MapFS{DefsFile: {Data: []byte(defsYAML)}})
+	require.NoError(t, err)
+	migrations := []Migration{{
+		Version: 1,
+		Up: []byte("version: 1\nactions:\n  - role: billing\n    # every billing permission\n    add:\n" +
+			"      - $billing_all\n      - billing:refund\n  - role: support\n    ad: [tickets:read]\n"),
+	}}
+	require.NoError(t, ExpandDefs(migrations, defs, nil))
+
+	_, err = migrations[0].Parse(schema.DefaultRegistry(), DirectionUp)
+	require.EqualError(t, err, `schema v1: line 9, column 5: unknown field "ad" in action (did you mean "add"?)`)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExpandDefsReportsUndefinedReferences(t *testing.T) {
+	defs, err := LoadDefs(fstest.MapFS{DefsFile: {Data: []byte(defsYAML)}})
+	require.NoError(t, err)
+	migrations := []Migration{{
+		Version:    3,
+		Identifier: "billing",
+		Up:         []byte("version: 1\nactions:\n  - role: billing\n    add: [$billing_al, \"${each.key}:read\"]\n    remove:\n      - $nope\n"),
+	}}
+
+	err = ExpandDefs(migrations, defs, nil)
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorContains(t, err, "parse migration 3 (billing, up): line 4: undefined permission set $billing_al ("+DefsFile+" defines $billing_all, $billing_read)")
+	require.ErrorContains(t, err, "line 6: undefined permission set $nope")
+
+	none, err := LoadDefs(nil)
+	require.NoError(t, err)
+	err = ExpandDefs(migrations, none, nil)
+	require.ErrorContains(t, err, "undefined permission set $billing_al (no "+DefsFile+" in the source)")
+}
+
+func TestLoadDefsRejectsBrokenSets(t *testing.T) {
+	_, err := LoadDefs(fstest.MapFS{DefsFile: {Data: []byte("permission_sets:\n  a: [$b]\n  b: [x, $a]\n  c: [$missing]\n")}})
+	require.ErrorContains(t, err, "permission set a: permission sets form a cycle: $a -> $b -> $a")
+	require.ErrorContains(t, err, "permission set c: undefined permission set $missing")
+
+	defs, err := LoadDefs(fstest.MapFS{})
+	require.NoError(t, err)
+	require.Empty(t, defs.PermissionSets)
+}
@@ -0,0 +1,98 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+)
+
+// DriftReport compares the role/permission model implied by applied migrations with the live backend.
+type DriftReport struct {
+	Version int  `json:"version"`
+	Dirty   bool `json:"dirty"`
+	model.Diff
+}
+
+// HasDrift reports whether the live backend deviates from the expected model.
+func (d *DriftReport) HasDrift() bool {
+	return !d.Diff.Empty()
+}
+
+// Drift replays migrations up to the current version in memory and compares the
+// resulting model with the live state read from the executor.
+func (r *Runner) Drift(ctx context.Context) (*DriftReport, error) {
+	reader, ok := r.exec.(executor.Reader)
+	if !ok {
+		r.logger.Error("executor cannot read live state", slog.String("executor", fmt.Sprintf("%T", r.exec)))
+		return nil, fmt.Errorf("executor %T does not support reading live state", r.exec)
+	}
+
+	current, dirty, err := r.store.Version(ctx)
+	if err != nil {
+		r.logger.Error("read version", slog.Any("err", err))
+		return nil, err
+	}
+	if current < 0 {
+		current = 0
+	}
+	if dirty {
+		r.logger.Warn("state is dirty; drift is computed against the last recorded version", slog.Int("current", current))
+	}
+
+	expected, err := r.replay(uint(current))
+	if err != nil {
+		return nil, err
+	}
+	live, err := readLive(ctx, reader)
+	if err != nil {
+		r.logger.Error("read live state", slog.Any("err", err))
+		return nil, fmt.Errorf("read live state: %w", err)
+	}
+
+	report := &DriftReport{Version: current, Dirty: dirty, Diff: model.Compare(expected, live)}
+	r.logger.Info("drift check complete",
+		slog.Int("version", current),
+		slog.Bool("drift", report.HasDrift()),
+		slog.Int("extra_roles", len(report.ExtraRoles)),
+		slog.Int("missing_roles", len(report.MissingRoles)),
+		slog.Int("roles_with_extra_permissions", len(report.ExtraPermissions)),
+		slog.Int("roles_with_missing_permissions", len(report.MissingPermissions)),
+	)
+	return report, nil
+}
+
+// replay builds the expected model by applying every up migration with version <= target.
+func (r *Runner) replay(target uint) (*model.State, error) {
+	state := model.New()
+	for _, m := range r.migrations {
+		if m.Version > target {
+			break
+		}
+		spec, err := r.parse(m, DirectionUp)
+		if err != nil {
+			return nil, err
+		}
+		state.Apply(spec)
+	}
+	return state, nil
+}
+
+func readLive(ctx context.Context, reader executor.Reader) (*model.State, error) {
+	roles, err := reader.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	live := model.New()
+	for _, role := range roles {
+		perms, err := reader.ListPermissions(ctx, role)
+		if err != nil {
+			return nil, fmt.Errorf("list permissions for %s: %w", role, err)
+		}
+		live.EnsureRole(role)
+		live.AddPermissions(role, perms)
+	}
+	return live, nil
+}
@@ -0,0 +1,72 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+type writeOnlyExecutor struct{}
+
+func (writeOnlyExecutor) EnsureRole(context.Context, string) error                  { return nil }
+func (writeOnlyExecutor) DeleteRole(context.Context, string) error                  { return nil }
+func (writeOnlyExecutor) AddPermissions(context.Context, string, []string) error    { return nil }
+func (writeOnlyExecutor) RemovePermissions(context.Context, string, []string) error { return nil }
+
+func driftMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: admin\n    add: [a, b]\n"), Down: []byte("version: 1\nactions:\n  - role: admin\n    ensure: absent\n")},
+		{Version: 2, Up: []byte("version: 1\nactions:\n  - role: viewer\n    add: [read]\n"), Down: []byte("version: 1\nactions:\n  - role: viewer\n    ensure: absent\n")},
+	}
+}
+
+func TestRunnerDriftCleanAfterUp(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, driftMigrations())
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+
+	report, err := r.Drift(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Version)
+	require.False(t, report.HasDrift())
+}
+
+func TestRunnerDriftDetectsOutOfBandChanges(t *testing.T) {
+	exec := executor.NewMock()
+	target := uint(1)
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, driftMigrations())
+	_, err := r.Up(context.Background(), &target)
+	require.NoError(t, err)
+
+	// simulate dashboard edits
+	exec.Live["admin"] = []string{"a", "c"}
+	exec.Live["rogue"] = []string{}
+
+	report, err := r.Drift(context.Background())
+	require.NoError(t, err)
+	require.True(t, report.HasDrift())
+	require.Equal(t, []string{"rogue"}, report.ExtraRoles)
+	require.Empty(t, report.MissingRoles)
+	require.Equal(t, map[string][]string{"admin": {"c"}}, report.ExtraPermissions)
+	require.Equal(t, map[string][]string{"admin": {"b"}}, report.MissingPermissions)
+}
+
+func TestRunnerDriftRequiresReader(t *testing.T) {
+	r := NewRunner(memory.New(), writeOnlyExecutor{}, schema.DefaultRegistry(), nil, false, driftMigrations())
+	_, err := r.Drift(context.Background())
+	require.Error(t, err)
+}
+
+func TestRunnerDriftPropagatesReadError(t *testing.T) {
+	exec := executor.NewMock()
+	exec.FailWith = errors.New("boom")
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, driftMigrations())
+	_, err := r.Drift(context.Background())
+	require.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrDirty signals the store is marked dirty after a failed migration.
+	// Use errors.As with *DirtyError to get the affected version.
+	ErrDirty = errors.New("state is dirty")
+	// ErrVersionNotFound signals a requested or recorded version has no migration in the source.
+	ErrVersionNotFound = errors.New("migration version not found")
+	// ErrParse signals a migration document could not be parsed.
+	// Use errors.As with *ParseError to get the file and version.
+	ErrParse = errors.New("parse migration")
+)
+
+// DirtyError reports the version the store was left dirty at.
+type DirtyError struct {
+	Version int
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("state is dirty at version %d; resolve before running migrations", e.Version)
+}
+
+// Is matches ErrDirty.
+func (e *DirtyError) Is(target error) bool {
+	return target == ErrDirty
+}
+
+// ParseError reports a migration document that failed to parse.
+type ParseError struct {
+	// File is the source identifier of the migration (for file sources, the name without version prefix).
+	File      string
+	Version   uint
+	Direction string
+	Err       error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse migration %d (%s, %s): %v", e.Version, e.File, e.Direction, e.Err)
+}
+
+// Is matches ErrParse.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrParse
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+// HistoryEntry is an applied migration as recorded by the store.
+// Identifier is empty when the migration no longer exists in the source.
+type HistoryEntry struct {
+	Version    uint      `json:"version"`
+	Identifier string    `json:"identifier"`
+	AppliedAt  time.Time `json:"applied_at"`
+	Checksum   string    `json:"checksum"`
+	// Modified reports that the source changed since the migration was applied.
+	Modified bool `json:"modified"`
+}
+
+// History lists applied migrations in the order they were applied.
+// The store must keep history (the file and memory stores do).
+func (r *Runner) History(ctx context.Context) ([]HistoryEntry, error) {
+	hs, ok := r.store.(store.HistoryStore)
+	if !ok {
+		r.logger.Error("history unsupported", slog.String("store", fmt.Sprintf("%T", r.store)))
+		return nil, fmt.Errorf("state store %T does not record history", r.store)
+	}
+	records, err := hs.History(ctx)
+	if err != nil {
+		r.logger.Error("read history", slog.Any("err", err))
+		return nil, err
+	}
+	idx := indexByVersion(r.migrations)
+	out := make([]HistoryEntry, 0, len(records))
+	for _, rec := range records {
+		entry := HistoryEntry{Version: rec.Version, AppliedAt: rec.AppliedAt, Checksum: rec.Checksum}
+		if m, ok := idx[rec.Version]; ok {
+			entry.Identifier = m.Identifier
+			entry.Modified = rec.Checksum != "" && rec.Checksum != m.Checksum()
+		}
+		out = append(out, entry)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].AppliedAt.Equal(out[j].AppliedAt) {
+			return out[i].AppliedAt.Before(out[j].AppliedAt)
+		}
+		return out[i].Version < out[j].Version
+	})
+	return out, nil
+}
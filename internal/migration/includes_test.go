@@ -0,0 +1,111 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func includeMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Up:      []byte("version: 1\nactions:\n  - role: viewer\n    add: [docs:read]\n"),
+			Down:    []byte("version: 1\nactions:\n  - role: viewer\n    ensure: absent\n"),
+		},
+		{
+			Version: 2,
+			Up:      []byte("version: 1\nactions:\n  - role: editor\n    includes: [viewer]\n    add: [docs:write]\n"),
+			Down:    []byte("version: 1\nactions:\n  - role: editor\n    ensure: absent\n"),
+		},
+		{
+			Version: 3,
+			Up:      []byte("version: 1\nactions:\n  - role: viewer\n    add: [docs:export]\n    propagate: true\n"),
+			Down:    []byte("version: 1\nactions:\n  - role: viewer\n    remove: [docs:export]\n    propagate: true\n"),
+		},
+	}
+}
+
+func TestRunnerExpandsIncludesAndPropagates(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, includeMigrations())
+
+	two := uint(2)
+	_, err := r.Up(context.Background(), &two)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"docs:read", "docs:write"}, exec.Live["editor"])
+
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"docs:read", "docs:export"}, exec.Live["viewer"])
+	require.ElementsMatch(t, []string{"docs:read", "docs:write", "docs:export"}, exec.Live["editor"])
+
+	drift, err := r.Drift(context.Background())
+	require.NoError(t, err)
+	require.False(t, drift.HasDrift(), "the replayed model expands includes the same way")
+
+	_, err = r.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"docs:read", "docs:write"}, exec.Live["editor"])
+}
+
+func TestPlanShowsExpandedIncludes(t *testing.T) {
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, includeMigrations())
+
+	plan, err := r.Plan(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 3)
+	require.Equal(t, []string{"docs:write", "docs:read"}, plan.Steps[1].Actions[0].Add)
+	require.Len(t, plan.Steps[2].Actions, 2, "the propagated change is listed for editor")
+	require.Equal(t, "editor", plan.Steps[2].Actions[1].Role)
+	require.Equal(t, []string{"docs:export", "docs:read", "docs:write"}, plan.Result["editor"])
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Failed())
+}
+
+func TestRunnerRejectsUnknownIncludes(t *testing.T) {
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, []Migration{{
+		Version: 1,
+		Up:      []byte("version: 1\nactions:\n  - role: editor\n    includes: [viewer]\n"),
+	}})
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorContains(t, err, "role editor includes unknown role viewer")
+}
+
+func TestRunnerRejectsIncludesWithSet(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, []Migration{
+		includeMigrations()[0],
+		{Version: 2, Up: []byte("version: 1\nactions:\n  - role: editor\n    includes: [viewer]\n    set: [docs:write]\n")},
+	})
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorContains(t, err, "cannot combine includes or propagate with set or restore")
+	require.NotContains(t, exec.Live, "editor")
+}
+
+func TestRunnerSetAfterIncludesHasNoDrift(t *testing.T) {
+	exec := executor.NewMock()
+	ms := append(includeMigrations()[:2], Migration{
+		Version: 3,
+		Up:      []byte("version: 1\nactions:\n  - role: editor\n    set: [docs:write]\n"),
+	})
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, ms)
+
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs:write"}, exec.Live["editor"])
+
+	drift, err := r.Drift(context.Background())
+	require.NoError(t, err)
+	require.False(t, drift.HasDrift())
+}
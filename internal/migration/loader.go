@@ -2,6 +2,7 @@ package migration
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"io/fs"
 	"log/slog"
@@ -35,25 +36,32 @@ func LoadAll(src source.Driver, logger *slog.Logger) ([]Migration, error) {
 		up, ident, err := src.ReadUp(version)
 		if err != nil {
 			logger.Error("read up migration", slog.Uint64("version", uint64(version)), slog.Any("err", err))
-			return nil, err
+			return nil, fmt.Errorf("read up migration %d: %w", version, err)
 		}
 		upBytes, err := io.ReadAll(up)
 		up.Close()
 		if err != nil {
 			logger.Error("read up content", slog.Uint64("version", uint64(version)), slog.Any("err", err))
-			return nil, err
+			return nil, fmt.Errorf("read up migration %d: %w", version, err)
 		}
 
+		var downBytes []byte
+		missingDown := false
 		down, _, err := src.ReadDown(version)
-		if err != nil {
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			logger.Warn("down migration missing", slog.Uint64("version", uint64(version)), slog.String("identifier", ident))
+			missingDown = true
+		case err != nil:
 			logger.Error("read down migration", slog.Uint64("version", uint64(version)), slog.Any("err", err))
-			return nil, err
-		}
-		downBytes, err := io.ReadAll(down)
-		down.Close()
-		if err != nil {
-			logger.Error("read down content", slog.Uint64("version", uint64(version)), slog.Any("err", err))
-			return nil, err
+			return nil, fmt.Errorf("read down migration %d: %w", version, err)
+		default:
+			downBytes, err = io.ReadAll(down)
+			down.Close()
+			if err != nil {
+				logger.Error("read down content", slog.Uint64("version", uint64(version)), slog.Any("err", err))
+				return nil, fmt.Errorf("read down migration %d: %w", version, err)
+			}
 		}
 
 		if _, exists := seen[version]; exists {
@@ -63,10 +71,11 @@ func LoadAll(src source.Driver, logger *slog.Logger) ([]Migration, error) {
 
 		logger.Debug("loaded migration", slog.Uint64("version", uint64(version)), slog.String("identifier", ident))
 		migrations = append(migrations, Migration{
-			Version:    version,
-			Identifier: ident,
-			Up:         upBytes,
-			Down:       downBytes,
+			Version:     version,
+			Identifier:  ident,
+			Up:          upBytes,
+			Down:        downBytes,
+			MissingDown: missingDown,
 		})
 
 		next, err := src.Next(version)
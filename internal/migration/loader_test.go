@@ -105,3 +105,21 @@ func TestLoadAllNextError(t *testing.T) {
 	_, err := LoadAll(src, nil)
 	require.Error(t, err)
 }
+
+func TestLoadAllToleratesMissingDown(t *testing.T) {
+	src := stubSource{
+		firstFn: func() (uint, error) { return 1, nil },
+		readUpFn: func(uint) (io.ReadCloser, string, error) {
+			return io.NopCloser(bytes.NewReader([]byte("up"))), "only_up", nil
+		},
+		readDownFn: func(uint) (io.ReadCloser, string, error) {
+			return nil, "", &fs.PathError{Op: "read down", Path: "1", Err: fs.ErrNotExist}
+		},
+		nextFn: func(uint) (uint, error) { return 0, fs.ErrNotExist },
+	}
+	ms, err := LoadAll(src, nil)
+	require.NoError(t, err)
+	require.Len(t, ms, 1)
+	require.True(t, ms[0].MissingDown)
+	require.Equal(t, "only_up", ms[0].Identifier)
+}
@@ -0,0 +1,41 @@
+package migration
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// writeMetadata stores schema v2 role metadata when the executor's backend can keep it.
+func (r *Runner) writeMetadata(ctx context.Context, exec executor.Executor, action schema.Action) error {
+	if !action.HasMetadata() {
+		return nil
+	}
+	writer, ok := exec.(executor.MetadataWriter)
+	if !ok {
+		r.logger.Debug("executor cannot store role metadata", slog.String("role", action.Role))
+		return nil
+	}
+	if err := writer.SetRoleMetadata(ctx, action.Role, roleMetadata(action)); err != nil {
+		r.logger.Error("set role metadata", slog.String("role", action.Role), slog.Any("err", err))
+		return &executor.Error{Role: action.Role, Op: executor.OpSetMetadata, Err: err}
+	}
+	return nil
+}
+
+func roleMetadata(action schema.Action) executor.RoleMetadata {
+	meta := executor.RoleMetadata{
+		Description: action.Description,
+		Owner:       action.Owner,
+		Tags:        append([]string(nil), action.Tags...),
+	}
+	if len(action.Permissions) > 0 {
+		meta.Permissions = make(map[string]string, len(action.Permissions))
+		for _, p := range action.Permissions {
+			meta.Permissions[p.Name] = p.Description
+		}
+	}
+	return meta
+}
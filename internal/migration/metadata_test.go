@@ -0,0 +1,54 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func v2Migrations() []Migration {
+	return []Migration{{
+		Version:    1,
+		Identifier: "support",
+		Up: []byte(`version: 2
+description: Introduce support role
+ticket: AUTH-12
+actions:
+  - role: app:support
+    owner: support-team
+    tags: [support]
+    add:
+      - name: ticket:read
+        description: Read support tickets
+`),
+		Down: []byte("version: 2\nactions:\n  - role: app:support\n    ensure: absent\n"),
+	}}
+}
+
+func TestRunnerStoresV2MetadataAndReportsHeader(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, v2Migrations())
+
+	report, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"ticket:read"}, exec.PermsAdded["app:support"])
+	require.Equal(t, executor.RoleMetadata{
+		Owner:       "support-team",
+		Tags:        []string{"support"},
+		Permissions: map[string]string{"ticket:read": "Read support tickets"},
+	}, exec.Metadata["app:support"])
+	require.Equal(t, &schema.Header{Description: "Introduce support role", Ticket: "AUTH-12"}, report.Entries[0].Header)
+
+	status, err := r.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "Introduce support role", status.Migrations[0].Header.Description)
+
+	target := uint(0)
+	plan, err := r.Plan(context.Background(), &target)
+	require.NoError(t, err)
+	require.Nil(t, plan.Steps[0].Header)
+}
@@ -1,9 +1,71 @@
 package migration
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"gopkg.in/yaml.v3"
+)
+
 // Migration holds both directions for a single version.
 type Migration struct {
 	Version    uint
 	Identifier string
 	Up         []byte
 	Down       []byte
+	// MissingDown is set when the source has no down file for this version.
+	MissingDown bool
+	// defs and vars, when set, are resolved on the decoded documents before they are parsed,
+	// permission sets first; see ExpandDefs and RenderVars.
+	defs *Defs
+	vars map[string]string
+	// resolved describes what they substitute into Up; it is checksummed with Up.
+	resolved []byte
+}
+
+// Checksum returns the hex-encoded SHA-256 of the up document, including the permission sets
+// and variables it references.
+func (m Migration) Checksum() string {
+	h := sha256.New()
+	h.Write(m.Up)
+	if len(m.resolved) > 0 {
+		h.Write([]byte{0})
+		h.Write(m.resolved)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Parse decodes the document for direction with reg, resolving the permission set and
+// variable references found by ExpandDefs and RenderVars.
+func (m Migration) Parse(reg *schema.Registry, direction string) (*schema.Spec, error) {
+	doc := m.Document(direction)
+	if m.defs == nil && m.vars == nil {
+		return reg.Parse(doc)
+	}
+	if m.vars != nil {
+		doc = maskVars(doc)
+	}
+	return reg.ParseWith(doc, m.resolve)
+}
+
+func (m Migration) resolve(root *yaml.Node) error {
+	if m.defs != nil {
+		if err := m.defs.expand(root); err != nil {
+			return err
+		}
+	}
+	if m.vars != nil {
+		var r rendering
+		return r.renderNode(root, m.vars)
+	}
+	return nil
+}
+
+// Document returns the raw document for the given direction (DirectionUp or DirectionDown).
+func (m Migration) Document(direction string) []byte {
+	if direction == DirectionDown {
+		return m.Down
+	}
+	return m.Up
 }
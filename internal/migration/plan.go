@@ -0,0 +1,138 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// PlanStep describes one migration that would run to reach the plan target.
+type PlanStep struct {
+	Version    uint            `json:"version"`
+	Identifier string          `json:"identifier"`
+	Direction  string          `json:"direction"`
+	Actions    []schema.Action `json:"actions"`
+	Warnings   []model.Warning `json:"warnings"`
+	// Header is the schema v2 migration header, when the document declares one.
+	Header *schema.Header `json:"header,omitempty"`
+	// AffectedUsers counts the users holding each role the step deletes, when the executor can tell.
+	AffectedUsers map[string]int `json:"affected_users,omitempty"`
+}
+
+// Plan is the simulated outcome of moving from the current version to a target.
+type Plan struct {
+	Current int                 `json:"current"`
+	Target  uint                `json:"target"`
+	Steps   []PlanStep          `json:"steps"`
+	Result  map[string][]string `json:"result"`
+}
+
+// Warnings returns the no-op warnings of every step in execution order.
+func (p *Plan) Warnings() []model.Warning {
+	out := make([]model.Warning, 0)
+	for _, step := range p.Steps {
+		out = append(out, step.Warnings...)
+	}
+	return out
+}
+
+// Plan simulates moving to the target version (latest when nil) without touching the
+// store or executor. The simulation starts from the model implied by the current version.
+func (r *Runner) Plan(ctx context.Context, target *uint) (*Plan, error) {
+	current, _, err := r.store.Version(ctx)
+	if err != nil {
+		r.logger.Error("read version", slog.Any("err", err))
+		return nil, err
+	}
+	if current < 0 {
+		current = 0
+	}
+
+	to := uint(current)
+	if target != nil {
+		to = *target
+	} else if len(r.migrations) > 0 {
+		to = r.migrations[len(r.migrations)-1].Version
+	}
+	if to > uint(current) && (len(r.migrations) == 0 || to > r.migrations[len(r.migrations)-1].Version) {
+		r.logger.Error("target version not found", slog.Uint64("target", uint64(to)))
+		return nil, fmt.Errorf("%w: target %d", ErrVersionNotFound, to)
+	}
+
+	seed, err := r.replay(uint(current))
+	if err != nil {
+		return nil, err
+	}
+	sim := model.NewSimulator(seed)
+	plan := &Plan{Current: current, Target: to, Steps: []PlanStep{}}
+
+	if to >= uint(current) {
+		for _, m := range r.migrations {
+			if int(m.Version) <= current || m.Version > to {
+				continue
+			}
+			if err := r.planStep(ctx, sim, plan, m, DirectionUp); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		idx := indexByVersion(r.migrations)
+		for v := uint(current); v > to; v = previousVersion(r.migrations, v) {
+			m, ok := idx[v]
+			if !ok {
+				r.logger.Warn("missing migration for rollback", slog.Uint64("version", uint64(v)))
+				return nil, fmt.Errorf("%w: %d has no migration to roll back", ErrVersionNotFound, v)
+			}
+			if m.MissingDown {
+				return nil, fmt.Errorf("migration %d has no down file", m.Version)
+			}
+			if err := r.planStep(ctx, sim, plan, m, DirectionDown); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	plan.Result = sim.State().Map()
+	r.logger.Info("plan complete", slog.Int("current", current), slog.Uint64("target", uint64(to)), slog.Int("steps", len(plan.Steps)), slog.Int("warnings", len(plan.Warnings())))
+	return plan, nil
+}
+
+func (r *Runner) planStep(ctx context.Context, sim *model.Simulator, plan *Plan, m Migration, direction string) error {
+	spec, err := r.parse(m, direction)
+	if err != nil {
+		return err
+	}
+	if spec, err = r.expand(sim, m, direction, spec); err != nil {
+		return err
+	}
+	if err := r.checkProtection(m, direction, spec); err != nil {
+		return err
+	}
+	if err := r.checkSetProtection(ctx, sim, m, direction, spec); err != nil {
+		return err
+	}
+	if err := r.applySpec(ctx, sim, m, direction, spec); err != nil {
+		return err
+	}
+	affected, err := r.affectedUsers(ctx, spec)
+	if err != nil {
+		r.logger.Warn("plan: cannot count users holding deleted roles", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+	}
+	var header *schema.Header
+	if !spec.Header.Empty() {
+		header = &spec.Header
+	}
+	plan.Steps = append(plan.Steps, PlanStep{
+		Version:       m.Version,
+		Identifier:    m.Identifier,
+		Direction:     direction,
+		Actions:       spec.Actions,
+		Warnings:      sim.TakeWarnings(),
+		AffectedUsers: affected,
+		Header:        header,
+	})
+	return nil
+}
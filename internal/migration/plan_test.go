@@ -0,0 +1,74 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerPlanUpSimulatesWithoutSideEffects(t *testing.T) {
+	exec := executor.NewMock()
+	store := memory.New()
+	r := NewRunner(store, exec, schema.DefaultRegistry(), nil, false, driftMigrations())
+
+	plan, err := r.Plan(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, plan.Current)
+	require.Equal(t, uint(2), plan.Target)
+	require.Len(t, plan.Steps, 2)
+	require.Equal(t, "up", plan.Steps[0].Direction)
+	require.Equal(t, map[string][]string{"admin": {"a", "b"}, "viewer": {"read"}}, plan.Result)
+	require.Empty(t, plan.Warnings())
+
+	require.Empty(t, exec.RolesEnsured)
+	v, _, err := store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, v)
+}
+
+func TestRunnerPlanDownFlagsNoOps(t *testing.T) {
+	ms := append(driftMigrations(), Migration{
+		Version: 3,
+		Up:      []byte("version: 1\nactions:\n  - role: admin\n    add: [a]\n"),
+		Down:    []byte("version: 1\nactions:\n  - role: ghost\n    ensure: absent\n"),
+	})
+	store := memory.New()
+	require.NoError(t, store.SetVersion(context.Background(), 3, false))
+	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+
+	target := uint(1)
+	plan, err := r.Plan(context.Background(), &target)
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 2)
+	require.Equal(t, uint(3), plan.Steps[0].Version)
+	require.Equal(t, "down", plan.Steps[0].Direction)
+	require.Equal(t, map[string][]string{"admin": {"a", "b"}}, plan.Result)
+
+	warnings := plan.Warnings()
+	require.Len(t, warnings, 1)
+	require.Equal(t, "ghost", warnings[0].Role)
+}
+
+func TestRunnerPlanRejectsUnknownTarget(t *testing.T) {
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, driftMigrations())
+	target := uint(9)
+	_, err := r.Plan(context.Background(), &target)
+	require.Error(t, err)
+}
+
+func TestRunnerDryRunDownStepsThroughVersions(t *testing.T) {
+	exec := executor.NewMock()
+	store := memory.New()
+	require.NoError(t, store.SetVersion(context.Background(), 2, false))
+	r := NewRunner(store, exec, schema.DefaultRegistry(), nil, true, driftMigrations())
+
+	_, err := r.Down(context.Background(), 2)
+	require.NoError(t, err)
+	require.Empty(t, exec.RolesDeleted)
+	require.Empty(t, r.sim.State().Roles())
+	require.Empty(t, r.sim.Warnings())
+}
@@ -0,0 +1,147 @@
+package migration
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// ErrProtected signals a migration would delete or strip a protected role or permission.
+// Use errors.As with *ProtectedError for details.
+var ErrProtected = errors.New("protected role or permission")
+
+// Protection lists glob patterns (path.Match syntax) for roles and permissions that
+// migrations must never delete or remove. A protected role can be neither deleted nor
+// have permissions removed; a protected permission cannot be removed from any role.
+type Protection struct {
+	Roles       []string `yaml:"roles" json:"roles"`
+	Permissions []string `yaml:"permissions" json:"permissions"`
+}
+
+// Validate reports malformed patterns.
+func (p Protection) Validate() error {
+	for _, pattern := range append(append([]string{}, p.Roles...), p.Permissions...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("protection pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Empty reports whether nothing is protected.
+func (p Protection) Empty() bool {
+	return len(p.Roles) == 0 && len(p.Permissions) == 0
+}
+
+// ProtectsRole reports whether role matches a protected role pattern.
+func (p Protection) ProtectsRole(role string) bool {
+	return matchAny(p.Roles, role)
+}
+
+// ProtectsPermission reports whether perm matches a protected permission pattern.
+func (p Protection) ProtectsPermission(perm string) bool {
+	return matchAny(p.Permissions, perm)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ProtectedError reports the first protected role or permission a migration would touch.
+type ProtectedError struct {
+	Version    uint
+	Direction  string
+	Role       string
+	Permission string
+	// Override is set when the migration declares allow_protected but the run did not acknowledge it.
+	Override bool
+}
+
+func (e *ProtectedError) Error() string {
+	target := "role " + e.Role
+	if e.Permission != "" {
+		target = fmt.Sprintf("permission %s on role %s", e.Permission, e.Role)
+	}
+	msg := fmt.Sprintf("migration %d (%s) would remove protected %s", e.Version, e.Direction, target)
+	if e.Override {
+		msg += fmt.Sprintf("; it declares allow_protected, acknowledge it with --allow-protected %d", e.Version)
+	}
+	return msg
+}
+
+// Is matches ErrProtected.
+func (e *ProtectedError) Is(target error) bool {
+	return target == ErrProtected
+}
+
+// SetProtection configures protected roles and permissions. acknowledged lists migration
+// versions whose allow_protected override the caller accepts.
+func (r *Runner) SetProtection(p Protection, acknowledged []uint) {
+	r.protection = p
+	r.acknowledged = make(map[uint]struct{}, len(acknowledged))
+	for _, v := range acknowledged {
+		r.acknowledged[v] = struct{}{}
+	}
+}
+
+// checkProtection refuses a parsed migration that deletes or strips a protected role or
+// permission, unless it declares allow_protected and the run acknowledged its version.
+func (r *Runner) checkProtection(m Migration, direction string, spec *schema.Spec) error {
+	if r.protection.Empty() {
+		return nil
+	}
+	violation := r.protectionViolation(spec)
+	if violation == nil {
+		return nil
+	}
+	return r.refuseProtected(m, direction, spec, violation)
+}
+
+// refuseProtected returns violation for m, or nil when m declares allow_protected and the run
+// acknowledged its version.
+func (r *Runner) refuseProtected(m Migration, direction string, spec *schema.Spec, violation *ProtectedError) error {
+	violation.Version = m.Version
+	violation.Direction = direction
+	if spec.AllowProtected {
+		if _, ok := r.acknowledged[m.Version]; ok {
+			r.logger.Warn("protected change acknowledged", slog.Uint64("version", uint64(m.Version)), slog.String("role", violation.Role), slog.String("permission", violation.Permission))
+			return nil
+		}
+		violation.Override = true
+	}
+	r.logger.Error("migration touches protected role or permission", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.String("role", violation.Role), slog.String("permission", violation.Permission))
+	return violation
+}
+
+func (r *Runner) protectionViolation(spec *schema.Spec) *ProtectedError {
+	for _, action := range spec.Actions {
+		if action.Rename != nil {
+			// A rename deletes its old role once the users have moved.
+			if r.protection.ProtectsRole(action.Rename.From) {
+				return &ProtectedError{Role: action.Rename.From}
+			}
+			continue
+		}
+		switch action.Ensure {
+		case "absent":
+			if r.protection.ProtectsRole(action.Role) {
+				return &ProtectedError{Role: action.Role}
+			}
+		case "present":
+			for _, perm := range action.Remove {
+				if r.protection.ProtectsRole(action.Role) || r.protection.ProtectsPermission(perm) {
+					return &ProtectedError{Role: action.Role, Permission: perm}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func protectedMigrations(allow bool) []Migration {
+	down := "version: 1\nactions:\n  - role: admin\n    ensure: absent\n"
+	if allow {
+		down = "version: 1\nallow_protected: true\nactions:\n  - role: admin\n    ensure: absent\n"
+	}
+	return []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: admin\n    add: [billing:read]\n"), Down: []byte(down)},
+		{Version: 2, Up: []byte("version: 1\nactions:\n  - role: viewer\n    remove: [billing:read]\n"), Down: []byte("version: 1\nactions:\n  - role: viewer\n")},
+	}
+}
+
+func TestProtectionValidateRejectsBadPattern(t *testing.T) {
+	require.Error(t, Protection{Roles: []string{"["}}.Validate())
+	require.NoError(t, Protection{Roles: []string{"super*"}, Permissions: []string{"billing:*"}}.Validate())
+}
+
+func TestRunnerRefusesDeletingProtectedRole(t *testing.T) {
+	exec := executor.NewMock()
+	store := memory.New()
+	require.NoError(t, store.SetVersion(context.Background(), 1, false))
+	r := NewRunner(store, exec, schema.DefaultRegistry(), nil, false, protectedMigrations(false)[:1])
+	r.SetProtection(Protection{Roles: []string{"adm*"}}, nil)
+
+	_, err := r.Down(context.Background(), 1)
+	require.ErrorIs(t, err, ErrProtected)
+	var perr *ProtectedError
+	require.True(t, errors.As(err, &perr))
+	require.Equal(t, uint(1), perr.Version)
+	require.Equal(t, "admin", perr.Role)
+	require.False(t, perr.Override)
+	require.Empty(t, exec.RolesDeleted)
+
+	v, dirty, err := store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.False(t, dirty)
+}
+
+func TestRunnerRefusesRemovingProtectedPermission(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, protectedMigrations(false))
+	r.SetProtection(Protection{Permissions: []string{"billing:*"}}, nil)
+
+	report, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrProtected)
+	require.Equal(t, 1, report.Applied)
+	require.Equal(t, 1, report.Failed)
+	require.Empty(t, exec.PermsRemoved)
+}
+
+func TestRunnerAllowProtectedRequiresAcknowledgment(t *testing.T) {
+	store := memory.New()
+	require.NoError(t, store.SetVersion(context.Background(), 1, false))
+	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, protectedMigrations(true)[:1])
+	r.SetProtection(Protection{Roles: []string{"admin"}}, nil)
+
+	_, err := r.Down(context.Background(), 1)
+	var perr *ProtectedError
+	require.True(t, errors.As(err, &perr))
+	require.True(t, perr.Override)
+	require.Contains(t, err.Error(), "--allow-protected 1")
+
+	exec := executor.NewMock()
+	r = NewRunner(store, exec, schema.DefaultRegistry(), nil, false, protectedMigrations(true)[:1])
+	r.SetProtection(Protection{Roles: []string{"admin"}}, []uint{1})
+	_, err = r.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin"}, exec.RolesDeleted)
+}
+
+func TestRunnerPlanRefusesProtectedChange(t *testing.T) {
+	store := memory.New()
+	require.NoError(t, store.SetVersion(context.Background(), 1, false))
+	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, protectedMigrations(false)[:1])
+	r.SetProtection(Protection{Roles: []string{"admin"}}, nil)
+
+	target := uint(0)
+	_, err := r.Plan(context.Background(), &target)
+	require.ErrorIs(t, err, ErrProtected)
+}
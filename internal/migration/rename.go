@@ -0,0 +1,199 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+// RenameError reports a rename that stopped part way. When Resumable, a marker records the
+// rename in progress and the store is not marked dirty, so re-running the migration finishes it.
+type RenameError struct {
+	From      string
+	To        string
+	Resumable bool
+	Err       error
+}
+
+func (e *RenameError) Error() string {
+	return fmt.Sprintf("rename %s -> %s stopped part way: %v", e.From, e.To, e.Err)
+}
+
+func (e *RenameError) Unwrap() error { return e.Err }
+
+// renameExecutor is what a rename needs from an executor.
+type renameExecutor interface {
+	executor.Executor
+	executor.Reader
+	executor.UserLister
+	executor.Assigner
+}
+
+// applyRename moves a role to its new name: it copies the permissions, assigns the new role
+// to the old role's users page by page and finally deletes the old role. Every step is
+// idempotent, so a rename interrupted part way resumes when the migration is re-run; a marker
+// in the store's ValueStore tells a resumed rename apart from one into an existing role.
+func (r *Runner) applyRename(ctx context.Context, exec executor.Executor, m Migration, rename *schema.Rename) error {
+	rex, ok := exec.(renameExecutor)
+	if !ok {
+		err := fmt.Errorf("executor %T cannot rename roles; it needs executor.Reader, executor.UserLister and executor.Assigner", exec)
+		r.logger.Error("rename role", slog.String("from", rename.From), slog.String("to", rename.To), slog.Any("err", err))
+		return &executor.Error{Role: rename.From, Op: executor.OpRenameRole, Err: err}
+	}
+	roles, err := rex.ListRoles(ctx)
+	if err != nil {
+		r.logger.Error("list roles", slog.Any("err", err))
+		return &executor.Error{Role: rename.From, Op: executor.OpRenameRole, Err: err}
+	}
+	fromExists, toExists := slices.Contains(roles, rename.From), slices.Contains(roles, rename.To)
+	values := r.liveValues(exec)
+	key := renameKey(m.Version, rename)
+	resuming, marker := false, ""
+	if values != nil {
+		if marker, resuming, err = values.Value(ctx, key); err != nil {
+			r.logger.Error("read rename marker", slog.String("key", key), slog.Any("err", err))
+			return fmt.Errorf("read rename marker %s: %w", key, err)
+		}
+	}
+	switch {
+	case !fromExists && toExists:
+		r.logger.Info("role already renamed", slog.String("from", rename.From), slog.String("to", rename.To))
+		return r.clearRenameMarker(ctx, values, key)
+	case !fromExists:
+		err := fmt.Errorf("role %s does not exist", rename.From)
+		r.logger.Error("rename role", slog.String("from", rename.From), slog.String("to", rename.To), slog.Any("err", err))
+		return &executor.Error{Role: rename.From, Op: executor.OpRenameRole, Err: err}
+	case toExists && !resuming:
+		err := fmt.Errorf("role %s already exists; renaming %s would merge into it", rename.To, rename.From)
+		r.logger.Error("rename role", slog.String("from", rename.From), slog.String("to", rename.To), slog.Any("err", err))
+		return &executor.Error{Role: rename.From, Op: executor.OpRenameRole, Err: err}
+	case resuming:
+		r.logger.Info("resuming rename", slog.String("from", rename.From), slog.String("to", rename.To), slog.String("checkpoint", marker))
+	}
+	if !resuming {
+		if err := r.saveRenameMarker(ctx, values, key, renameCheckpoint{To: rename.To}); err != nil {
+			return err
+		}
+	}
+
+	if err := r.renameSteps(ctx, rex, rename, values, key); err != nil {
+		return &RenameError{From: rename.From, To: rename.To, Resumable: values != nil, Err: err}
+	}
+	return r.clearRenameMarker(ctx, values, key)
+}
+
+// renameSteps copies the permissions, moves the users and deletes the old role.
+func (r *Runner) renameSteps(ctx context.Context, rex renameExecutor, rename *schema.Rename, values store.ValueStore, key string) error {
+	perms, err := rex.ListPermissions(ctx, rename.From)
+	if err != nil {
+		r.logger.Error("list permissions", slog.String("role", rename.From), slog.Any("err", err))
+		return &executor.Error{Role: rename.From, Op: executor.OpRenameRole, Err: err}
+	}
+	if err := rex.EnsureRole(ctx, rename.To); err != nil {
+		r.logger.Error("ensure role", slog.String("role", rename.To), slog.Any("err", err))
+		return &executor.Error{Role: rename.To, Op: executor.OpEnsureRole, Err: err}
+	}
+	if len(perms) > 0 {
+		if err := rex.AddPermissions(ctx, rename.To, perms); err != nil {
+			r.logger.Error("add permissions", slog.String("role", rename.To), slog.Any("err", err))
+			return &executor.Error{Role: rename.To, Op: executor.OpAddPermissions, Err: err}
+		}
+	}
+	for _, tenant := range model.RenameTenants(rename) {
+		if err := r.moveUsers(ctx, rex, rename, tenant, values, key); err != nil {
+			return err
+		}
+	}
+	if err := rex.DeleteRole(ctx, rename.From); err != nil {
+		r.logger.Error("delete role", slog.String("role", rename.From), slog.Any("err", err))
+		return &executor.Error{Role: rename.From, Op: executor.OpDeleteRole, Err: err}
+	}
+	r.logger.Info("role renamed", slog.String("from", rename.From), slog.String("to", rename.To), slog.Int("permissions", len(perms)))
+	return nil
+}
+
+// moveUsers assigns the new role to the old role's users in tenant who lack it, a page at a
+// time, checkpointing the rename marker after every page.
+func (r *Runner) moveUsers(ctx context.Context, rex renameExecutor, rename *schema.Rename, tenant string, values store.ValueStore, key string) error {
+	users, err := rex.ListUsersWithRole(ctx, tenant, rename.From)
+	if err != nil {
+		r.logger.Error("list users with role", slog.String("tenant", tenant), slog.String("role", rename.From), slog.Any("err", err))
+		return &executor.Error{Role: rename.From, Op: executor.OpListUsers, Err: err}
+	}
+	moved, err := rex.ListUsersWithRole(ctx, tenant, rename.To)
+	if err != nil {
+		r.logger.Error("list users with role", slog.String("tenant", tenant), slog.String("role", rename.To), slog.Any("err", err))
+		return &executor.Error{Role: rename.To, Op: executor.OpListUsers, Err: err}
+	}
+	pending := make([]string, 0, len(users))
+	for _, u := range users {
+		if !slices.Contains(moved, u) {
+			pending = append(pending, u)
+		}
+	}
+	pageSize := rename.PageSize
+	if pageSize <= 0 {
+		pageSize = schema.DefaultRenamePageSize
+	}
+	for start := 0; start < len(pending); start += pageSize {
+		page := pending[start:min(start+pageSize, len(pending))]
+		for _, u := range page {
+			if err := rex.AssignRole(ctx, tenant, u, rename.To); err != nil {
+				r.logger.Error("assign role", slog.String("tenant", tenant), slog.String("user", u), slog.String("role", rename.To), slog.Any("err", err))
+				return &executor.Error{Role: rename.To, Op: executor.OpAssignRole, Err: err}
+			}
+		}
+		done := start + len(page)
+		if err := r.saveRenameMarker(ctx, values, key, renameCheckpoint{To: rename.To, Tenant: tenant, Moved: done}); err != nil {
+			return err
+		}
+		r.logger.Info("rename progress", slog.String("from", rename.From), slog.String("to", rename.To), slog.String("tenant", tenant), slog.Int("moved", done), slog.Int("total", len(pending)))
+	}
+	r.logger.Info("rename moved users", slog.String("from", rename.From), slog.String("to", rename.To), slog.String("tenant", tenant), slog.Int("moved", len(pending)), slog.Int("already_moved", len(users)-len(pending)))
+	return nil
+}
+
+// renameCheckpoint is the marker of a rename in progress: the users moved so far in Tenant.
+// Re-running a rename skips users holding the new role, so it is informational.
+type renameCheckpoint struct {
+	To     string `json:"to"`
+	Tenant string `json:"tenant,omitempty"`
+	Moved  int    `json:"moved,omitempty"`
+}
+
+func (r *Runner) saveRenameMarker(ctx context.Context, values store.ValueStore, key string, cp renameCheckpoint) error {
+	if values == nil {
+		return nil
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := values.SetValue(ctx, key, string(data)); err != nil {
+		r.logger.Error("save rename marker", slog.String("key", key), slog.Any("err", err))
+		return fmt.Errorf("save rename marker %s: %w", key, err)
+	}
+	return nil
+}
+
+func (r *Runner) clearRenameMarker(ctx context.Context, values store.ValueStore, key string) error {
+	if values == nil {
+		return nil
+	}
+	if err := values.DeleteValue(ctx, key); err != nil {
+		r.logger.Warn("clear rename marker", slog.String("key", key), slog.Any("err", err))
+	}
+	return nil
+}
+
+// renameKey names the marker of a rename started by migration version.
+func renameKey(version uint, rename *schema.Rename) string {
+	return fmt.Sprintf("rename:%d:%s:%s", version, rename.From, rename.To)
+}
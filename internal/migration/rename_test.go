@@ -0,0 +1,188 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func renameMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Up:      []byte("version: 1\nactions:\n  - role: admin\n    add: [users:read, users:write]\n"),
+			Down:    []byte("version: 1\nactions:\n  - role: admin\n    ensure: absent\n"),
+		},
+		{
+			Version:    2,
+			Identifier: "rename_admin",
+			Up:         []byte("version: 1\nactions:\n  - rename: {from: admin, to: administrator, tenants: [public, tenant-a]}\n"),
+			Down:       []byte("version: 1\ninverse: true\n"),
+		},
+	}
+}
+
+// renameMock holds admin with two permissions and three users across two tenants.
+func renameMock(t *testing.T) (*executor.Mock, *Runner) {
+	t.Helper()
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, renameMigrations())
+	one := uint(1)
+	_, err := r.Up(context.Background(), &one)
+	require.NoError(t, err)
+	exec.Assignments = []executor.Assignment{
+		{Tenant: executor.DefaultTenant, User: "u1", Role: "admin"},
+		{Tenant: executor.DefaultTenant, User: "u2", Role: "admin"},
+		{Tenant: "tenant-a", User: "u3", Role: "admin"},
+	}
+	return exec, r
+}
+
+func TestRunnerRenamesRoleAndInvertsOnDown(t *testing.T) {
+	exec, r := renameMock(t)
+
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.NotContains(t, exec.Live, "admin")
+	require.ElementsMatch(t, []string{"users:read", "users:write"}, exec.Live["administrator"])
+	require.ElementsMatch(t, []executor.Assignment{
+		{Tenant: executor.DefaultTenant, User: "u1", Role: "administrator"},
+		{Tenant: executor.DefaultTenant, User: "u2", Role: "administrator"},
+		{Tenant: "tenant-a", User: "u3", Role: "administrator"},
+	}, exec.Assignments)
+
+	_, err = r.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.NotContains(t, exec.Live, "administrator")
+	require.ElementsMatch(t, []string{"users:read", "users:write"}, exec.Live["admin"])
+	require.Len(t, exec.Assignments, 3)
+	require.Contains(t, exec.Assignments, executor.Assignment{Tenant: "tenant-a", User: "u3", Role: "admin"})
+}
+
+func TestRunnerRenameResumesAfterFailure(t *testing.T) {
+	mock, _ := renameMock(t)
+	exec := &recordingAssigner{Mock: mock, failUser: "u2"}
+	st := memory.New()
+	require.NoError(t, st.SetVersion(context.Background(), 1, false))
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, renameMigrations())
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, executor.ErrExecutor)
+	var renameErr *RenameError
+	require.True(t, errors.As(err, &renameErr))
+	require.True(t, renameErr.Resumable)
+	require.Equal(t, []string{"u1"}, exec.assigned)
+	v, dirty, err := st.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.False(t, dirty, "an interrupted rename leaves the state clean for a re-run")
+
+	exec.failUser = ""
+	exec.assigned = nil
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"u2", "u3"}, exec.assigned, "users already moved are skipped")
+	require.NotContains(t, exec.Live, "admin")
+	_, ok, err := st.Value(context.Background(), renameKey(2, &schema.Rename{From: "admin", To: "administrator"}))
+	require.NoError(t, err)
+	require.False(t, ok, "the marker is cleared once the rename completes")
+}
+
+func TestRunnerRenameCheckpointsEveryPage(t *testing.T) {
+	mock, _ := renameMock(t)
+	exec := &recordingAssigner{Mock: mock, failUser: "u2"}
+	st := memory.New()
+	require.NoError(t, st.SetVersion(context.Background(), 1, false))
+	migrations := renameMigrations()
+	migrations[1].Up = []byte("version: 1\nactions:\n  - rename: {from: admin, to: administrator, tenants: [public, tenant-a], page_size: 1}\n")
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, migrations)
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, executor.ErrExecutor)
+	marker, ok, err := st.Value(context.Background(), renameKey(2, &schema.Rename{From: "admin", To: "administrator"}))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.JSONEq(t, `{"to": "administrator", "tenant": "public", "moved": 1}`, marker, "the first page is checkpointed")
+}
+
+func TestRunnerRenameRefusesExistingTarget(t *testing.T) {
+	exec, r := renameMock(t)
+	exec.Live["administrator"] = []string{}
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, executor.ErrExecutor)
+	require.ErrorContains(t, err, "role administrator already exists")
+	require.Contains(t, exec.Live, "admin")
+}
+
+func TestRunnerRenameSkipsCompletedRename(t *testing.T) {
+	exec, r := renameMock(t)
+	require.NoError(t, exec.DeleteRole(context.Background(), "admin"))
+	require.NoError(t, exec.EnsureRole(context.Background(), "administrator"))
+
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+}
+
+func TestRunnerInverseRejectsUnsupportedActions(t *testing.T) {
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, []Migration{{
+		Version: 1,
+		Up:      []byte("version: 1\nactions:\n  - role: admin\n"),
+		Down:    []byte("version: 1\ninverse: true\n"),
+	}})
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+
+	_, err = r.Down(context.Background(), 1)
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorContains(t, err, "action admin cannot be inverted automatically")
+}
+
+func TestRunnerRenameProtectsOldRole(t *testing.T) {
+	_, r := renameMock(t)
+	r.SetProtection(Protection{Roles: []string{"admin"}}, nil)
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrProtected)
+}
+
+func TestPlanAndRoundTripSimulateRenames(t *testing.T) {
+	exec, r := renameMock(t)
+
+	plan, err := r.Plan(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 1)
+	require.Contains(t, plan.Result, "administrator")
+	require.NotContains(t, plan.Result, "admin")
+	require.Contains(t, exec.Live, "admin", "planning leaves the backend untouched")
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Failed())
+}
+
+func TestRunnerRefusesRenameStrandingUsersInUnlistedTenants(t *testing.T) {
+	exec, r := renameMock(t)
+	exec.Assignments = append(exec.Assignments, executor.Assignment{Tenant: "tenant-b", User: "u4", Role: "admin"})
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrRoleInUse)
+	var inUse *RoleInUseError
+	require.True(t, errors.As(err, &inUse))
+	require.Equal(t, "admin", inUse.Role)
+	require.Equal(t, 1, inUse.Users)
+	require.Equal(t, []string{"tenant-b"}, inUse.Tenants)
+	require.Contains(t, exec.Live, "admin")
+	require.NotContains(t, exec.Live, "administrator")
+
+	r.SetForce(true)
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.NotContains(t, exec.Live, "admin")
+	require.NotContains(t, exec.Assignments, executor.Assignment{Tenant: "tenant-b", User: "u4", Role: "administrator"})
+}
@@ -0,0 +1,83 @@
+package migration
+
+import (
+	"time"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// Migration directions recorded in reports.
+const (
+	DirectionUp   = "up"
+	DirectionDown = "down"
+)
+
+// RunEntry records the outcome of running one migration in one direction.
+type RunEntry struct {
+	Version    uint          `json:"version"`
+	Identifier string        `json:"identifier"`
+	Direction  string        `json:"direction"`
+	Actions    int           `json:"actions"`
+	Duration   time.Duration `json:"duration_ns"`
+	Error      string        `json:"error,omitempty"`
+	// Header is the schema v2 migration header, when the document declares one.
+	Header *schema.Header `json:"header,omitempty"`
+}
+
+// RunReport summarizes an Up, Down or Migrate call.
+// In dry-run mode entries describe simulated executions, FinalVersion is the version
+// the run would have reached and Result the roles and permissions it would leave.
+type RunReport struct {
+	DryRun       bool          `json:"dry_run"`
+	StartVersion int           `json:"start_version"`
+	FinalVersion int           `json:"final_version"`
+	Applied      int           `json:"applied"`
+	RolledBack   int           `json:"rolled_back"`
+	Failed       int           `json:"failed"`
+	Duration     time.Duration `json:"duration_ns"`
+	Entries      []RunEntry    `json:"entries"`
+	// Result is set by dry runs; it maps each simulated role to its permissions.
+	Result map[string][]string `json:"result,omitempty"`
+}
+
+func (r *Runner) newReport() *RunReport {
+	return &RunReport{DryRun: r.dryRun, Entries: []RunEntry{}}
+}
+
+// start notes the version the run began from.
+func (rep *RunReport) start(current int) {
+	rep.StartVersion = current
+	rep.FinalVersion = current
+}
+
+// finish stamps the total duration of the run.
+func (rep *RunReport) finish(started time.Time) {
+	rep.Duration = time.Since(started)
+}
+
+// record appends the outcome of one migration; spec is nil when it could not be parsed.
+func (rep *RunReport) record(m Migration, direction string, spec *schema.Spec, started time.Time, err error) {
+	entry := RunEntry{
+		Version:    m.Version,
+		Identifier: m.Identifier,
+		Direction:  direction,
+		Duration:   time.Since(started),
+	}
+	if spec != nil {
+		entry.Actions = len(spec.Actions)
+		if !spec.Header.Empty() {
+			header := spec.Header
+			entry.Header = &header
+		}
+	}
+	switch {
+	case err != nil:
+		entry.Error = err.Error()
+		rep.Failed++
+	case direction == DirectionUp:
+		rep.Applied++
+	default:
+		rep.RolledBack++
+	}
+	rep.Entries = append(rep.Entries, entry)
+}
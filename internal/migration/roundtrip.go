@@ -0,0 +1,110 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+)
+
+// RoundTripResult records whether a single migration's down file inverts its up file.
+type RoundTripResult struct {
+	Version    uint   `json:"version"`
+	Identifier string `json:"identifier"`
+	OK         bool   `json:"ok"`
+	// Diff compares the model before up (expected) with the model after up+down (live side).
+	Diff  *model.Diff `json:"diff,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// RoundTripReport aggregates round-trip results for every migration.
+type RoundTripReport struct {
+	Results []RoundTripResult `json:"results"`
+}
+
+// Failed returns the results whose down file is not the inverse of the up file.
+func (r *RoundTripReport) Failed() []RoundTripResult {
+	out := make([]RoundTripResult, 0)
+	for _, res := range r.Results {
+		if !res.OK {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// RoundTrip replays every migration through a simulated backend. For each version it
+// applies up, then down, then up again and checks that down restores the model seen
+// before up and that re-applying up reproduces the same model.
+func (r *Runner) RoundTrip(ctx context.Context) (*RoundTripReport, error) {
+	report := &RoundTripReport{Results: []RoundTripResult{}}
+	state := model.New()
+	for _, m := range r.migrations {
+		res := RoundTripResult{Version: m.Version, Identifier: m.Identifier}
+		next, err := r.roundTripOne(ctx, state, m, &res)
+		if err != nil {
+			res.Error = err.Error()
+			r.logger.Warn("round trip failed", slog.Uint64("version", uint64(m.Version)), slog.String("identifier", m.Identifier), slog.String("error", res.Error))
+		}
+		report.Results = append(report.Results, res)
+		if next == nil {
+			// without a forward state later versions cannot be checked meaningfully
+			break
+		}
+		state = next
+	}
+	r.logger.Info("round trip complete", slog.Int("checked", len(report.Results)), slog.Int("failed", len(report.Failed())))
+	return report, nil
+}
+
+// roundTripOne checks a single migration starting from state and returns the model after
+// applying only its up file, so later versions are checked against the forward path.
+func (r *Runner) roundTripOne(ctx context.Context, state *model.State, m Migration, res *RoundTripResult) (*model.State, error) {
+	upSpec, err := r.parse(m, DirectionUp)
+	if err != nil {
+		return nil, err
+	}
+
+	sim := model.NewSimulator(state.Clone())
+	if upSpec, err = r.expand(sim, m, DirectionUp, upSpec); err != nil {
+		return nil, err
+	}
+	if err := r.applySpec(ctx, sim, m, DirectionUp, upSpec); err != nil {
+		return nil, err
+	}
+	after := sim.State()
+	if m.MissingDown {
+		return after, fmt.Errorf("migration %d has no down file", m.Version)
+	}
+	downSpec, err := r.parse(m, DirectionDown)
+	if err != nil {
+		return after, err
+	}
+	if downSpec, err = r.expand(sim, m, DirectionDown, downSpec); err != nil {
+		return after, err
+	}
+	if err := r.applySpec(ctx, sim, m, DirectionDown, downSpec); err != nil {
+		return after, err
+	}
+	restored := sim.State()
+	if err := r.applySpec(ctx, sim, m, DirectionUp, upSpec); err != nil {
+		return after, err
+	}
+	reapplied := sim.State()
+
+	if diff := model.Compare(state, restored); !diff.Empty() {
+		res.Diff = &diff
+		return after, fmt.Errorf("down migration %d does not restore the state before up", m.Version)
+	}
+	if diff := model.Compare(after, reapplied); !diff.Empty() {
+		res.Diff = &diff
+		return after, fmt.Errorf("re-applying up migration %d after down produces a different state", m.Version)
+	}
+	if !reflect.DeepEqual(state.Assignments(), restored.Assignments()) {
+		return after, fmt.Errorf("down migration %d does not restore the user role assignments before up", m.Version)
+	}
+	res.OK = true
+	return after, nil
+}
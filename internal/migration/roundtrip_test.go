@@ -0,0 +1,61 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerRoundTripPasses(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, driftMigrations())
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Results, 2)
+	require.Empty(t, report.Failed())
+	require.Empty(t, exec.RolesEnsured)
+}
+
+func TestRunnerRoundTripNamesBrokenDown(t *testing.T) {
+	ms := append(driftMigrations(), Migration{
+		Version:    3,
+		Identifier: "grant_export",
+		Up:         []byte("version: 1\nactions:\n  - role: admin\n    add: [export]\n"),
+		Down:       []byte("version: 1\nactions:\n  - role: admin\n    remove: [a]\n"),
+	}, Migration{
+		Version: 4,
+		Up:      []byte("version: 1\nactions:\n  - role: auditor\n"),
+		Down:    []byte("version: 1\nactions:\n  - role: auditor\n    ensure: absent\n"),
+	})
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Results, 4)
+
+	failed := report.Failed()
+	require.Len(t, failed, 1)
+	require.Equal(t, uint(3), failed[0].Version)
+	require.Equal(t, "grant_export", failed[0].Identifier)
+	require.Contains(t, failed[0].Error, "down migration 3")
+	require.Equal(t, map[string][]string{"admin": {"export"}}, failed[0].Diff.ExtraPermissions)
+	require.Equal(t, map[string][]string{"admin": {"a"}}, failed[0].Diff.MissingPermissions)
+}
+
+func TestRunnerRoundTripStopsOnParseError(t *testing.T) {
+	ms := []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: r\n    ensure: bogus\n"), Down: []byte("version: 1")},
+		{Version: 2, Up: []byte("version: 1"), Down: []byte("version: 1")},
+	}
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	require.False(t, report.Results[0].OK)
+}
@@ -2,33 +2,46 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
 	"sort"
+	"time"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/schema"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/state"
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
 )
 
 // Runner coordinates applying migrations using a state store and executor.
 type Runner struct {
-	store      state.Store
+	store      store.Store
 	exec       executor.Executor
 	registry   *schema.Registry
 	logger     *slog.Logger
 	dryRun     bool
 	migrations []Migration
+	// sim receives executor calls during dry runs.
+	sim *model.Simulator
+	// protection guards roles/permissions; acknowledged lists versions allowed to override it.
+	protection   Protection
+	acknowledged map[uint]struct{}
+	// force deletes roles even while users still hold them.
+	force bool
+	// seedFS holds the seed files referenced by seed actions.
+	seedFS fs.FS
 }
 
 // NewRunner constructs a Runner with parsed migrations.
-func NewRunner(store state.Store, exec executor.Executor, registry *schema.Registry, logger *slog.Logger, dryRun bool, migrations []Migration) *Runner {
+func NewRunner(st store.Store, exec executor.Executor, registry *schema.Registry, logger *slog.Logger, dryRun bool, migrations []Migration) *Runner {
 	if logger == nil {
 		logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 	}
 	return &Runner{
-		store:      store,
+		store:      st,
 		exec:       exec,
 		registry:   registry,
 		logger:     logger,
@@ -39,33 +52,17 @@ func NewRunner(store state.Store, exec executor.Executor, registry *schema.Regis
 
 // Up applies pending migrations up to the optional target version.
 // If target is nil, all pending migrations are applied.
-func (r *Runner) Up(ctx context.Context, target *uint) error {
+// The returned report is never nil and covers the migrations run before any failure.
+func (r *Runner) Up(ctx context.Context, target *uint) (*RunReport, error) {
+	report := r.newReport()
+	defer report.finish(time.Now())
 	r.logger.Info("up start", slog.Any("target", target), slog.Bool("dry_run", r.dryRun), slog.Int("available", len(r.migrations)))
-	if err := r.store.Lock(ctx); err != nil {
-		r.logger.Error("lock state store", slog.Any("err", err))
-		return fmt.Errorf("lock state store: %w", err)
-	}
-	defer func() {
-		if err := r.store.Unlock(ctx); err != nil {
-			r.logger.Error("unlock state store", slog.Any("err", err))
-		}
-	}()
-
-	current, dirty, err := r.store.Version(ctx)
+	current, release, err := r.begin(ctx, report)
 	if err != nil {
-		r.logger.Error("read version", slog.Any("err", err))
-		return err
-	}
-	if current < 0 {
-		r.logger.Debug("normalizing negative current version to zero", slog.Int("current", current))
-		current = 0
-	}
-	if dirty {
-		r.logger.Warn("state is dirty; refusing to run migrations")
-		return fmt.Errorf("state is dirty; resolve before running migrations")
+		return report, err
 	}
+	defer release()
 
-	applied := 0
 	for _, m := range r.migrations {
 		if target != nil && m.Version > *target {
 			break
@@ -74,53 +71,61 @@ func (r *Runner) Up(ctx context.Context, target *uint) error {
 			r.logger.Debug("skip already applied", slog.Uint64("version", uint64(m.Version)))
 			continue
 		}
-		if err := r.apply(ctx, m.Version, m.Up); err != nil {
-			_ = r.store.SetVersion(ctx, int(m.Version), true)
-			r.logger.Error("apply up migration", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
-			return err
+		if err := r.stepUp(ctx, report, m); err != nil {
+			return report, err
 		}
-		if r.dryRun {
+	}
+	r.logger.Info("up complete", slog.Int("applied", report.Applied), slog.Any("target", target))
+	return report, nil
+}
+
+// UpSteps applies the next steps pending migrations (default 1 if steps<=0).
+// The returned report is never nil and covers the migrations run before any failure.
+func (r *Runner) UpSteps(ctx context.Context, steps int) (*RunReport, error) {
+	report := r.newReport()
+	defer report.finish(time.Now())
+	if steps <= 0 {
+		steps = 1
+	}
+	r.logger.Info("up steps start", slog.Int("steps", steps), slog.Bool("dry_run", r.dryRun))
+	current, release, err := r.begin(ctx, report)
+	if err != nil {
+		return report, err
+	}
+	defer release()
+
+	for _, m := range r.migrations {
+		if report.Applied == steps {
+			break
+		}
+		if int(m.Version) <= current {
 			continue
 		}
-		if err := r.store.SetVersion(ctx, int(m.Version), false); err != nil {
-			r.logger.Error("persist version", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
-			return err
+		if err := r.stepUp(ctx, report, m); err != nil {
+			return report, err
 		}
-		r.logger.Info("applied migration", slog.Uint64("version", uint64(m.Version)), slog.String("direction", "up"))
-		applied++
 	}
-	r.logger.Info("up complete", slog.Int("applied", applied), slog.Any("target", target))
-	return nil
+	r.logger.Info("up steps complete", slog.Int("steps_requested", steps), slog.Int("applied", report.Applied))
+	return report, nil
 }
 
 // Down rolls back a number of migrations (default 1 if steps<=0).
-func (r *Runner) Down(ctx context.Context, steps int) error {
+// The returned report is never nil and covers the migrations run before any failure.
+func (r *Runner) Down(ctx context.Context, steps int) (*RunReport, error) {
+	report := r.newReport()
+	defer report.finish(time.Now())
 	if steps <= 0 {
 		steps = 1
 	}
 	r.logger.Info("down start", slog.Int("steps", steps), slog.Bool("dry_run", r.dryRun))
-	if err := r.store.Lock(ctx); err != nil {
-		r.logger.Error("lock state store", slog.Any("err", err))
-		return fmt.Errorf("lock state store: %w", err)
-	}
-	defer func() {
-		if err := r.store.Unlock(ctx); err != nil {
-			r.logger.Error("unlock state store", slog.Any("err", err))
-		}
-	}()
-
-	current, dirty, err := r.store.Version(ctx)
+	current, release, err := r.begin(ctx, report)
 	if err != nil {
-		r.logger.Error("read version", slog.Any("err", err))
-		return err
-	}
-	if dirty {
-		r.logger.Warn("state is dirty; refusing to run migrations")
-		return fmt.Errorf("state is dirty; resolve before running migrations")
+		return report, err
 	}
+	defer release()
 	if current <= 0 {
 		r.logger.Info("no migrations to roll back")
-		return nil
+		return report, nil
 	}
 
 	idx := indexByVersion(r.migrations)
@@ -128,42 +133,68 @@ func (r *Runner) Down(ctx context.Context, steps int) error {
 		m, ok := idx[uint(current)]
 		if !ok {
 			r.logger.Warn("migration version not found for down", slog.Int("current", current))
-			return fmt.Errorf("migration version %d not found for down", current)
-		}
-		if err := r.apply(ctx, m.Version, m.Down); err != nil {
-			_ = r.store.SetVersion(ctx, int(m.Version), true)
-			r.logger.Error("apply down migration", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
-			return err
+			return report, fmt.Errorf("%w: %d has no migration to roll back", ErrVersionNotFound, current)
 		}
-		if r.dryRun {
-			continue
+		prev, err := r.stepDown(ctx, report, m)
+		if err != nil {
+			return report, err
 		}
-		prev := previousVersion(r.migrations, m.Version)
-		if err := r.store.SetVersion(ctx, int(prev), false); err != nil {
-			r.logger.Error("persist version", slog.Uint64("version", uint64(prev)), slog.Any("err", err))
-			return err
-		}
-		r.logger.Info("rolled back migration", slog.Uint64("version", uint64(m.Version)))
 		current = int(prev)
 	}
 	r.logger.Info("down complete", slog.Int("steps_requested", steps), slog.Int("current_version", current))
-	return nil
+	return report, nil
 }
 
-// Status reports the current applied version and the pending migration versions.
-func (r *Runner) Status(ctx context.Context) (int, []uint, error) {
-	current, _, err := r.store.Version(ctx)
+// Reset rolls back every applied migration, leaving the store at version 0.
+// The returned report is never nil and covers the migrations run before any failure.
+func (r *Runner) Reset(ctx context.Context) (*RunReport, error) {
+	report := r.newReport()
+	defer report.finish(time.Now())
+	r.logger.Info("reset start", slog.Bool("dry_run", r.dryRun))
+	current, release, err := r.begin(ctx, report)
 	if err != nil {
-		r.logger.Error("read version", slog.Any("err", err))
-		return 0, nil, err
+		return report, err
 	}
-	pending := make([]uint, 0)
-	for _, m := range r.migrations {
-		if int(m.Version) > current {
-			pending = append(pending, m.Version)
-		}
+	defer release()
+	if current <= 0 {
+		r.logger.Info("no migrations to roll back")
+		return report, nil
+	}
+	if err := r.downTo(ctx, report, 0, uint(current)); err != nil {
+		return report, err
+	}
+	r.logger.Info("reset complete", slog.Int("rolled_back", report.RolledBack))
+	return report, nil
+}
+
+// Redo rolls back the latest applied migration and applies it again.
+// The returned report is never nil and covers the migrations run before any failure.
+func (r *Runner) Redo(ctx context.Context) (*RunReport, error) {
+	report := r.newReport()
+	defer report.finish(time.Now())
+	r.logger.Info("redo start", slog.Bool("dry_run", r.dryRun))
+	current, release, err := r.begin(ctx, report)
+	if err != nil {
+		return report, err
+	}
+	defer release()
+	if current <= 0 {
+		r.logger.Info("no migration to redo")
+		return report, nil
+	}
+	m, ok := indexByVersion(r.migrations)[uint(current)]
+	if !ok {
+		r.logger.Warn("migration version not found for redo", slog.Int("current", current))
+		return report, fmt.Errorf("%w: %d has no migration to redo", ErrVersionNotFound, current)
+	}
+	if _, err := r.stepDown(ctx, report, m); err != nil {
+		return report, err
 	}
-	return current, pending, nil
+	if err := r.stepUp(ctx, report, m); err != nil {
+		return report, err
+	}
+	r.logger.Info("redo complete", slog.Uint64("version", uint64(m.Version)))
+	return report, nil
 }
 
 // Close releases resources on the store, if any.
@@ -172,73 +203,94 @@ func (r *Runner) Close() error {
 }
 
 // Migrate moves to the target version, applying up or down as needed.
-func (r *Runner) Migrate(ctx context.Context, target uint) error {
+// The returned report is never nil and covers the migrations run before any failure.
+func (r *Runner) Migrate(ctx context.Context, target uint) (*RunReport, error) {
+	report := r.newReport()
+	defer report.finish(time.Now())
 	r.logger.Info("migrate start", slog.Uint64("target", uint64(target)), slog.Bool("dry_run", r.dryRun))
+	current, release, err := r.begin(ctx, report)
+	if err != nil {
+		return report, err
+	}
+	defer release()
+	if uint(current) == target {
+		r.logger.Info("no-op migrate; already at target", slog.Int("current", current))
+		return report, nil
+	}
+
+	var maxVersion uint
+	if len(r.migrations) > 0 {
+		maxVersion = r.migrations[len(r.migrations)-1].Version
+	}
+	if target > maxVersion {
+		r.logger.Error("target version not found", slog.Uint64("target", uint64(target)), slog.Uint64("max_available", uint64(maxVersion)))
+		return report, fmt.Errorf("%w: target %d, max available %d", ErrVersionNotFound, target, maxVersion)
+	}
+
+	if target > uint(current) {
+		return report, r.upTo(ctx, report, uint(current), target)
+	}
+	return report, r.downTo(ctx, report, target, uint(current))
+}
+
+// begin locks the store, reads the current version and refuses to continue on a dirty state.
+// In dry-run mode it also seeds the simulator. The release func undoes both and must be
+// called once the run is over.
+func (r *Runner) begin(ctx context.Context, report *RunReport) (int, func(), error) {
 	if err := r.store.Lock(ctx); err != nil {
 		r.logger.Error("lock state store", slog.Any("err", err))
-		return fmt.Errorf("lock state store: %w", err)
+		return 0, nil, fmt.Errorf("lock state store: %w", err)
 	}
-	defer func() {
+	unlock := func() {
 		if err := r.store.Unlock(ctx); err != nil {
 			r.logger.Error("unlock state store", slog.Any("err", err))
 		}
-	}()
+	}
 
 	current, dirty, err := r.store.Version(ctx)
 	if err != nil {
 		r.logger.Error("read version", slog.Any("err", err))
-		return err
+		unlock()
+		return 0, nil, err
 	}
 	if current < 0 {
 		r.logger.Debug("normalizing negative current version to zero", slog.Int("current", current))
 		current = 0
 	}
+	report.start(current)
 	if dirty {
-		r.logger.Warn("state is dirty; refusing to migrate")
-		return fmt.Errorf("state is dirty; resolve before running migrations")
-	}
-	if uint(current) == target {
-		r.logger.Info("no-op migrate; already at target", slog.Int("current", current))
-		return nil
-	}
-
-	maxVersion := r.migrations[len(r.migrations)-1].Version
-	if target > maxVersion {
-		r.logger.Error("target version not found", slog.Uint64("target", uint64(target)), slog.Uint64("max_available", uint64(maxVersion)))
-		return fmt.Errorf("target version %d not found; max available %d", target, maxVersion)
+		r.logger.Warn("state is dirty; refusing to run migrations")
+		unlock()
+		return current, nil, &DirtyError{Version: current}
 	}
-
-	if target > uint(current) {
-		return r.upTo(ctx, target)
+	if err := r.beginSimulation(current); err != nil {
+		unlock()
+		return current, nil, err
 	}
-	return r.downTo(ctx, target, uint(current))
+	return current, func() {
+		r.endSimulation(report)
+		unlock()
+	}, nil
 }
 
-func (r *Runner) upTo(ctx context.Context, target uint) error {
+func (r *Runner) upTo(ctx context.Context, report *RunReport, current uint, target uint) error {
 	r.logger.Debug("migrate up path", slog.Uint64("target", uint64(target)))
 	for _, m := range r.migrations {
 		if m.Version > target {
 			break
 		}
-		if err := r.apply(ctx, m.Version, m.Up); err != nil {
-			_ = r.store.SetVersion(ctx, int(m.Version), true)
-			r.logger.Error("apply up migration", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
-			return err
-		}
-		if r.dryRun {
+		if m.Version <= current {
 			continue
 		}
-		if err := r.store.SetVersion(ctx, int(m.Version), false); err != nil {
-			r.logger.Error("persist version", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+		if err := r.stepUp(ctx, report, m); err != nil {
 			return err
 		}
-		r.logger.Info("applied migration", slog.Uint64("version", uint64(m.Version)), slog.String("direction", "up"))
 	}
 	r.logger.Info("migrate up complete", slog.Uint64("target", uint64(target)))
 	return nil
 }
 
-func (r *Runner) downTo(ctx context.Context, target uint, current uint) error {
+func (r *Runner) downTo(ctx context.Context, report *RunReport, target uint, current uint) error {
 	r.logger.Debug("migrate down path", slog.Uint64("target", uint64(target)), slog.Uint64("current", uint64(current)))
 	// Build a set of versions we need to roll back.
 	needed := make(map[uint]Migration)
@@ -253,63 +305,251 @@ func (r *Runner) downTo(ctx context.Context, target uint, current uint) error {
 		m, ok := needed[v]
 		if !ok {
 			r.logger.Warn("missing migration for rollback", slog.Uint64("version", uint64(v)))
-			return fmt.Errorf("missing migration version %d for rollback", v)
+			return fmt.Errorf("%w: %d has no migration to roll back", ErrVersionNotFound, v)
 		}
-		if err := r.apply(ctx, m.Version, m.Down); err != nil {
-			_ = r.store.SetVersion(ctx, int(m.Version), true)
-			r.logger.Error("apply down migration", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+		prev, err := r.stepDown(ctx, report, m)
+		if err != nil {
 			return err
 		}
-		if !r.dryRun {
-			prev := previousVersion(r.migrations, m.Version)
-			if err := r.store.SetVersion(ctx, int(prev), false); err != nil {
-				r.logger.Error("persist version", slog.Uint64("version", uint64(prev)), slog.Any("err", err))
-				return err
-			}
-			r.logger.Info("rolled back migration", slog.Uint64("version", uint64(m.Version)))
-			v = prev
-		} else {
-			v = previousVersion(r.migrations, m.Version)
+		v = prev
+	}
+	return nil
+}
+
+// stepUp applies a single up migration, persists the new version and records the outcome.
+func (r *Runner) stepUp(ctx context.Context, report *RunReport, m Migration) error {
+	started := time.Now()
+	spec, err := r.apply(ctx, m, DirectionUp)
+	if err != nil {
+		report.record(m, DirectionUp, spec, started, err)
+		r.markDirty(ctx, m.Version, err)
+		r.logger.Error("apply up migration", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+		return err
+	}
+	if !r.dryRun {
+		if err := r.store.SetVersion(ctx, int(m.Version), false); err != nil {
+			report.record(m, DirectionUp, spec, started, err)
+			r.logger.Error("persist version", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+			return err
 		}
+		if err := r.recordApplied(ctx, m); err != nil {
+			report.record(m, DirectionUp, spec, started, err)
+			return err
+		}
+		r.logger.Info("applied migration", slog.Uint64("version", uint64(m.Version)), slog.String("direction", DirectionUp))
 	}
+	report.record(m, DirectionUp, spec, started, nil)
+	report.FinalVersion = int(m.Version)
 	return nil
 }
 
-func (r *Runner) apply(ctx context.Context, version uint, data []byte) error {
-	spec, err := r.registry.Parse(data)
+// stepDown applies a single down migration, persists the previous version and records the outcome.
+// It returns the version the store is at afterwards.
+func (r *Runner) stepDown(ctx context.Context, report *RunReport, m Migration) (uint, error) {
+	started := time.Now()
+	if m.MissingDown {
+		err := fmt.Errorf("migration %d has no down file", m.Version)
+		report.record(m, DirectionDown, nil, started, err)
+		r.logger.Error("down migration missing", slog.Uint64("version", uint64(m.Version)))
+		return m.Version, err
+	}
+	spec, err := r.apply(ctx, m, DirectionDown)
 	if err != nil {
-		r.logger.Error("parse migration", slog.Uint64("version", uint64(version)), slog.Any("err", err))
-		return fmt.Errorf("parse migration %d: %w", version, err)
+		report.record(m, DirectionDown, spec, started, err)
+		r.markDirty(ctx, m.Version, err)
+		r.logger.Error("apply down migration", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+		return m.Version, err
+	}
+	prev := previousVersion(r.migrations, m.Version)
+	if !r.dryRun {
+		if err := r.store.SetVersion(ctx, int(prev), false); err != nil {
+			report.record(m, DirectionDown, spec, started, err)
+			r.logger.Error("persist version", slog.Uint64("version", uint64(prev)), slog.Any("err", err))
+			return m.Version, err
+		}
+		if err := r.forgetApplied(ctx, m.Version); err != nil {
+			report.record(m, DirectionDown, spec, started, err)
+			return m.Version, err
+		}
+		r.logger.Info("rolled back migration", slog.Uint64("version", uint64(m.Version)))
+	}
+	report.record(m, DirectionDown, spec, started, nil)
+	report.FinalVersion = int(prev)
+	return prev, nil
+}
+
+// markDirty flags the store after a failed apply. Dry runs and migrations refused by the
+// protection check never reached the backend, so they leave the state untouched; neither do
+// checkpointed seeds, which a re-run resumes.
+func (r *Runner) markDirty(ctx context.Context, version uint, err error) {
+	if r.dryRun || errors.Is(err, ErrProtected) || errors.Is(err, ErrRoleInUse) {
+		return
+	}
+	var seedErr *SeedError
+	if errors.As(err, &seedErr) && seedErr.Resumable {
+		r.logger.Warn("seed interrupted; re-run to resume", slog.Uint64("version", uint64(version)), slog.String("file", seedErr.File), slog.Int("rows", seedErr.Rows))
+		return
 	}
+	var renameErr *RenameError
+	if errors.As(err, &renameErr) && renameErr.Resumable {
+		r.logger.Warn("rename interrupted; re-run to resume", slog.Uint64("version", uint64(version)), slog.String("from", renameErr.From), slog.String("to", renameErr.To))
+		return
+	}
+	_ = r.store.SetVersion(ctx, int(version), true)
+}
+
+// apply parses and executes one direction of a migration, returning the parsed spec
+// (nil when parsing failed).
+func (r *Runner) apply(ctx context.Context, m Migration, direction string) (*schema.Spec, error) {
+	spec, err := r.parse(m, direction)
+	if err != nil {
+		return nil, err
+	}
+	exec := r.exec
 	if r.dryRun {
-		r.logger.Info("dry run: would apply migration", slog.Uint64("version", uint64(version)), slog.Int("actions", len(spec.Actions)))
-		return nil
+		exec = r.sim
+	}
+	if spec, err = r.expand(exec, m, direction, spec); err != nil {
+		return nil, err
+	}
+	if err := r.checkProtection(m, direction, spec); err != nil {
+		return spec, err
+	}
+	if err := r.checkRoleUsage(ctx, m, direction, spec); err != nil {
+		return spec, err
+	}
+	if err := r.checkSetProtection(ctx, exec, m, direction, spec); err != nil {
+		return spec, err
+	}
+	if !spec.Header.Empty() {
+		r.logger.Info("migration header", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.String("description", spec.Header.Description), slog.String("author", spec.Header.Author), slog.String("ticket", spec.Header.Ticket))
+	}
+	if r.dryRun {
+		r.logger.Info("dry run: simulating migration", slog.Uint64("version", uint64(m.Version)), slog.Int("actions", len(spec.Actions)))
+		if err := r.applySpec(ctx, r.sim, m, direction, spec); err != nil {
+			return spec, err
+		}
+		for _, w := range r.sim.TakeWarnings() {
+			r.logger.Warn("dry run: no-op action", slog.Uint64("version", uint64(m.Version)), slog.String("role", w.Role), slog.String("op", w.Op), slog.String("message", w.Message))
+		}
+		return spec, nil
+	}
+	r.logger.Debug("apply migration", slog.Uint64("version", uint64(m.Version)), slog.Int("actions", len(spec.Actions)))
+	return spec, r.applySpec(ctx, r.exec, m, direction, spec)
+}
+
+// parse decodes one direction of a migration, wrapping failures in a ParseError. A down
+// document declaring inverse: true is replaced by the inverse of the up document.
+func (r *Runner) parse(m Migration, direction string) (*schema.Spec, error) {
+	spec, err := m.Parse(r.registry, direction)
+	if err == nil && spec.Inverse {
+		spec, err = r.invert(m, direction, spec)
+	}
+	if err != nil {
+		r.logger.Error("parse migration", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.Any("err", err))
+		return nil, &ParseError{File: m.Identifier, Version: m.Version, Direction: direction, Err: err}
 	}
-	r.logger.Debug("apply migration", slog.Uint64("version", uint64(version)), slog.Int("actions", len(spec.Actions)))
+	return spec, nil
+}
+
+// invert derives the down spec of m from its up document, keeping the down document's
+// allow_protected override.
+func (r *Runner) invert(m Migration, direction string, down *schema.Spec) (*schema.Spec, error) {
+	if direction != DirectionDown {
+		return nil, fmt.Errorf("inverse: true is only allowed in a down migration")
+	}
+	up, err := m.Parse(r.registry, DirectionUp)
+	if err != nil {
+		return nil, fmt.Errorf("inverse of up migration: %w", err)
+	}
+	spec, err := up.Invert()
+	if err != nil {
+		return nil, fmt.Errorf("inverse of up migration: %w", err)
+	}
+	spec.AllowProtected = down.AllowProtected
+	return spec, nil
+}
+
+// expand resolves the includes and propagation of spec against the model as it stands before
+// m runs in direction: the simulated state for simulators, otherwise the replayed history.
+// Failures are reported as a ParseError.
+func (r *Runner) expand(exec executor.Executor, m Migration, direction string, spec *schema.Spec) (*schema.Spec, error) {
+	if !spec.HasIncludes() {
+		return spec, nil
+	}
+	var state *model.State
+	if sim, ok := exec.(*model.Simulator); ok {
+		state = sim.State()
+	} else {
+		target := m.Version
+		if direction == DirectionUp {
+			target = previousVersion(r.migrations, m.Version)
+		}
+		var err error
+		if state, err = r.replay(target); err != nil {
+			return nil, err
+		}
+	}
+	expanded, err := state.Expand(spec)
+	if err != nil {
+		r.logger.Error("expand includes", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.Any("err", err))
+		return nil, &ParseError{File: m.Identifier, Version: m.Version, Direction: direction, Err: err}
+	}
+	return expanded, nil
+}
+
+// applySpec executes the actions of one direction of m against exec.
+func (r *Runner) applySpec(ctx context.Context, exec executor.Executor, m Migration, direction string, spec *schema.Spec) error {
 	for _, action := range spec.Actions {
+		if action.Seed != nil {
+			if err := r.applySeed(ctx, exec, m, action.Seed); err != nil {
+				return err
+			}
+			continue
+		}
+		if action.Rename != nil {
+			if err := r.applyRename(ctx, exec, m, action.Rename); err != nil {
+				return err
+			}
+			continue
+		}
 		r.logger.Debug("apply action", slog.String("role", action.Role), slog.String("ensure", action.Ensure), slog.Int("add_count", len(action.Add)), slog.Int("remove_count", len(action.Remove)))
 		switch action.Ensure {
 		case "present":
-			if err := r.exec.EnsureRole(ctx, action.Role); err != nil {
+			if err := exec.EnsureRole(ctx, action.Role); err != nil {
 				r.logger.Error("ensure role", slog.String("role", action.Role), slog.Any("err", err))
-				return fmt.Errorf("ensure role %s: %w", action.Role, err)
+				return &executor.Error{Role: action.Role, Op: executor.OpEnsureRole, Err: err}
+			}
+			if sim, ok := exec.(*model.Simulator); ok {
+				sim.Include(action.Role, action.Includes)
+			}
+			if action.HasSet() {
+				if err := r.applySet(ctx, exec, m, action); err != nil {
+					return err
+				}
 			}
 			if len(action.Add) > 0 {
-				if err := r.exec.AddPermissions(ctx, action.Role, action.Add); err != nil {
+				if err := exec.AddPermissions(ctx, action.Role, action.Add); err != nil {
 					r.logger.Error("add permissions", slog.String("role", action.Role), slog.Any("err", err))
-					return fmt.Errorf("add permissions to %s: %w", action.Role, err)
+					return &executor.Error{Role: action.Role, Op: executor.OpAddPermissions, Err: err}
 				}
 			}
 			if len(action.Remove) > 0 {
-				if err := r.exec.RemovePermissions(ctx, action.Role, action.Remove); err != nil {
+				if err := exec.RemovePermissions(ctx, action.Role, action.Remove); err != nil {
 					r.logger.Error("remove permissions", slog.String("role", action.Role), slog.Any("err", err))
-					return fmt.Errorf("remove permissions from %s: %w", action.Role, err)
+					return &executor.Error{Role: action.Role, Op: executor.OpRemovePermissions, Err: err}
 				}
 			}
+			if err := r.writeMetadata(ctx, exec, action); err != nil {
+				return err
+			}
+			if err := r.applyAssignments(ctx, exec, action); err != nil {
+				return err
+			}
 		case "absent":
-			if err := r.exec.DeleteRole(ctx, action.Role); err != nil {
+			if err := exec.DeleteRole(ctx, action.Role); err != nil {
 				r.logger.Error("delete role", slog.String("role", action.Role), slog.Any("err", err))
-				return fmt.Errorf("delete role %s: %w", action.Role, err)
+				return &executor.Error{Role: action.Role, Op: executor.OpDeleteRole, Err: err}
 			}
 		default:
 			r.logger.Error("unknown ensure value", slog.String("ensure", action.Ensure), slog.String("role", action.Role))
@@ -319,6 +559,58 @@ func (r *Runner) apply(ctx context.Context, version uint, data []byte) error {
 	return nil
 }
 
+// beginSimulation seeds the dry-run simulator with the model implied by the current version.
+func (r *Runner) beginSimulation(current int) error {
+	if !r.dryRun {
+		return nil
+	}
+	if current < 0 {
+		current = 0
+	}
+	seed, err := r.replay(uint(current))
+	if err != nil {
+		return err
+	}
+	r.sim = model.NewSimulator(seed)
+	return nil
+}
+
+// endSimulation records the resulting dry-run model in report.
+func (r *Runner) endSimulation(report *RunReport) {
+	if !r.dryRun || r.sim == nil {
+		return
+	}
+	report.Result = r.sim.State().Map()
+	r.logger.Info("dry run: resulting state", slog.Any("roles", report.Result))
+}
+
+// recordApplied stores history for an applied migration when the store supports it.
+func (r *Runner) recordApplied(ctx context.Context, m Migration) error {
+	hs, ok := r.store.(store.HistoryStore)
+	if !ok {
+		return nil
+	}
+	rec := store.Record{Version: m.Version, AppliedAt: time.Now().UTC(), Checksum: m.Checksum()}
+	if err := hs.RecordApplied(ctx, rec); err != nil {
+		r.logger.Error("record applied migration", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+		return fmt.Errorf("record applied migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// forgetApplied drops history for a rolled-back migration when the store supports it.
+func (r *Runner) forgetApplied(ctx context.Context, version uint) error {
+	hs, ok := r.store.(store.HistoryStore)
+	if !ok {
+		return nil
+	}
+	if err := hs.RemoveApplied(ctx, version); err != nil {
+		r.logger.Error("remove applied migration", slog.Uint64("version", uint64(version)), slog.Any("err", err))
+		return fmt.Errorf("remove applied migration %d: %w", version, err)
+	}
+	return nil
+}
+
 func sortMigrations(ms []Migration) []Migration {
 	out := make([]Migration, len(ms))
 	copy(out, ms)
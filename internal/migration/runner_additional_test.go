@@ -5,9 +5,9 @@ import (
 	"errors"
 	"testing"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/schema"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/state/memory"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
 	"github.com/stretchr/testify/require"
 )
 
@@ -35,13 +35,15 @@ func (e *errStore) Close() error                   { return nil }
 func TestRunnerUpFailsOnLockError(t *testing.T) {
 	store := &errStore{lockErr: errors.New("locked")}
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, nil)
-	require.Error(t, r.Up(context.Background(), nil))
+	_, err := r.Up(context.Background(), nil)
+	require.Error(t, err)
 }
 
 func TestRunnerUpFailsOnVersionError(t *testing.T) {
 	store := &errStore{versionErr: errors.New("boom")}
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, nil)
-	require.Error(t, r.Up(context.Background(), nil))
+	_, err := r.Up(context.Background(), nil)
+	require.Error(t, err)
 }
 
 func TestRunnerDownUsesDefaultStep(t *testing.T) {
@@ -52,7 +54,8 @@ func TestRunnerDownUsesDefaultStep(t *testing.T) {
 	require.NoError(t, store.SetVersion(context.Background(), 1, false))
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
 
-	require.NoError(t, r.Down(context.Background(), 0))
+	_, err := r.Down(context.Background(), 0)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 0, v)
@@ -67,7 +70,8 @@ func TestRunnerUpNormalizesNegativeVersion(t *testing.T) {
 	require.NoError(t, store.SetVersion(context.Background(), -1, false))
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
 
-	require.NoError(t, r.Up(context.Background(), nil))
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 1, v)
@@ -81,7 +85,8 @@ func TestRunnerUpDryRunDoesNotPersist(t *testing.T) {
 	store := memory.New()
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, true, ms)
 
-	require.NoError(t, r.Up(context.Background(), nil))
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 0, v)
@@ -96,7 +101,8 @@ func TestRunnerDownDryRunDoesNotPersist(t *testing.T) {
 	require.NoError(t, store.SetVersion(context.Background(), 1, false))
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, true, ms)
 
-	require.NoError(t, r.Down(context.Background(), 1))
+	_, err := r.Down(context.Background(), 1)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 1, v)
@@ -111,7 +117,8 @@ func TestRunnerMigrateNoopWhenAtTarget(t *testing.T) {
 	require.NoError(t, store.SetVersion(context.Background(), 1, false))
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
 
-	require.NoError(t, r.Migrate(context.Background(), 1))
+	_, err := r.Migrate(context.Background(), 1)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 1, v)
@@ -125,7 +132,8 @@ func TestRunnerMigrateUpToSetVersionError(t *testing.T) {
 	store := &errStore{setErr: errors.New("set version")}
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
 
-	require.Error(t, r.Migrate(context.Background(), 1))
+	_, err := r.Migrate(context.Background(), 1)
+	require.Error(t, err)
 }
 
 func TestRunnerMigrateDownDryRunDoesNotPersist(t *testing.T) {
@@ -137,7 +145,9 @@ func TestRunnerMigrateDownDryRunDoesNotPersist(t *testing.T) {
 	require.NoError(t, store.SetVersion(context.Background(), 2, false))
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, true, ms)
 
-	require.NoError(t, r.Migrate(context.Background(), 0))
+	report, err := r.Migrate(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"r": {}}, report.Result, "the report holds the simulated state")
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 2, v)
@@ -149,7 +159,9 @@ func TestRunnerApplyRemovesPermissions(t *testing.T) {
 	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, nil)
 	data := []byte("version: 1\nactions:\n  - role: r\n    ensure: present\n    remove:\n      - p1\n")
 
-	require.NoError(t, r.apply(context.Background(), 1, data))
+	spec, err := r.apply(context.Background(), Migration{Version: 1, Up: data}, DirectionUp)
+	require.NoError(t, err)
+	require.Len(t, spec.Actions, 1)
 	require.Equal(t, []string{"p1"}, exec.PermsRemoved["r"])
 }
 
@@ -159,5 +171,6 @@ func TestRunnerApplyAddPermissionsError(t *testing.T) {
 	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, nil)
 	data := []byte("version: 1\nactions:\n  - role: r\n    ensure: present\n    add:\n      - p1\n")
 
-	require.Error(t, r.apply(context.Background(), 1, data))
+	_, err := r.apply(context.Background(), Migration{Version: 1, Up: data}, DirectionUp)
+	require.Error(t, err)
 }
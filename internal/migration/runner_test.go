@@ -6,9 +6,9 @@ import (
 	"log/slog"
 	"testing"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/schema"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/state/memory"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
 	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/stretchr/testify/require"
@@ -30,7 +30,8 @@ func TestRunnerUpAndDown(t *testing.T) {
 	r := NewRunner(store, exec, reg, logger, false, migrations)
 
 	ctx := context.Background()
-	require.NoError(t, r.Up(ctx, nil))
+	_, err = r.Up(ctx, nil)
+	require.NoError(t, err)
 
 	version, dirty, err := store.Version(ctx)
 	require.NoError(t, err)
@@ -43,7 +44,8 @@ func TestRunnerUpAndDown(t *testing.T) {
 	require.ElementsMatch(t, []string{"app:read", "app:write"}, exec.PermsAdded["app:admin"])
 
 	// Roll back one step
-	require.NoError(t, r.Down(ctx, 1))
+	_, err = r.Down(ctx, 1)
+	require.NoError(t, err)
 	version, dirty, err = store.Version(ctx)
 	require.NoError(t, err)
 	require.False(t, dirty)
@@ -57,10 +59,13 @@ func TestRunnerDirtyStatePreventsActions(t *testing.T) {
 	reg := schema.DefaultRegistry()
 
 	r := NewRunner(m, exec, reg, nil, false, []Migration{})
-	err := r.Up(context.Background(), nil)
-	require.Error(t, err)
-	err = r.Down(context.Background(), 1)
-	require.Error(t, err)
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrDirty)
+	var dirtyErr *DirtyError
+	require.ErrorAs(t, err, &dirtyErr)
+	require.Equal(t, 1, dirtyErr.Version)
+	_, err = r.Down(context.Background(), 1)
+	require.ErrorIs(t, err, ErrDirty)
 }
 
 func TestRunnerApplyErrorMarksDirty(t *testing.T) {
@@ -76,8 +81,13 @@ func TestRunnerApplyErrorMarksDirty(t *testing.T) {
 	reg := schema.DefaultRegistry()
 	r := NewRunner(store, exec, reg, nil, false, migrations)
 
-	err = r.Up(context.Background(), nil)
-	require.Error(t, err)
+	_, err = r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, executor.ErrExecutor)
+	require.ErrorIs(t, err, context.Canceled)
+	var execErr *executor.Error
+	require.ErrorAs(t, err, &execErr)
+	require.Equal(t, executor.OpEnsureRole, execErr.Op)
+	require.NotEmpty(t, execErr.Role)
 	_, dirty, derr := store.Version(context.Background())
 	require.NoError(t, derr)
 	require.True(t, dirty)
@@ -91,8 +101,8 @@ func TestRunnerMissingDownErrors(t *testing.T) {
 	r := NewRunner(store, exec, reg, nil, false, []Migration{
 		{Version: 1, Up: []byte("version:1"), Down: []byte("version:1")},
 	})
-	err := r.Down(context.Background(), 1)
-	require.Error(t, err)
+	_, err := r.Down(context.Background(), 1)
+	require.ErrorIs(t, err, ErrVersionNotFound)
 }
 
 func TestRunnerUpRespectsTarget(t *testing.T) {
@@ -108,7 +118,8 @@ func TestRunnerUpRespectsTarget(t *testing.T) {
 	r := NewRunner(store, exec, reg, nil, false, migrations)
 
 	target := uint(1)
-	require.NoError(t, r.Up(context.Background(), &target))
+	_, err = r.Up(context.Background(), &target)
+	require.NoError(t, err)
 
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
@@ -121,7 +132,8 @@ func TestRunnerHandlesNoMigrations(t *testing.T) {
 	exec := executor.NewMock()
 	reg := schema.DefaultRegistry()
 	r := NewRunner(store, exec, reg, nil, false, []Migration{})
-	require.NoError(t, r.Up(context.Background(), nil))
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 0, v)
@@ -141,14 +153,16 @@ func TestRunnerMigrateUpAndDown(t *testing.T) {
 	r := NewRunner(store, exec, reg, nil, false, migrations)
 
 	// up to 2
-	require.NoError(t, r.Migrate(context.Background(), 2))
+	_, err = r.Migrate(context.Background(), 2)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 2, v)
 	require.False(t, dirty)
 
 	// down to 1
-	require.NoError(t, r.Migrate(context.Background(), 1))
+	_, err = r.Migrate(context.Background(), 1)
+	require.NoError(t, err)
 	v, dirty, err = store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 1, v)
@@ -160,7 +174,8 @@ func TestRunnerMigrateErrorsOnDirty(t *testing.T) {
 	require.NoError(t, store.SetVersion(context.Background(), 1, true))
 	reg := schema.DefaultRegistry()
 	r := NewRunner(store, executor.NewMock(), reg, nil, false, []Migration{})
-	require.Error(t, r.Migrate(context.Background(), 0))
+	_, err := r.Migrate(context.Background(), 0)
+	require.Error(t, err)
 }
 
 func TestRunnerMigrateRejectsUnknownTarget(t *testing.T) {
@@ -169,8 +184,22 @@ func TestRunnerMigrateRejectsUnknownTarget(t *testing.T) {
 	r := NewRunner(store, executor.NewMock(), reg, nil, false, []Migration{
 		{Version: 1, Up: []byte("version:1"), Down: []byte("version:1")},
 	})
-	err := r.Migrate(context.Background(), 5)
-	require.Error(t, err)
+	_, err := r.Migrate(context.Background(), 5)
+	require.ErrorIs(t, err, ErrVersionNotFound)
+}
+
+func TestRunnerParseErrorCarriesFileAndVersion(t *testing.T) {
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, []Migration{
+		{Version: 3, Identifier: "bad_roles", Up: []byte("version: 99\n"), Down: []byte("version: 1\n")},
+	})
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorIs(t, err, schema.ErrUnsupportedSchema)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Equal(t, "bad_roles", parseErr.File)
+	require.Equal(t, uint(3), parseErr.Version)
+	require.Equal(t, DirectionUp, parseErr.Direction)
 }
 
 type closeStore struct {
@@ -199,7 +228,7 @@ func TestRunnerApplyRejectsUnknownEnsure(t *testing.T) {
 	store := memory.New()
 	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, nil)
 
-	err := r.apply(context.Background(), 1, []byte("version: 1\nactions:\n  - role: r\n    ensure: weird\n"))
+	_, err := r.apply(context.Background(), Migration{Version: 1, Up: []byte("version: 1\nactions:\n  - role: r\n    ensure: weird\n")}, DirectionUp)
 	require.Error(t, err)
 }
 
@@ -211,6 +240,85 @@ func TestRunnerMigrateDownMissingVersion(t *testing.T) {
 		{Version: 1, Up: []byte("version: 1"), Down: []byte("version: 1")},
 	})
 
-	err := r.Migrate(context.Background(), 0)
+	_, err := r.Migrate(context.Background(), 0)
+	require.Error(t, err)
+}
+
+func TestRunnerReportsExecutedMigrations(t *testing.T) {
+	ms := []Migration{
+		{Version: 1, Identifier: "roles", Up: []byte("version: 1\nactions:\n  - role: r\n  - role: s\n"), Down: []byte("version: 1\nactions:\n  - role: r\n    ensure: absent\n")},
+		{Version: 2, Identifier: "broken", Up: []byte("version: 1\nactions:\n  - role: t\n    ensure: weird\n"), Down: []byte("version: 1\nactions:\n  - role: t\n    ensure: absent\n")},
+	}
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+
+	report, err := r.Up(context.Background(), nil)
 	require.Error(t, err)
+	require.NotNil(t, report)
+	require.Equal(t, 0, report.StartVersion)
+	require.Equal(t, 1, report.FinalVersion)
+	require.Equal(t, 1, report.Applied)
+	require.Equal(t, 1, report.Failed)
+	require.Len(t, report.Entries, 2)
+	require.Equal(t, RunEntry{Version: 1, Identifier: "roles", Direction: DirectionUp, Actions: 2, Duration: report.Entries[0].Duration}, report.Entries[0])
+	require.Equal(t, uint(2), report.Entries[1].Version)
+	require.NotEmpty(t, report.Entries[1].Error)
+}
+
+func navigationMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Identifier: "a", Up: []byte("version: 1\nactions:\n  - role: a\n"), Down: []byte("version: 1\nactions:\n  - role: a\n    ensure: absent\n")},
+		{Version: 2, Identifier: "b", Up: []byte("version: 1\nactions:\n  - role: b\n"), Down: []byte("version: 1\nactions:\n  - role: b\n    ensure: absent\n")},
+		{Version: 3, Identifier: "c", Up: []byte("version: 1\nactions:\n  - role: c\n"), Down: []byte("version: 1\nactions:\n  - role: c\n    ensure: absent\n")},
+	}
+}
+
+func TestRunnerUpStepsAppliesNextN(t *testing.T) {
+	store := memory.New()
+	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, navigationMigrations())
+
+	report, err := r.UpSteps(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Applied)
+	require.Equal(t, 2, report.FinalVersion)
+
+	report, err = r.UpSteps(context.Background(), 5)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Applied)
+	v, _, err := store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+}
+
+func TestRunnerRedoAndReset(t *testing.T) {
+	store := memory.New()
+	exec := executor.NewMock()
+	r := NewRunner(store, exec, schema.DefaultRegistry(), nil, false, navigationMigrations())
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+
+	report, err := r.Redo(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Entries, 2)
+	require.Equal(t, DirectionDown, report.Entries[0].Direction)
+	require.Equal(t, DirectionUp, report.Entries[1].Direction)
+	require.Equal(t, uint(3), report.Entries[1].Version)
+	require.Equal(t, 3, report.FinalVersion)
+	require.Contains(t, exec.Live, "c")
+
+	dry := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, true, navigationMigrations())
+	report, err = dry.Reset(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, report.RolledBack)
+	require.Equal(t, 0, report.FinalVersion)
+	v, _, err := store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, v)
+
+	report, err = r.Reset(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, report.RolledBack)
+	v, _, err = store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, v)
+	require.Empty(t, exec.Live)
 }
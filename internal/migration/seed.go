@@ -0,0 +1,336 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+// ErrNoSeedFS signals a seed action while the runner has no filesystem to read seed files from.
+var ErrNoSeedFS = errors.New("seed files unavailable")
+
+// SeedError reports a seed file that stopped part way after applying Rows rows. When
+// Resumable, that progress is checkpointed and the store is not marked dirty, so re-running
+// the migration continues after the last completed batch.
+type SeedError struct {
+	File      string
+	Rows      int
+	Resumable bool
+	Err       error
+}
+
+func (e *SeedError) Error() string {
+	return fmt.Sprintf("seed %s stopped after %d rows: %v", e.File, e.Rows, e.Err)
+}
+
+func (e *SeedError) Unwrap() error { return e.Err }
+
+// SetSeedFS sets the filesystem seed files are read from, normally the migrations directory.
+func (r *Runner) SetSeedFS(fsys fs.FS) {
+	r.seedFS = fsys
+}
+
+// seedRow is one user role assignment read from a seed file; Entry is its 1-based position
+// among the file's rows (the CSV header excluded).
+type seedRow struct {
+	User   string `json:"user_id"`
+	Role   string `json:"role"`
+	Tenant string `json:"tenant"`
+	Entry  int    `json:"-"`
+}
+
+// seedCheckpoint is persisted after every batch so a failed seed resumes where it stopped.
+// Checksum ties the progress to the file content; an edited file starts over.
+type seedCheckpoint struct {
+	Checksum string `json:"checksum"`
+	Rows     int    `json:"rows"`
+}
+
+// applySeed streams a seed file in batches, assigning (or unassigning) each row's role.
+// Live runs checkpoint progress in a store.ValueStore and resume from it after a failure.
+func (r *Runner) applySeed(ctx context.Context, exec executor.Executor, m Migration, seed *schema.Seed) error {
+	op := executor.OpAssignRole
+	if seed.Unassign {
+		op = executor.OpUnassignRole
+	}
+	assigner, ok := exec.(executor.Assigner)
+	if !ok {
+		err := fmt.Errorf("executor %T cannot assign roles to users", exec)
+		r.logger.Error("seed roles", slog.String("file", seed.File), slog.Any("err", err))
+		return &executor.Error{Op: op, Err: err}
+	}
+	if r.seedFS == nil {
+		r.logger.Error("seed roles", slog.String("file", seed.File), slog.Any("err", ErrNoSeedFS))
+		return fmt.Errorf("%w: %s needs a source that reads files next to its migrations or an explicit seed filesystem", ErrNoSeedFS, seed.File)
+	}
+	checksum, err := seedChecksum(r.seedFS, seed.File)
+	if err != nil {
+		r.logger.Error("read seed file", slog.String("file", seed.File), slog.Any("err", err))
+		return fmt.Errorf("read seed file %s: %w", seed.File, err)
+	}
+
+	// simulators (dry runs, plans, round trips) never resume or persist progress
+	values := r.liveValues(exec)
+	if _, simulated := exec.(*model.Simulator); values == nil && !simulated {
+		r.logger.Warn("store cannot checkpoint seed progress; a failure leaves the migration dirty", slog.String("file", seed.File), slog.String("store", fmt.Sprintf("%T", r.store)))
+	}
+	key := seedKey(m.Version, seed)
+	skip := r.seedResume(ctx, values, key, checksum)
+
+	f, err := r.seedFS.Open(seed.File)
+	if err != nil {
+		r.logger.Error("open seed file", slog.String("file", seed.File), slog.Any("err", err))
+		return fmt.Errorf("open seed file %s: %w", seed.File, err)
+	}
+	defer f.Close()
+	next, err := seedReader(seed.Format, f)
+	if err != nil {
+		r.logger.Error("read seed file", slog.String("file", seed.File), slog.Any("err", err))
+		return fmt.Errorf("read seed file %s: %w", seed.File, err)
+	}
+
+	r.logger.Info("seed start", slog.String("file", seed.File), slog.String("op", op), slog.Int("batch_size", seed.BatchSize), slog.Int("resume_from", skip))
+	done := 0
+	batch := make([]seedRow, 0, seed.BatchSize)
+	flush := func() error {
+		for _, row := range batch {
+			if err := seedOne(ctx, assigner, seed.Unassign, row); err != nil {
+				r.logger.Error("seed row", slog.String("file", seed.File), slog.Int("entry", row.Entry), slog.String("user", row.User), slog.String("role", row.Role), slog.Any("err", err))
+				return &SeedError{
+					File:      seed.File,
+					Rows:      done,
+					Resumable: values != nil,
+					Err:       &executor.Error{Role: row.Role, Op: op, Err: fmt.Errorf("entry %d user %s: %w", row.Entry, row.User, err)},
+				}
+			}
+		}
+		done += len(batch)
+		batch = batch[:0]
+		r.logger.Info("seed progress", slog.String("file", seed.File), slog.Int("rows", done))
+		return r.seedSave(ctx, values, key, seedCheckpoint{Checksum: checksum, Rows: done})
+	}
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			r.logger.Error("read seed file", slog.String("file", seed.File), slog.Any("err", err))
+			return fmt.Errorf("read seed file %s: %w", seed.File, err)
+		}
+		if done < skip {
+			done++
+			continue
+		}
+		batch = append(batch, row)
+		if len(batch) == seed.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if values != nil {
+		if err := values.DeleteValue(ctx, key); err != nil {
+			r.logger.Warn("clear seed checkpoint", slog.String("file", seed.File), slog.Any("err", err))
+		}
+	}
+	r.logger.Info("seed complete", slog.String("file", seed.File), slog.Int("rows", done), slog.Int("resumed_from", skip))
+	return nil
+}
+
+func seedOne(ctx context.Context, assigner executor.Assigner, unassign bool, row seedRow) error {
+	if unassign {
+		return assigner.UnassignRole(ctx, row.Tenant, row.User, row.Role)
+	}
+	return assigner.AssignRole(ctx, row.Tenant, row.User, row.Role)
+}
+
+// seedKey names the checkpoint of a migration's seed file and direction in the store.
+func seedKey(version uint, seed *schema.Seed) string {
+	op := "assign"
+	if seed.Unassign {
+		op = "unassign"
+	}
+	return fmt.Sprintf("seed:%d:%s:%s", version, op, seed.File)
+}
+
+// seedResume returns the number of rows already applied by an interrupted run of the same file.
+func (r *Runner) seedResume(ctx context.Context, values store.ValueStore, key, checksum string) int {
+	if values == nil {
+		return 0
+	}
+	raw, ok, err := values.Value(ctx, key)
+	if err != nil {
+		r.logger.Warn("read seed checkpoint; starting over", slog.String("key", key), slog.Any("err", err))
+		return 0
+	}
+	if !ok {
+		return 0
+	}
+	var cp seedCheckpoint
+	if err := json.Unmarshal([]byte(raw), &cp); err != nil || cp.Checksum != checksum {
+		r.logger.Warn("seed checkpoint does not match the file; starting over", slog.String("key", key))
+		return 0
+	}
+	r.logger.Info("resuming seed", slog.String("key", key), slog.Int("rows", cp.Rows))
+	return cp.Rows
+}
+
+func (r *Runner) seedSave(ctx context.Context, values store.ValueStore, key string, cp seedCheckpoint) error {
+	if values == nil {
+		return nil
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := values.SetValue(ctx, key, string(data)); err != nil {
+		r.logger.Error("save seed checkpoint", slog.String("key", key), slog.Any("err", err))
+		return fmt.Errorf("save seed checkpoint %s: %w", key, err)
+	}
+	return nil
+}
+
+// seedRows calls fn with every row of a seed file.
+func (r *Runner) seedRows(seed *schema.Seed, fn func(seedRow)) error {
+	f, err := r.seedFS.Open(seed.File)
+	if err != nil {
+		r.logger.Error("open seed file", slog.String("file", seed.File), slog.Any("err", err))
+		return fmt.Errorf("open seed file %s: %w", seed.File, err)
+	}
+	defer f.Close()
+	next, err := seedReader(seed.Format, f)
+	if err != nil {
+		return fmt.Errorf("read seed file %s: %w", seed.File, err)
+	}
+	for {
+		row, err := next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read seed file %s: %w", seed.File, err)
+		}
+		fn(row)
+	}
+}
+
+func seedChecksum(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// seedReader returns an iterator over the rows of a seed file; it reports io.EOF at the end.
+func seedReader(format string, r io.Reader) (func() (seedRow, error), error) {
+	switch format {
+	case schema.SeedFormatCSV:
+		return csvSeedReader(r)
+	case schema.SeedFormatJSON:
+		return jsonSeedReader(r)
+	default:
+		return nil, fmt.Errorf("unsupported seed format %q", format)
+	}
+}
+
+// csvSeedReader reads a CSV file whose header names the user_id, role and optional tenant columns.
+func csvSeedReader(r io.Reader) (func() (seedRow, error), error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("missing header row")
+		}
+		return nil, err
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	userCol, ok := cols["user_id"]
+	if !ok {
+		return nil, fmt.Errorf("header has no user_id column")
+	}
+	roleCol, ok := cols["role"]
+	if !ok {
+		return nil, fmt.Errorf("header has no role column")
+	}
+	tenantCol, hasTenant := cols["tenant"]
+	index := 0
+	return func() (seedRow, error) {
+		record, err := cr.Read()
+		if err != nil {
+			return seedRow{}, err
+		}
+		index++
+		row := seedRow{User: record[userCol], Role: record[roleCol], Entry: index}
+		if hasTenant {
+			row.Tenant = record[tenantCol]
+		}
+		return normalizeSeedRow(row)
+	}, nil
+}
+
+// jsonSeedReader streams a JSON array of {"user_id", "role", "tenant"} objects.
+func jsonSeedReader(r io.Reader) (func() (seedRow, error), error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected a JSON array of assignments")
+	}
+	index := 0
+	return func() (seedRow, error) {
+		if !dec.More() {
+			return seedRow{}, io.EOF
+		}
+		index++
+		var row seedRow
+		if err := dec.Decode(&row); err != nil {
+			return seedRow{}, fmt.Errorf("entry %d: %w", index, err)
+		}
+		row.Entry = index
+		return normalizeSeedRow(row)
+	}, nil
+}
+
+func normalizeSeedRow(row seedRow) (seedRow, error) {
+	row.User = strings.TrimSpace(row.User)
+	row.Role = strings.TrimSpace(row.Role)
+	row.Tenant = strings.TrimSpace(row.Tenant)
+	if row.User == "" || row.Role == "" {
+		return seedRow{}, fmt.Errorf("entry %d is missing user_id or role", row.Entry)
+	}
+	if row.Tenant == "" {
+		row.Tenant = executor.DefaultTenant
+	}
+	return row, nil
+}
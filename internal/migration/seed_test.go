@@ -0,0 +1,181 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+const seedCSV = "user_id,role,tenant\nu1,admin,\nu2,admin,tenant-a\nu3,viewer,\nu4,viewer,\nu5,admin,\n"
+
+func seedMigrations() []Migration {
+	return []Migration{{
+		Version:    1,
+		Identifier: "seed_legacy_users",
+		Up:         []byte("version: 1\nactions:\n  - role: admin\n  - role: viewer\n  - seed: {file: users.csv, batch_size: 2}\n"),
+		Down:       []byte("version: 1\nactions:\n  - seed: {file: users.csv, unassign: true}\n  - role: admin\n    ensure: absent\n  - role: viewer\n    ensure: absent\n"),
+	}}
+}
+
+// recordingAssigner fails when assigning failUser and logs every user it assigns.
+type recordingAssigner struct {
+	*executor.Mock
+	failUser string
+	assigned []string
+}
+
+func (a *recordingAssigner) AssignRole(ctx context.Context, tenantID, userID, role string) error {
+	if userID == a.failUser {
+		return errors.New("core unavailable")
+	}
+	a.assigned = append(a.assigned, userID)
+	return a.Mock.AssignRole(ctx, tenantID, userID, role)
+}
+
+// plainStore hides the optional interfaces of the wrapped store.
+type plainStore struct{ store.Store }
+
+func TestRunnerSeedsAssignmentsInBatches(t *testing.T) {
+	exec := executor.NewMock()
+	st := memory.New()
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, seedMigrations())
+	r.SetSeedFS(fstest.MapFS{"users.csv": {Data: []byte(seedCSV)}})
+
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, exec.Assignments, 5)
+	require.Contains(t, exec.Assignments, executor.Assignment{Tenant: "tenant-a", User: "u2", Role: "admin"})
+	require.Contains(t, exec.Assignments, executor.Assignment{Tenant: executor.DefaultTenant, User: "u4", Role: "viewer"})
+	_, ok, err := st.Value(context.Background(), seedKey(1, &schema.Seed{File: "users.csv"}))
+	require.NoError(t, err)
+	require.False(t, ok, "checkpoint is cleared once the seed completes")
+
+	_, err = r.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Empty(t, exec.Assignments)
+}
+
+func TestRunnerSeedResumesAfterFailure(t *testing.T) {
+	exec := &recordingAssigner{Mock: executor.NewMock(), failUser: "u4"}
+	st := memory.New()
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, seedMigrations())
+	r.SetSeedFS(fstest.MapFS{"users.csv": {Data: []byte(seedCSV)}})
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, executor.ErrExecutor)
+	var seedErr *SeedError
+	require.True(t, errors.As(err, &seedErr))
+	require.Equal(t, 2, seedErr.Rows)
+	require.True(t, seedErr.Resumable)
+	require.ErrorContains(t, err, "seed users.csv stopped after 2 rows")
+	require.ErrorContains(t, err, "entry 4 user u4")
+	require.Equal(t, []string{"u1", "u2", "u3"}, exec.assigned)
+	v, dirty, err := st.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, v)
+	require.False(t, dirty, "a checkpointed seed leaves the state clean for a re-run")
+
+	exec.failUser = ""
+	exec.assigned = nil
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"u3", "u4", "u5"}, exec.assigned, "the first completed batch is skipped")
+}
+
+func TestRunnerSeedIgnoresCheckpointsOfOtherMigrations(t *testing.T) {
+	exec := executor.NewMock()
+	st := memory.New()
+	files := fstest.MapFS{"users.csv": {Data: []byte(seedCSV)}}
+	checksum, err := seedChecksum(files, "users.csv")
+	require.NoError(t, err)
+	// an earlier migration seeding the same file stopped after 4 rows
+	require.NoError(t, st.SetValue(context.Background(), seedKey(1, &schema.Seed{File: "users.csv"}), `{"checksum":"`+checksum+`","rows":4}`))
+	require.NoError(t, st.SetVersion(context.Background(), 1, false))
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: admin\n  - role: viewer\n")},
+		{Version: 2, Up: []byte("version: 1\nactions:\n  - seed: {file: users.csv}\n")},
+	})
+	r.SetSeedFS(files)
+
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, exec.Assignments, 5, "version 2 does not resume from version 1's checkpoint")
+}
+
+func TestRunnerSeedWithoutValueStoreMarksDirty(t *testing.T) {
+	exec := &recordingAssigner{Mock: executor.NewMock(), failUser: "u2"}
+	st := plainStore{memory.New()}
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, seedMigrations())
+	r.SetSeedFS(fstest.MapFS{"users.csv": {Data: []byte(seedCSV)}})
+
+	_, err := r.Up(context.Background(), nil)
+	var seedErr *SeedError
+	require.True(t, errors.As(err, &seedErr))
+	require.False(t, seedErr.Resumable)
+	_, dirty, err := st.Version(context.Background())
+	require.NoError(t, err)
+	require.True(t, dirty)
+}
+
+func TestRunnerSeedReadsJSON(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, []Migration{{
+		Version: 1,
+		Up:      []byte("version: 1\nactions:\n  - role: admin\n  - seed: {file: seeds/users.json}\n"),
+	}})
+	r.SetSeedFS(fstest.MapFS{"seeds/users.json": {Data: []byte(`[{"user_id": "u1", "role": "admin"}, {"user_id": "u2", "role": "admin", "tenant": "t1"}]`)}})
+
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []executor.Assignment{
+		{Tenant: executor.DefaultTenant, User: "u1", Role: "admin"},
+		{Tenant: "t1", User: "u2", Role: "admin"},
+	}, exec.Assignments)
+}
+
+func TestRunnerSeedRejectsBadFiles(t *testing.T) {
+	cases := map[string]string{
+		"role,tenant\nadmin,\n":    "no user_id column",
+		"user_id,role\n,admin\n":   "entry 1 is missing user_id or role",
+		"user_id,role\nu1,admin,x": "wrong number of fields",
+	}
+	for data, want := range cases {
+		r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, seedMigrations())
+		r.SetSeedFS(fstest.MapFS{"users.csv": {Data: []byte(data)}})
+		_, err := r.Up(context.Background(), nil)
+		require.ErrorContains(t, err, want, data)
+	}
+}
+
+func TestRunnerSeedRequiresSeedFS(t *testing.T) {
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, seedMigrations())
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrNoSeedFS)
+}
+
+func TestPlanAndRoundTripSimulateSeeds(t *testing.T) {
+	exec := executor.NewMock()
+	st := memory.New()
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, seedMigrations())
+	r.SetSeedFS(fstest.MapFS{"users.csv": {Data: []byte(seedCSV)}})
+
+	plan, err := r.Plan(context.Background(), nil)
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 1)
+	require.Empty(t, exec.Assignments)
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Failed())
+	_, ok, err := st.Value(context.Background(), seedKey(1, &schema.Seed{File: "users.csv"}))
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,180 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+// applySet makes the action role's permissions exactly match its set (or, for restore, the
+// set captured before the up migration ran) by adding and removing only the difference.
+// It needs an executor implementing executor.Reader. Live runs snapshot the replaced set in
+// a store.ValueStore so the down migration can restore it.
+func (r *Runner) applySet(ctx context.Context, exec executor.Executor, m Migration, action schema.Action) error {
+	current, err := r.currentPermissions(ctx, exec, action.Role)
+	if err != nil {
+		return err
+	}
+	target, err := r.setTarget(ctx, exec, m, action)
+	if err != nil {
+		return err
+	}
+	add, remove := diffPermissions(current, target)
+	values := r.liveValues(exec)
+	if action.Set != nil {
+		if err := r.saveSnapshot(ctx, values, setKey(m.Version, action.Role), current); err != nil {
+			return err
+		}
+	}
+	r.logger.Debug("set permissions", slog.String("role", action.Role), slog.Any("add", add), slog.Any("remove", remove))
+	if len(add) > 0 {
+		if err := exec.AddPermissions(ctx, action.Role, add); err != nil {
+			r.logger.Error("add permissions", slog.String("role", action.Role), slog.Any("err", err))
+			return &executor.Error{Role: action.Role, Op: executor.OpSetPermissions, Err: err}
+		}
+	}
+	if len(remove) > 0 {
+		if err := exec.RemovePermissions(ctx, action.Role, remove); err != nil {
+			r.logger.Error("remove permissions", slog.String("role", action.Role), slog.Any("err", err))
+			return &executor.Error{Role: action.Role, Op: executor.OpSetPermissions, Err: err}
+		}
+	}
+	if action.Restore && values != nil {
+		if err := values.DeleteValue(ctx, setKey(m.Version, action.Role)); err != nil {
+			r.logger.Warn("clear permission snapshot", slog.String("role", action.Role), slog.Any("err", err))
+		}
+	}
+	return nil
+}
+
+// checkSetProtection refuses a migration whose set or restore actions would strip a protected
+// role or permission, comparing against the permissions exec currently reports.
+func (r *Runner) checkSetProtection(ctx context.Context, exec executor.Executor, m Migration, direction string, spec *schema.Spec) error {
+	if r.protection.Empty() {
+		return nil
+	}
+	for _, action := range spec.Actions {
+		if !action.HasSet() {
+			continue
+		}
+		current, err := r.currentPermissions(ctx, exec, action.Role)
+		if err != nil {
+			return err
+		}
+		target, err := r.setTarget(ctx, exec, m, action)
+		if err != nil {
+			return err
+		}
+		_, remove := diffPermissions(current, target)
+		for _, perm := range remove {
+			if r.protection.ProtectsRole(action.Role) || r.protection.ProtectsPermission(perm) {
+				return r.refuseProtected(m, direction, spec, &ProtectedError{Role: action.Role, Permission: perm})
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Runner) currentPermissions(ctx context.Context, exec executor.Executor, role string) ([]string, error) {
+	reader, ok := exec.(executor.Reader)
+	if !ok {
+		err := fmt.Errorf("executor %T cannot read permissions; set and restore need an executor.Reader", exec)
+		r.logger.Error("set permissions", slog.String("role", role), slog.Any("err", err))
+		return nil, &executor.Error{Role: role, Op: executor.OpSetPermissions, Err: err}
+	}
+	perms, err := reader.ListPermissions(ctx, role)
+	if err != nil {
+		r.logger.Error("list permissions", slog.String("role", role), slog.Any("err", err))
+		return nil, &executor.Error{Role: role, Op: executor.OpSetPermissions, Err: err}
+	}
+	return perms, nil
+}
+
+// setTarget returns the exact permission set an action asks for. Restore uses the snapshot
+// taken when m's up migration ran, falling back to the set implied by earlier migrations.
+func (r *Runner) setTarget(ctx context.Context, exec executor.Executor, m Migration, action schema.Action) ([]string, error) {
+	if !action.Restore {
+		return action.Set, nil
+	}
+	if values := r.liveValues(exec); values != nil {
+		key := setKey(m.Version, action.Role)
+		raw, ok, err := values.Value(ctx, key)
+		if err != nil {
+			r.logger.Error("read permission snapshot", slog.String("key", key), slog.Any("err", err))
+			return nil, fmt.Errorf("read permission snapshot %s: %w", key, err)
+		}
+		if ok {
+			var perms []string
+			if err := json.Unmarshal([]byte(raw), &perms); err != nil {
+				r.logger.Error("parse permission snapshot", slog.String("key", key), slog.Any("err", err))
+				return nil, fmt.Errorf("parse permission snapshot %s: %w", key, err)
+			}
+			return perms, nil
+		}
+		r.logger.Warn("no permission snapshot; restoring the set implied by earlier migrations", slog.Uint64("version", uint64(m.Version)), slog.String("role", action.Role))
+	}
+	prev, err := r.replay(previousVersion(r.migrations, m.Version))
+	if err != nil {
+		return nil, err
+	}
+	return prev.Permissions(action.Role), nil
+}
+
+// liveValues returns the store's ValueStore for real runs; simulations never persist snapshots.
+func (r *Runner) liveValues(exec executor.Executor) store.ValueStore {
+	if _, simulated := exec.(*model.Simulator); simulated {
+		return nil
+	}
+	values, _ := r.store.(store.ValueStore)
+	return values
+}
+
+func (r *Runner) saveSnapshot(ctx context.Context, values store.ValueStore, key string, perms []string) error {
+	if values == nil {
+		return nil
+	}
+	if perms == nil {
+		perms = []string{}
+	}
+	data, err := json.Marshal(perms)
+	if err != nil {
+		return err
+	}
+	if err := values.SetValue(ctx, key, string(data)); err != nil {
+		r.logger.Error("save permission snapshot", slog.String("key", key), slog.Any("err", err))
+		return fmt.Errorf("save permission snapshot %s: %w", key, err)
+	}
+	return nil
+}
+
+// setKey names the snapshot of role's permissions replaced by migration version.
+func setKey(version uint, role string) string {
+	return fmt.Sprintf("set:%d:%s", version, role)
+}
+
+// diffPermissions returns the permissions to add to and remove from current to reach target.
+func diffPermissions(current, target []string) (add, remove []string) {
+	have := make(map[string]struct{}, len(current))
+	for _, p := range current {
+		have[p] = struct{}{}
+	}
+	want := make(map[string]struct{}, len(target))
+	for _, p := range target {
+		want[p] = struct{}{}
+		if _, ok := have[p]; !ok {
+			add = append(add, p)
+		}
+	}
+	for _, p := range current {
+		if _, ok := want[p]; !ok {
+			remove = append(remove, p)
+		}
+	}
+	return add, remove
+}
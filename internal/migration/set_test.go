@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func setMigrations() []Migration {
+	return []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: admin\n    add: [a, b]\n"), Down: []byte("version: 1\nactions:\n  - role: admin\n    ensure: absent\n")},
+		{Version: 2, Up: []byte("version: 1\nactions:\n  - role: admin\n    set: [b, c]\n"), Down: []byte("version: 1\nactions:\n  - role: admin\n    restore: true\n")},
+	}
+}
+
+func TestRunnerSetsExactPermissionsAndRestoresSnapshot(t *testing.T) {
+	exec := executor.NewMock()
+	st := memory.New()
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, setMigrations())
+	ctx := context.Background()
+
+	_, err := r.UpSteps(ctx, 1)
+	require.NoError(t, err)
+	// an out-of-band grant the migrations do not know about
+	exec.Live["admin"] = append(exec.Live["admin"], "stray")
+
+	_, err = r.Up(ctx, nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"b", "c"}, exec.Live["admin"])
+	require.Equal(t, []string{"c"}, exec.PermsAdded["admin"][2:])
+	require.ElementsMatch(t, []string{"a", "stray"}, exec.PermsRemoved["admin"])
+	snapshot, ok, err := st.Value(ctx, "set:2:admin")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, `["a","b","stray"]`, snapshot)
+
+	_, err = r.Down(ctx, 1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "b", "stray"}, exec.Live["admin"], "restore brings back the exact previous set")
+	_, ok, err = st.Value(ctx, "set:2:admin")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestRunnerRestoreFallsBackToMigrationHistory(t *testing.T) {
+	exec := executor.NewMock()
+	st := plainStore{memory.New()}
+	r := NewRunner(st, exec, schema.DefaultRegistry(), nil, false, setMigrations())
+	ctx := context.Background()
+
+	_, err := r.Up(ctx, nil)
+	require.NoError(t, err)
+	_, err = r.Down(ctx, 1)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"a", "b"}, exec.Live["admin"])
+}
+
+func TestRunnerSetRequiresReader(t *testing.T) {
+	r := NewRunner(memory.New(), plainExecutor{executor.NewMock()}, schema.DefaultRegistry(), nil, false, setMigrations())
+
+	_, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, executor.ErrExecutor)
+	require.ErrorContains(t, err, "need an executor.Reader")
+}
+
+func TestRunnerSetRefusesStrippingProtectedPermission(t *testing.T) {
+	exec := executor.NewMock()
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, setMigrations())
+	r.SetProtection(Protection{Permissions: []string{"a"}}, nil)
+
+	report, err := r.Up(context.Background(), nil)
+	require.ErrorIs(t, err, ErrProtected)
+	require.Equal(t, 1, report.Applied)
+	require.Empty(t, exec.PermsRemoved)
+
+	r = NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, setMigrations())
+	r.SetProtection(Protection{Permissions: []string{"a"}}, nil)
+	_, err = r.Plan(context.Background(), nil)
+	require.ErrorIs(t, err, ErrProtected)
+}
+
+func TestRoundTripAcceptsRestoreAsInverseOfSet(t *testing.T) {
+	r := NewRunner(memory.New(), executor.NewMock(), schema.DefaultRegistry(), nil, false, setMigrations())
+
+	report, err := r.RoundTrip(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, report.Failed())
+}
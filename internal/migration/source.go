@@ -0,0 +1,36 @@
+package migration
+
+import (
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// FileSource is implemented by source drivers that can also read the files stored next to
+// their migrations, such as seed files and _defs.yaml. Names are relative to the directory
+// holding the migrations.
+type FileSource interface {
+	Files() fs.FS
+}
+
+// SourceFiles returns the files stored next to src's migrations, or nil when src cannot
+// read them.
+func SourceFiles(src source.Driver) fs.FS {
+	if fsrc, ok := src.(FileSource); ok {
+		return fsrc.Files()
+	}
+	return nil
+}
+
+// WithFiles returns src extended with FileSource, reading the files next to its migrations
+// from files.
+func WithFiles(src source.Driver, files fs.FS) source.Driver {
+	return &filesSource{Driver: src, files: files}
+}
+
+type filesSource struct {
+	source.Driver
+	files fs.FS
+}
+
+func (s *filesSource) Files() fs.FS { return s.files }
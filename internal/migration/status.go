@@ -0,0 +1,140 @@
+package migration
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+// Migration states reported by Status.
+const (
+	StateApplied           = "applied"
+	StatePending           = "pending"
+	StateMissingFromSource = "missing-from-source"
+)
+
+// MigrationStatus describes one known migration.
+// AppliedAt and AppliedChecksum are only set when the store keeps history.
+type MigrationStatus struct {
+	Version         uint       `json:"version"`
+	Identifier      string     `json:"identifier"`
+	State           string     `json:"state"`
+	MissingDown     bool       `json:"missing_down"`
+	Checksum        string     `json:"checksum,omitempty"`
+	AppliedAt       *time.Time `json:"applied_at,omitempty"`
+	AppliedChecksum string     `json:"applied_checksum,omitempty"`
+	// Modified reports that the source changed since the migration was applied.
+	Modified bool `json:"modified"`
+	// Header is the schema v2 header of the up document, when it declares one.
+	Header *schema.Header `json:"header,omitempty"`
+}
+
+// StatusReport lists every migration known to the source or the store.
+type StatusReport struct {
+	Current    int               `json:"current"`
+	Dirty      bool              `json:"dirty"`
+	Migrations []MigrationStatus `json:"migrations"`
+}
+
+// Pending returns the versions not yet applied, in ascending order.
+func (s *StatusReport) Pending() []uint {
+	return s.versionsIn(StatePending)
+}
+
+// MissingFromSource returns applied versions that no longer exist in the source.
+func (s *StatusReport) MissingFromSource() []uint {
+	return s.versionsIn(StateMissingFromSource)
+}
+
+func (s *StatusReport) versionsIn(state string) []uint {
+	out := make([]uint, 0)
+	for _, m := range s.Migrations {
+		if m.State == state {
+			out = append(out, m.Version)
+		}
+	}
+	return out
+}
+
+// Status reports the current version, dirty flag and per-migration state.
+func (r *Runner) Status(ctx context.Context) (*StatusReport, error) {
+	current, dirty, err := r.store.Version(ctx)
+	if err != nil {
+		r.logger.Error("read version", slog.Any("err", err))
+		return nil, err
+	}
+
+	history := map[uint]store.Record{}
+	if hs, ok := r.store.(store.HistoryStore); ok {
+		records, err := hs.History(ctx)
+		if err != nil {
+			r.logger.Error("read history", slog.Any("err", err))
+			return nil, err
+		}
+		for _, rec := range records {
+			history[rec.Version] = rec
+		}
+	}
+
+	report := &StatusReport{Current: current, Dirty: dirty, Migrations: []MigrationStatus{}}
+	known := map[uint]struct{}{}
+	for _, m := range r.migrations {
+		known[m.Version] = struct{}{}
+		st := MigrationStatus{
+			Version:     m.Version,
+			Identifier:  m.Identifier,
+			State:       StatePending,
+			MissingDown: m.MissingDown,
+			Checksum:    m.Checksum(),
+			Header:      r.header(m),
+		}
+		if int(m.Version) <= current {
+			st.State = StateApplied
+		}
+		if rec, ok := history[m.Version]; ok {
+			appliedAt := rec.AppliedAt
+			st.AppliedAt = &appliedAt
+			st.AppliedChecksum = rec.Checksum
+			st.Modified = rec.Checksum != "" && rec.Checksum != st.Checksum
+		}
+		report.Migrations = append(report.Migrations, st)
+	}
+
+	missing := map[uint]store.Record{}
+	for v, rec := range history {
+		if _, ok := known[v]; !ok {
+			missing[v] = rec
+		}
+	}
+	if current > 0 {
+		v := uint(current)
+		_, inSource := known[v]
+		_, recorded := missing[v]
+		if !inSource && !recorded {
+			missing[v] = store.Record{Version: v}
+		}
+	}
+	for v, rec := range missing {
+		st := MigrationStatus{Version: v, State: StateMissingFromSource, AppliedChecksum: rec.Checksum}
+		if !rec.AppliedAt.IsZero() {
+			appliedAt := rec.AppliedAt
+			st.AppliedAt = &appliedAt
+		}
+		report.Migrations = append(report.Migrations, st)
+	}
+	sort.Slice(report.Migrations, func(i, j int) bool { return report.Migrations[i].Version < report.Migrations[j].Version })
+	return report, nil
+}
+
+// header returns the up document's schema v2 header; unparsable documents have none.
+func (r *Runner) header(m Migration) *schema.Header {
+	spec, err := m.Parse(r.registry, DirectionUp)
+	if err != nil || spec.Header.Empty() {
+		return nil
+	}
+	return &spec.Header
+}
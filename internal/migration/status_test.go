@@ -4,9 +4,10 @@ import (
 	"context"
 	"testing"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/schema"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/state/memory"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	statestore "github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
 	"github.com/stretchr/testify/require"
 )
 
@@ -21,10 +22,10 @@ func TestRunnerStatusReportsPending(t *testing.T) {
 	reg := schema.DefaultRegistry()
 	r := NewRunner(store, exec, reg, nil, false, ms)
 
-	current, pending, err := r.Status(context.Background())
+	report, err := r.Status(context.Background())
 	require.NoError(t, err)
-	require.Equal(t, 1, current)
-	require.Equal(t, []uint{2}, pending)
+	require.Equal(t, 1, report.Current)
+	require.Equal(t, []uint{2}, report.Pending())
 }
 
 func TestRunnerMigrateDryRunDoesNotMutate(t *testing.T) {
@@ -36,9 +37,83 @@ func TestRunnerMigrateDryRunDoesNotMutate(t *testing.T) {
 	reg := schema.DefaultRegistry()
 	r := NewRunner(store, exec, reg, nil, true, ms)
 
-	require.NoError(t, r.Migrate(context.Background(), 1))
+	_, err := r.Migrate(context.Background(), 1)
+	require.NoError(t, err)
 	v, dirty, err := store.Version(context.Background())
 	require.NoError(t, err)
 	require.Equal(t, 0, v)
 	require.False(t, dirty)
 }
+
+func TestRunnerStatusReportsHistoryAndMissing(t *testing.T) {
+	ms := []Migration{
+		{Version: 1, Identifier: "roles", Up: []byte("version: 1\nactions:\n  - role: r\n"), Down: []byte("version: 1\nactions:\n  - role: r\n    ensure: absent\n")},
+		{Version: 2, Identifier: "support", Up: []byte("version: 1\nactions:\n  - role: s\n"), MissingDown: true},
+	}
+	store := memory.New()
+	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+	target := uint(1)
+	_, err := r.Up(context.Background(), &target)
+	require.NoError(t, err)
+	// a version applied elsewhere whose file has since been removed from the source
+	require.NoError(t, store.RecordApplied(context.Background(), statestore.Record{Version: 7, Checksum: "gone"}))
+	require.NoError(t, store.SetVersion(context.Background(), 1, true))
+
+	report, err := r.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, report.Current)
+	require.True(t, report.Dirty)
+	require.Len(t, report.Migrations, 3)
+
+	applied := report.Migrations[0]
+	require.Equal(t, StateApplied, applied.State)
+	require.Equal(t, "roles", applied.Identifier)
+	require.NotNil(t, applied.AppliedAt)
+	require.Equal(t, ms[0].Checksum(), applied.AppliedChecksum)
+	require.False(t, applied.Modified)
+
+	pending := report.Migrations[1]
+	require.Equal(t, StatePending, pending.State)
+	require.True(t, pending.MissingDown)
+	require.Nil(t, pending.AppliedAt)
+
+	require.Equal(t, []uint{7}, report.MissingFromSource())
+}
+
+func TestRunnerDownRefusesMissingDownFile(t *testing.T) {
+	ms := []Migration{{Version: 1, Up: []byte("version: 1\nactions:\n  - role: r\n"), MissingDown: true}}
+	store := memory.New()
+	require.NoError(t, store.SetVersion(context.Background(), 1, false))
+	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+
+	_, err := r.Down(context.Background(), 1)
+	require.Error(t, err)
+	v, dirty, err := store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.False(t, dirty)
+}
+
+func TestRunnerHistoryListsAppliedInOrder(t *testing.T) {
+	ms := []Migration{
+		{Version: 1, Identifier: "roles", Up: []byte("version: 1\nactions:\n  - role: r\n"), Down: []byte("version: 1\nactions:\n  - role: r\n    ensure: absent\n")},
+		{Version: 2, Identifier: "support", Up: []byte("version: 1\nactions:\n  - role: s\n"), Down: []byte("version: 1\nactions:\n  - role: s\n    ensure: absent\n")},
+	}
+	store := memory.New()
+	r := NewRunner(store, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms)
+	_, err := r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.NoError(t, store.RecordApplied(context.Background(), statestore.Record{Version: 9, Checksum: "gone"}))
+
+	history, err := r.History(context.Background())
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	require.Equal(t, uint(9), history[0].Version) // zero applied_at sorts first
+	require.Empty(t, history[0].Identifier)
+	require.Equal(t, "roles", history[1].Identifier)
+	require.Equal(t, "support", history[2].Identifier)
+	require.False(t, history[2].Modified)
+
+	_, err = NewRunner(&closeStore{}, executor.NewMock(), schema.DefaultRegistry(), nil, false, ms).History(context.Background())
+	require.Error(t, err)
+}
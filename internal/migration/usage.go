@@ -0,0 +1,284 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/BeardedWonderDev/st-migrate-go/internal/model"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// ErrRoleInUse signals a migration would delete a role that users still hold.
+// Use errors.As with *RoleInUseError for details.
+var ErrRoleInUse = errors.New("role still assigned to users")
+
+// RoleInUseError reports a role a migration would delete while users still hold it. Unknown
+// is set when the executor cannot tell how many users hold the role; Users is then zero.
+// For a rename, Tenants lists the tenants it does not move users in where Users still hold
+// the old role.
+type RoleInUseError struct {
+	Version   uint
+	Direction string
+	Role      string
+	Users     int
+	Unknown   bool
+	Tenants   []string
+}
+
+func (e *RoleInUseError) Error() string {
+	if len(e.Tenants) > 0 {
+		return fmt.Sprintf("migration %d (%s) renames role %s, but %d user(s) hold it in tenants the rename does not list %v and would lose it; list those tenants or force the rename", e.Version, e.Direction, e.Role, e.Users, e.Tenants)
+	}
+	if e.Unknown {
+		return fmt.Sprintf("migration %d (%s) would delete role %s, but the executor cannot count the users holding it; force the deletion to proceed", e.Version, e.Direction, e.Role)
+	}
+	return fmt.Sprintf("migration %d (%s) would delete role %s still held by %d user(s); unassign it first or force the deletion", e.Version, e.Direction, e.Role, e.Users)
+}
+
+// Is matches ErrRoleInUse.
+func (e *RoleInUseError) Is(target error) bool {
+	return target == ErrRoleInUse
+}
+
+// SetForce makes the runner delete roles still held by users, logging a warning instead of refusing.
+func (r *Runner) SetForce(force bool) {
+	r.force = force
+}
+
+// errUsersUnknown signals the executor cannot count the users holding a role.
+var errUsersUnknown = errors.New("executor cannot count users holding roles")
+
+// checkRoleUsage refuses a parsed migration that deletes roles users still hold, unless forced.
+// Deletions are refused too when the executor cannot count role holders (it needs
+// executor.UserCounter); dry runs tolerate counting failures. Renames are checked by checkRenames.
+func (r *Runner) checkRoleUsage(ctx context.Context, m Migration, direction string, spec *schema.Spec) error {
+	if err := r.checkRenames(ctx, m, direction, spec); err != nil {
+		return err
+	}
+	counts, err := r.affectedUsers(ctx, spec)
+	if errors.Is(err, errUsersUnknown) {
+		role := deletedRoles(spec)[0]
+		if r.force || r.dryRun {
+			r.logger.Warn("cannot count users holding deleted roles; deleting anyway", slog.Uint64("version", uint64(m.Version)), slog.String("role", role), slog.String("executor", fmt.Sprintf("%T", r.exec)))
+			return nil
+		}
+		r.logger.Error("cannot count users holding deleted role", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.String("role", role), slog.String("executor", fmt.Sprintf("%T", r.exec)))
+		return &RoleInUseError{Version: m.Version, Direction: direction, Role: role, Unknown: true}
+	}
+	if err != nil {
+		if r.dryRun {
+			r.logger.Warn("dry run: cannot count users holding deleted roles", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+			return nil
+		}
+		return err
+	}
+	for _, action := range spec.Actions {
+		users := counts[action.Role]
+		if action.Ensure != "absent" || users == 0 {
+			continue
+		}
+		if r.force {
+			r.logger.Warn("deleting role still held by users", slog.Uint64("version", uint64(m.Version)), slog.String("role", action.Role), slog.Int("users", users))
+			continue
+		}
+		r.logger.Error("migration deletes role still held by users", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.String("role", action.Role), slog.Int("users", users))
+		return &RoleInUseError{Version: m.Version, Direction: direction, Role: action.Role, Users: users}
+	}
+	return nil
+}
+
+// checkRenames refuses renames whose old role users hold in tenants the rename does not
+// list, since deleting the old role takes it from them, unless forced. Like deletions, renames
+// are refused when the executor cannot list the tenants holding the old role.
+func (r *Runner) checkRenames(ctx context.Context, m Migration, direction string, spec *schema.Spec) error {
+	for _, action := range spec.Actions {
+		if action.Rename == nil {
+			continue
+		}
+		rename := action.Rename
+		users, tenants, err := r.strandedUsers(ctx, rename)
+		switch {
+		case errors.Is(err, errUsersUnknown):
+			if r.force || r.dryRun {
+				r.logger.Warn("cannot list users holding renamed role outside its tenants; renaming anyway", slog.Uint64("version", uint64(m.Version)), slog.String("role", rename.From), slog.String("executor", fmt.Sprintf("%T", r.exec)))
+				continue
+			}
+			r.logger.Error("cannot list users holding renamed role", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.String("role", rename.From), slog.String("executor", fmt.Sprintf("%T", r.exec)))
+			return &RoleInUseError{Version: m.Version, Direction: direction, Role: rename.From, Unknown: true}
+		case err != nil:
+			if r.dryRun {
+				r.logger.Warn("dry run: cannot list users holding renamed role", slog.Uint64("version", uint64(m.Version)), slog.Any("err", err))
+				continue
+			}
+			return err
+		case users == 0:
+			continue
+		case r.force:
+			r.logger.Warn("renaming role held by users in unlisted tenants", slog.Uint64("version", uint64(m.Version)), slog.String("role", rename.From), slog.Int("users", users), slog.Any("tenants", tenants))
+			continue
+		}
+		r.logger.Error("rename leaves users in unlisted tenants without the role", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.String("role", rename.From), slog.Int("users", users), slog.Any("tenants", tenants))
+		return &RoleInUseError{Version: m.Version, Direction: direction, Role: rename.From, Users: users, Tenants: tenants}
+	}
+	return nil
+}
+
+// strandedUsers counts the users holding rename.From in tenants the rename does not list, and
+// names those tenants. It returns errUsersUnknown when the executor cannot list them.
+func (r *Runner) strandedUsers(ctx context.Context, rename *schema.Rename) (int, []string, error) {
+	users, ok := r.exec.(executor.UserLister)
+	tenants, ok2 := r.exec.(executor.TenantLister)
+	if !ok || !ok2 {
+		return 0, nil, errUsersUnknown
+	}
+	tenantIDs, err := tenants.ListTenants(ctx)
+	if err != nil {
+		r.logger.Error("list tenants", slog.Any("err", err))
+		return 0, nil, &executor.Error{Role: rename.From, Op: executor.OpListUsers, Err: err}
+	}
+	listed := model.RenameTenants(rename)
+	total := 0
+	var stranded []string
+	for _, tenant := range tenantIDs {
+		if slices.Contains(listed, tenant) {
+			continue
+		}
+		ids, err := users.ListUsersWithRole(ctx, tenant, rename.From)
+		if err != nil {
+			r.logger.Error("list users with role", slog.String("role", rename.From), slog.String("tenant", tenant), slog.Any("err", err))
+			return 0, nil, &executor.Error{Role: rename.From, Op: executor.OpListUsers, Err: err}
+		}
+		if len(ids) > 0 {
+			total += len(ids)
+			stranded = append(stranded, tenant)
+		}
+	}
+	return total, stranded, nil
+}
+
+// affectedUsers counts the users each role the spec deletes would be taken from. When the
+// executor can list role holders (executor.UserLister and executor.TenantLister) the spec's
+// assignments, unassignments and seed files are replayed over the actual holders first;
+// with only executor.UserCounter the live count is used as is. It returns errUsersUnknown
+// when the spec deletes a role and the executor can do neither.
+func (r *Runner) affectedUsers(ctx context.Context, spec *schema.Spec) (map[string]int, error) {
+	deleted := deletedRoles(spec)
+	if len(deleted) == 0 {
+		return nil, nil
+	}
+	held, err := r.roleHolders(ctx, deleted)
+	if errors.Is(err, errUsersUnknown) {
+		return r.countUsers(ctx, deleted)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tracked := map[string]bool{}
+	for _, role := range deleted {
+		tracked[role] = true
+	}
+	counts := map[string]int{}
+	for _, action := range spec.Actions {
+		switch {
+		case action.Rename != nil:
+		case action.Seed != nil:
+			if r.seedFS == nil {
+				continue
+			}
+			err := r.seedRows(action.Seed, func(row seedRow) {
+				if !tracked[row.Role] {
+					return
+				}
+				a := executor.Assignment{Tenant: row.Tenant, User: row.User, Role: row.Role}
+				if action.Seed.Unassign {
+					delete(held, a)
+				} else {
+					held[a] = struct{}{}
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+		case action.Ensure == "absent":
+			n := 0
+			for a := range held {
+				if a.Role == action.Role {
+					n++
+					delete(held, a)
+				}
+			}
+			counts[action.Role] = n
+		case tracked[action.Role]:
+			for _, a := range model.Assignments(action, action.Assign) {
+				held[a] = struct{}{}
+			}
+			for _, a := range model.Assignments(action, action.Unassign) {
+				delete(held, a)
+			}
+		}
+	}
+	return counts, nil
+}
+
+// roleHolders lists who holds each of roles in every tenant. It returns errUsersUnknown
+// when the executor cannot list role holders.
+func (r *Runner) roleHolders(ctx context.Context, roles []string) (map[executor.Assignment]struct{}, error) {
+	users, ok := r.exec.(executor.UserLister)
+	tenants, ok2 := r.exec.(executor.TenantLister)
+	if !ok || !ok2 {
+		return nil, errUsersUnknown
+	}
+	tenantIDs, err := tenants.ListTenants(ctx)
+	if err != nil {
+		r.logger.Error("list tenants", slog.Any("err", err))
+		return nil, &executor.Error{Op: executor.OpListUsers, Err: err}
+	}
+	held := map[executor.Assignment]struct{}{}
+	for _, role := range roles {
+		for _, tenant := range tenantIDs {
+			ids, err := users.ListUsersWithRole(ctx, tenant, role)
+			if err != nil {
+				r.logger.Error("list users with role", slog.String("role", role), slog.String("tenant", tenant), slog.Any("err", err))
+				return nil, &executor.Error{Role: role, Op: executor.OpListUsers, Err: err}
+			}
+			for _, id := range ids {
+				held[executor.Assignment{Tenant: tenant, User: id, Role: role}] = struct{}{}
+			}
+		}
+	}
+	return held, nil
+}
+
+// countUsers reports the live number of holders of each role, without accounting for the
+// spec's unassignments since the executor cannot say which users hold the role.
+func (r *Runner) countUsers(ctx context.Context, roles []string) (map[string]int, error) {
+	counter, ok := r.exec.(executor.UserCounter)
+	if !ok {
+		return nil, errUsersUnknown
+	}
+	counts := make(map[string]int, len(roles))
+	for _, role := range roles {
+		users, err := counter.CountUsersWithRole(ctx, role)
+		if err != nil {
+			r.logger.Error("count users with role", slog.String("role", role), slog.Any("err", err))
+			return nil, &executor.Error{Role: role, Op: executor.OpCountUsers, Err: err}
+		}
+		counts[role] = users
+	}
+	return counts, nil
+}
+
+// deletedRoles lists the roles the spec deletes, in action order.
+func deletedRoles(spec *schema.Spec) []string {
+	var roles []string
+	for _, action := range spec.Actions {
+		if action.Ensure == "absent" && action.Rename == nil && action.Seed == nil {
+			roles = append(roles, action.Role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,150 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	"github.com/stretchr/testify/require"
+)
+
+func roleInUseRunner(t *testing.T, exec *executor.Mock, dryRun bool) (*Runner, *memory.Store) {
+	t.Helper()
+	ms := []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: support\n"), Down: []byte("version: 1\nactions:\n  - role: support\n    ensure: absent\n")},
+	}
+	store := memory.New()
+	require.NoError(t, store.SetVersion(context.Background(), 1, false))
+	return NewRunner(store, exec, schema.DefaultRegistry(), nil, dryRun, ms), store
+}
+
+func TestRunnerRefusesDeletingRoleHeldByUsers(t *testing.T) {
+	exec := executor.NewMock()
+	exec.RoleUsers["support"] = []string{"u1", "u2"}
+	r, store := roleInUseRunner(t, exec, false)
+
+	_, err := r.Down(context.Background(), 1)
+	require.ErrorIs(t, err, ErrRoleInUse)
+	var inUse *RoleInUseError
+	require.True(t, errors.As(err, &inUse))
+	require.Equal(t, "support", inUse.Role)
+	require.Equal(t, 2, inUse.Users)
+	require.Empty(t, exec.RolesDeleted)
+
+	v, dirty, err := store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.False(t, dirty)
+}
+
+func TestRunnerForceDeletesRoleHeldByUsers(t *testing.T) {
+	exec := executor.NewMock()
+	exec.RoleUsers["support"] = []string{"u1"}
+	r, _ := roleInUseRunner(t, exec, false)
+	r.SetForce(true)
+
+	_, err := r.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"support"}, exec.RolesDeleted)
+}
+
+func TestRunnerDryRunRefusesDeletingRoleHeldByUsers(t *testing.T) {
+	exec := executor.NewMock()
+	exec.RoleUsers["support"] = []string{"u1"}
+	r, _ := roleInUseRunner(t, exec, true)
+
+	_, err := r.Down(context.Background(), 1)
+	require.ErrorIs(t, err, ErrRoleInUse)
+}
+
+func TestRunnerPlanShowsAffectedUsers(t *testing.T) {
+	exec := executor.NewMock()
+	exec.RoleUsers["support"] = []string{"u1", "u2", "u3"}
+	r, _ := roleInUseRunner(t, exec, false)
+
+	target := uint(0)
+	plan, err := r.Plan(context.Background(), &target)
+	require.NoError(t, err)
+	require.Len(t, plan.Steps, 1)
+	require.Equal(t, map[string]int{"support": 3}, plan.Steps[0].AffectedUsers)
+
+	exec.FailWith = errors.New("core unreachable")
+	plan, err = r.Plan(context.Background(), &target)
+	require.NoError(t, err)
+	require.Nil(t, plan.Steps[0].AffectedUsers)
+}
+
+// basicExecutor hides every optional capability of the wrapped executor.
+type basicExecutor struct{ executor.Executor }
+
+func TestRunnerRefusesDeletingWhenUsersCannotBeCounted(t *testing.T) {
+	mock := executor.NewMock()
+	ms := []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: support\n"), Down: []byte("version: 1\nactions:\n  - role: support\n    ensure: absent\n")},
+	}
+	st := memory.New()
+	require.NoError(t, st.SetVersion(context.Background(), 1, false))
+	r := NewRunner(st, basicExecutor{mock}, schema.DefaultRegistry(), nil, false, ms)
+
+	_, err := r.Down(context.Background(), 1)
+	require.ErrorIs(t, err, ErrRoleInUse)
+	var inUse *RoleInUseError
+	require.True(t, errors.As(err, &inUse))
+	require.True(t, inUse.Unknown)
+	require.ErrorContains(t, err, "cannot count the users holding it")
+	require.Empty(t, mock.RolesDeleted)
+
+	r.SetForce(true)
+	_, err = r.Down(context.Background(), 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"support"}, mock.RolesDeleted)
+}
+
+func TestRunnerCountsUsersInEveryTenant(t *testing.T) {
+	exec := executor.NewMock()
+	exec.Assignments = []executor.Assignment{{Tenant: "tenant-a", User: "u1", Role: "support"}}
+	r, _ := roleInUseRunner(t, exec, false)
+
+	_, err := r.Down(context.Background(), 1)
+	var inUse *RoleInUseError
+	require.True(t, errors.As(err, &inUse))
+	require.Equal(t, 1, inUse.Users)
+}
+
+func TestRunnerIgnoresUnassigningUsersWithoutTheRole(t *testing.T) {
+	exec := executor.NewMock()
+	exec.RoleUsers["support"] = []string{"u1", "u2"}
+	ms := []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: support\n    unassign: [u1, u3, u4]\n  - role: support\n    ensure: absent\n")},
+	}
+	r := NewRunner(memory.New(), exec, schema.DefaultRegistry(), nil, false, ms)
+
+	_, err := r.Up(context.Background(), nil)
+	var inUse *RoleInUseError
+	require.True(t, errors.As(err, &inUse))
+	require.Equal(t, 1, inUse.Users)
+	require.Empty(t, exec.RolesDeleted)
+}
+
+// counterExecutor can count role holders but not list them.
+type counterExecutor struct {
+	executor.Executor
+	executor.UserCounter
+}
+
+func TestRunnerWithOnlyUserCounterIgnoresUnassignments(t *testing.T) {
+	exec := executor.NewMock()
+	exec.RoleUsers["support"] = []string{"u1"}
+	ms := []Migration{
+		{Version: 1, Up: []byte("version: 1\nactions:\n  - role: support\n    unassign: [u1]\n  - role: support\n    ensure: absent\n")},
+	}
+	r := NewRunner(memory.New(), counterExecutor{exec, exec}, schema.DefaultRegistry(), nil, false, ms)
+
+	_, err := r.Up(context.Background(), nil)
+	var inUse *RoleInUseError
+	require.True(t, errors.As(err, &inUse))
+	require.Equal(t, 1, inUse.Users)
+}
@@ -0,0 +1,189 @@
+package migration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EachPrefix starts the variable names reserved for for_each expansion; RenderVars leaves
+// ${each.*} references to the schema parser.
+const EachPrefix = "each."
+
+// varRE matches an escaped "$${" or a ${name} reference in a document.
+var varRE = regexp.MustCompile(`\$\$\{|\$\{([^}\n]*)\}`)
+
+// RenderVars makes every migration substitute ${name} references with vars when it is parsed.
+// References are replaced inside the scalar values of the decoded document, after permission
+// sets are resolved, so a value is always plain text: it cannot add keys or actions, and a
+// value starting with $ is not a permission set reference. "$${" renders as a literal "${".
+// The values substituted into the up document are checksummed with it. Undefined variables
+// are left in place with a warning, or reported as ParseErrors when strict.
+func RenderVars(migrations []Migration, vars map[string]string, strict bool, logger *slog.Logger) error {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	var errs error
+	for i := range migrations {
+		m := &migrations[i]
+		found := false
+		for _, direction := range []string{DirectionUp, DirectionDown} {
+			r, refs, err := scanVars(m.Document(direction), vars)
+			for _, name := range r.undefined {
+				if strict {
+					err = errors.Join(err, fmt.Errorf("undefined variable %s", name))
+					continue
+				}
+				logger.Warn("undefined variable left as is", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.String("var", name))
+			}
+			if err != nil {
+				logger.Error("render variables", slog.Uint64("version", uint64(m.Version)), slog.String("direction", direction), slog.Any("err", err))
+				errs = errors.Join(errs, &ParseError{File: m.Identifier, Version: m.Version, Direction: direction, Err: err})
+				continue
+			}
+			found = found || refs
+			if direction == DirectionUp {
+				for _, name := range r.used {
+					m.resolved = append(m.resolved, fmt.Sprintf("${%s}: %s\n", name, vars[name])...)
+				}
+			}
+		}
+		if found {
+			m.vars = vars
+		}
+	}
+	return errs
+}
+
+// Masked references: the ${name} references and $${ escapes of a document are swapped for
+// private use characters of the same width before it is decoded, so references read as plain
+// text even inside flow collections ([${region}:read]) and every position is kept.
+const (
+	maskDollar = "\uE000"
+	maskOpen   = "\uE001"
+	maskClose  = "\uE002"
+	maskEscape = "\uE003"
+)
+
+// maskedRE matches a masked escape or a masked ${name} reference.
+var maskedRE = regexp.MustCompile(maskEscape + maskDollar + maskOpen + "|" + maskDollar + maskOpen + "([^" + maskClose + "\n]*)" + maskClose)
+
+// maskVars masks the references and escapes of doc; see maskDollar.
+func maskVars(doc []byte) []byte {
+	return varRE.ReplaceAllFunc(doc, func(ref []byte) []byte {
+		if bytes.Equal(ref, []byte("$${")) {
+			return []byte(maskEscape + maskDollar + maskOpen)
+		}
+		return []byte(maskDollar + maskOpen + string(ref[2:len(ref)-1]) + maskClose)
+	})
+}
+
+// rendering records what renderNode substituted.
+type rendering struct {
+	// used lists the variables substituted, in document order without repeats.
+	used []string
+	// undefined lists the undefined names, each with the line it first appears on.
+	undefined []string
+}
+
+// scanVars renders a throwaway decoding of doc to find its references; found reports whether
+// doc has any references or escapes. Documents that do not decode have none, so the schema
+// parser reports the problem.
+func scanVars(doc []byte, vars map[string]string) (r rendering, found bool, err error) {
+	if !varRE.Match(doc) {
+		return rendering{}, false, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(maskVars(doc), &root); err != nil {
+		return rendering{}, false, nil
+	}
+	err = r.renderNode(&root, vars)
+	return r, true, err
+}
+
+// renderNode substitutes the masked references in the scalar values under n. Mapping keys,
+// and references left in place, get their original text back.
+func (r *rendering) renderNode(n *yaml.Node, vars map[string]string) error {
+	var errs error
+	switch n.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range n.Content {
+			errs = errors.Join(errs, r.renderNode(child, vars))
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			n.Content[i].Value = unmask(n.Content[i].Value)
+			errs = errors.Join(errs, r.renderNode(n.Content[i+1], vars))
+		}
+	case yaml.ScalarNode:
+		errs = r.renderScalar(n, vars)
+	}
+	return errs
+}
+
+// unmask restores the original text of masked references.
+func unmask(s string) string {
+	return maskedRE.ReplaceAllStringFunc(s, func(ref string) string {
+		if strings.HasPrefix(ref, maskEscape) {
+			return "$${"
+		}
+		return "${" + strings.TrimSuffix(strings.TrimPrefix(ref, maskDollar+maskOpen), maskClose) + "}"
+	})
+}
+
+func (r *rendering) renderScalar(n *yaml.Node, vars map[string]string) error {
+	matches := maskedRE.FindAllStringSubmatchIndex(n.Value, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	var out strings.Builder
+	var errs error
+	substituted := false
+	last := 0
+	for _, loc := range matches {
+		out.WriteString(n.Value[last:loc[0]])
+		last = loc[1]
+		if loc[2] < 0 {
+			out.WriteString("${")
+			continue
+		}
+		inner := n.Value[loc[2]:loc[3]]
+		ref := "${" + inner + "}"
+		name := strings.TrimSpace(inner)
+		value, ok := vars[name]
+		switch {
+		case name == "":
+			errs = errors.Join(errs, fmt.Errorf("line %d: empty variable reference %s", n.Line, ref))
+			out.WriteString(ref)
+		case strings.HasPrefix(name, EachPrefix):
+			out.WriteString(ref)
+		case !ok:
+			if !slices.ContainsFunc(r.undefined, func(u string) bool { return strings.HasPrefix(u, "${"+name+"} ") }) {
+				r.undefined = append(r.undefined, fmt.Sprintf("${%s} (line %d)", name, n.Line))
+			}
+			out.WriteString(ref)
+		default:
+			if !slices.Contains(r.used, name) {
+				r.used = append(r.used, name)
+			}
+			out.WriteString(value)
+			substituted = true
+		}
+	}
+	out.WriteString(n.Value[last:])
+	n.Value = out.String()
+	if substituted && n.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		// let a plain scalar resolve to what it now reads as, such as a number
+		n.Tag = ""
+	}
+	return errs
+}
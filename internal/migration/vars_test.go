@@ -0,0 +1,88 @@
+package migration
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderVarsSubstitutesReferences(t *testing.T) {
+	plain := []byte("version: 1\nactions:\n  - role: admin\n")
+	migrations := []Migration{
+		{Version: 1, Up: plain},
+		{
+			Version: 2,
+			Up:      []byte("version: 1\nactions:\n  - role: ${tenant}:admin\n    add: [\"${ tenant }:read\", \"$${literal}\", \"${each.key}\"]\n"),
+			Down:    []byte("version: 1\nactions:\n  - role: ${tenant}:admin\n    ensure: absent\n"),
+		},
+	}
+
+	up := string(migrations[1].Up)
+	require.NoError(t, RenderVars(migrations, map[string]string{"tenant": "acme"}, true, nil))
+	require.Equal(t, plain, migrations[0].Up)
+	require.Equal(t, up, string(migrations[1].Up), "documents keep their bytes")
+	spec, err := migrations[1].Parse(schema.DefaultRegistry(), DirectionUp)
+	require.NoError(t, err)
+	require.Equal(t, "acme:admin", spec.Actions[0].Role)
+	require.Equal(t, []string{"acme:read", "${literal}", "${each.key}"}, spec.Actions[0].Add)
+	spec, err = migrations[1].Parse(schema.DefaultRegistry(), DirectionDown)
+	require.NoError(t, err)
+	require.Equal(t, "acme:admin", spec.Actions[0].Role)
+
+	require.Equal(t, sha256Hex(plain), migrations[0].Checksum())
+	other := []Migration{{Version: 2, Up: []byte(up)}}
+	require.NoError(t, RenderVars(other, map[string]string{"tenant": "globex"}, true, nil))
+	require.NotEqual(t, migrations[1].Checksum(), other[0].Checksum(), "values are checksummed with the up document")
+}
+
+func TestRenderVarsSubstitutesInsideFlowLists(t *testing.T) {
+	migrations := []Migration{{Version: 1, Up: []byte("version: 1\nactions:\n  - role: admin\n    add: [${region}:read, docs:${region}]\n")}}
+
+	require.NoError(t, RenderVars(migrations, map[string]string{"region": "eu"}, true, nil))
+	spec, err := migrations[0].Parse(schema.DefaultRegistry(), DirectionUp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"eu:read", "docs:eu"}, spec.Actions[0].Add)
+}
+
+func TestRenderVarsValuesCannotInjectYAML(t *testing.T) {
+	doc := "version: 1\nactions:\n  - role: ${tenant}:admin\n    add: [${perm}]\n"
+	migrations := []Migration{{Version: 1, Up: []byte(doc)}}
+	vars := map[string]string{"tenant": "acme\n  - role: evil\n    add: [all:write]", "perm": "docs:read, ensure: absent"}
+
+	require.NoError(t, RenderVars(migrations, vars, true, nil))
+	spec, err := migrations[0].Parse(schema.DefaultRegistry(), DirectionUp)
+	require.NoError(t, err)
+	require.Len(t, spec.Actions, 1, "a value cannot add actions")
+	require.Equal(t, "acme\n  - role: evil\n    add: [all:write]:admin", spec.Actions[0].Role)
+	require.Equal(t, []string{"docs:read, ensure: absent"}, spec.Actions[0].Add, "a value cannot add keys")
+	require.Equal(t, "present", spec.Actions[0].Ensure)
+}
+
+func TestRenderVarsValuesAreNotPermissionSets(t *testing.T) {
+	defs, err := LoadDefs(fstest.MapFS{DefsFile: {Data: []byte(defsYAML)}})
+	require.NoError(t, err)
+	migrations := []Migration{{Version: 1, Up: []byte("version: 1\nactions:\n  - role: billing\n    add: [${perm}, $billing_read]\n")}}
+
+	require.NoError(t, RenderVars(migrations, map[string]string{"perm": "$billing_all"}, true, nil))
+	require.NoError(t, ExpandDefs(migrations, defs, nil))
+	spec, err := migrations[0].Parse(schema.DefaultRegistry(), DirectionUp)
+	require.NoError(t, err)
+	require.Equal(t, []string{"$billing_all", "billing:read", "billing:export"}, spec.Actions[0].Add)
+}
+
+func TestRenderVarsReportsUndefinedVariables(t *testing.T) {
+	doc := "version: 1\nactions:\n  - role: ${tenant}:admin\n  - role: ${tenant}:viewer\n    add: [${region}:read]\n"
+	migrations := []Migration{{Version: 4, Identifier: "tenant_roles", Up: []byte(doc)}}
+
+	err := RenderVars(migrations, nil, true, nil)
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorContains(t, err, "parse migration 4 (tenant_roles, up): undefined variable ${tenant} (line 3)\nundefined variable ${region} (line 5)")
+
+	require.NoError(t, RenderVars(migrations, nil, false, nil))
+	require.Equal(t, doc, string(migrations[0].Up), "undefined references stay in place")
+
+	migrations[0].Up = []byte("version: 1\nactions:\n  - role: ${ }\n")
+	require.ErrorContains(t, RenderVars(migrations, nil, false, nil), "line 3: empty variable reference ${ }")
+}
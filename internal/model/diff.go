@@ -0,0 +1,80 @@
+package model
+
+import "sort"
+
+// Diff describes how a live state deviates from an expected state.
+// Permissions of roles that only exist on one side are listed as well, so the
+// diff alone is enough to reconcile the two states.
+type Diff struct {
+	ExtraRoles         []string            `json:"extra_roles"`
+	MissingRoles       []string            `json:"missing_roles"`
+	ExtraPermissions   map[string][]string `json:"extra_permissions"`
+	MissingPermissions map[string][]string `json:"missing_permissions"`
+}
+
+// Empty reports whether the two compared states were identical.
+func (d Diff) Empty() bool {
+	return len(d.ExtraRoles) == 0 && len(d.MissingRoles) == 0 &&
+		len(d.ExtraPermissions) == 0 && len(d.MissingPermissions) == 0
+}
+
+// Compare computes the difference between the expected and live states.
+// "Extra" entries exist live but not in expected; "missing" entries are expected but absent live.
+func Compare(expected, live *State) Diff {
+	d := Diff{
+		ExtraRoles:         []string{},
+		MissingRoles:       []string{},
+		ExtraPermissions:   map[string][]string{},
+		MissingPermissions: map[string][]string{},
+	}
+	for _, role := range live.Roles() {
+		if !expected.HasRole(role) {
+			d.ExtraRoles = append(d.ExtraRoles, role)
+		}
+		if extra := subtract(live.Permissions(role), expected, role); len(extra) > 0 {
+			d.ExtraPermissions[role] = extra
+		}
+	}
+	for _, role := range expected.Roles() {
+		if !live.HasRole(role) {
+			d.MissingRoles = append(d.MissingRoles, role)
+		}
+		if missing := subtract(expected.Permissions(role), live, role); len(missing) > 0 {
+			d.MissingPermissions[role] = missing
+		}
+	}
+	return d
+}
+
+// Roles returns every role mentioned by the diff in sorted order.
+func (d Diff) Roles() []string {
+	seen := map[string]struct{}{}
+	for _, r := range d.ExtraRoles {
+		seen[r] = struct{}{}
+	}
+	for _, r := range d.MissingRoles {
+		seen[r] = struct{}{}
+	}
+	for r := range d.ExtraPermissions {
+		seen[r] = struct{}{}
+	}
+	for r := range d.MissingPermissions {
+		seen[r] = struct{}{}
+	}
+	out := make([]string, 0, len(seen))
+	for r := range seen {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func subtract(perms []string, other *State, role string) []string {
+	out := make([]string, 0)
+	for _, p := range perms {
+		if !other.HasPermission(role, p) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// Include records that role includes the given roles.
+func (s *State) Include(role string, included []string) {
+	if len(included) == 0 {
+		return
+	}
+	set, ok := s.includes[role]
+	if !ok {
+		set = map[string]struct{}{}
+		s.includes[role] = set
+	}
+	for _, r := range included {
+		set[r] = struct{}{}
+	}
+}
+
+// Included returns the sorted roles role includes directly.
+func (s *State) Included(role string) []string {
+	out := make([]string, 0, len(s.includes[role]))
+	for r := range s.includes[role] {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Includers returns the sorted roles that include role, directly or through other roles.
+func (s *State) Includers(role string) []string {
+	seen := map[string]struct{}{}
+	queue := []string{role}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for r, included := range s.includes {
+			if _, ok := included[next]; !ok {
+				continue
+			}
+			if _, ok := seen[r]; ok || r == role {
+				continue
+			}
+			seen[r] = struct{}{}
+			queue = append(queue, r)
+		}
+	}
+	out := make([]string, 0, len(seen))
+	for r := range seen {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Expand returns spec with includes resolved into concrete Add permissions and every
+// propagating action followed by the same add/remove on each role including its role,
+// evaluated action by action against s. It reports unknown included roles and include
+// cycles. s is left unchanged.
+func (s *State) Expand(spec *schema.Spec) (*schema.Spec, error) {
+	work := s.Clone()
+	out := *spec
+	out.Actions = make([]schema.Action, 0, len(spec.Actions))
+	var errs error
+	for _, action := range spec.Actions {
+		expanded, err := work.expandAction(action)
+		errs = errors.Join(errs, err)
+		for _, a := range expanded {
+			work.applyAction(a)
+		}
+		out.Actions = append(out.Actions, expanded...)
+	}
+	return &out, errs
+}
+
+// expandAction resolves one action against s; see Expand.
+func (s *State) expandAction(action schema.Action) ([]schema.Action, error) {
+	if !action.HasIncludes() || action.Ensure != "present" {
+		return []schema.Action{action}, nil
+	}
+	var errs error
+	if len(action.Includes) > 0 {
+		add := append([]string(nil), action.Add...)
+		for _, role := range action.Includes {
+			switch {
+			case !s.HasRole(role):
+				errs = errors.Join(errs, fmt.Errorf("role %s includes unknown role %s", action.Role, role))
+			case slices.Contains(s.Includers(action.Role), role):
+				errs = errors.Join(errs, fmt.Errorf("role %s includes %s, which already includes %s", action.Role, role, action.Role))
+			default:
+				for _, p := range s.Permissions(role) {
+					if !slices.Contains(add, p) {
+						add = append(add, p)
+					}
+				}
+			}
+		}
+		action.Add = add
+	}
+	out := []schema.Action{action}
+	if action.Propagate {
+		for _, role := range s.Includers(action.Role) {
+			out = append(out, schema.Action{Role: role, Ensure: "present", Add: action.Add, Remove: action.Remove})
+		}
+	}
+	return out, errs
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateExpandResolvesIncludes(t *testing.T) {
+	s := New()
+	s.Apply(&schema.Spec{Actions: []schema.Action{
+		{Role: "viewer", Ensure: "present", Add: []string{"docs:read"}},
+	}})
+
+	spec, err := s.Expand(&schema.Spec{Actions: []schema.Action{
+		{Role: "viewer", Ensure: "present", Add: []string{"docs:export"}},
+		{Role: "editor", Ensure: "present", Add: []string{"docs:write"}, Includes: []string{"viewer"}},
+		{Role: "admin", Ensure: "present", Includes: []string{"editor"}},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"docs:write", "docs:export", "docs:read"}, spec.Actions[1].Add)
+	require.Equal(t, []string{"docs:export", "docs:read", "docs:write"}, spec.Actions[2].Add)
+	require.False(t, s.HasRole("editor"), "expanding leaves the state unchanged")
+
+	s.Apply(spec)
+	require.Equal(t, []string{"admin", "editor"}, s.Includers("viewer"))
+	require.Equal(t, []string{"viewer"}, s.Included("editor"))
+}
+
+func TestStateExpandPropagatesToIncludingRoles(t *testing.T) {
+	s := New()
+	s.Apply(&schema.Spec{Actions: []schema.Action{
+		{Role: "viewer", Ensure: "present", Add: []string{"docs:read", "docs:print"}},
+		{Role: "editor", Ensure: "present", Includes: []string{"viewer"}},
+		{Role: "admin", Ensure: "present", Includes: []string{"editor"}},
+	}})
+	require.Equal(t, []string{"docs:print", "docs:read"}, s.Permissions("admin"))
+
+	spec, err := s.Expand(&schema.Spec{Actions: []schema.Action{
+		{Role: "viewer", Ensure: "present", Add: []string{"docs:export"}, Remove: []string{"docs:print"}, Propagate: true},
+	}})
+	require.NoError(t, err)
+	require.Len(t, spec.Actions, 3)
+	require.Equal(t, schema.Action{Role: "admin", Ensure: "present", Add: []string{"docs:export"}, Remove: []string{"docs:print"}}, spec.Actions[1])
+
+	s.Apply(spec)
+	for _, role := range []string{"viewer", "editor", "admin"} {
+		require.Equal(t, []string{"docs:export", "docs:read"}, s.Permissions(role), role)
+	}
+}
+
+func TestStateExpandReportsUnknownRolesAndCycles(t *testing.T) {
+	s := New()
+	s.Apply(&schema.Spec{Actions: []schema.Action{
+		{Role: "viewer", Ensure: "present"},
+		{Role: "editor", Ensure: "present", Includes: []string{"viewer"}},
+	}})
+
+	_, err := s.Expand(&schema.Spec{Actions: []schema.Action{
+		{Role: "auditor", Ensure: "present", Includes: []string{"ghost"}},
+		{Role: "viewer", Ensure: "present", Includes: []string{"editor"}},
+	}})
+	require.ErrorContains(t, err, "role auditor includes unknown role ghost")
+	require.ErrorContains(t, err, "role viewer includes editor, which already includes viewer")
+}
+
+func TestStateDeleteAndRenameKeepIncludeLinks(t *testing.T) {
+	s := New()
+	s.Apply(&schema.Spec{Actions: []schema.Action{
+		{Role: "viewer", Ensure: "present"},
+		{Role: "editor", Ensure: "present", Includes: []string{"viewer"}},
+		{Rename: &schema.Rename{From: "viewer", To: "reader"}},
+	}})
+	require.Equal(t, []string{"editor"}, s.Includers("reader"))
+	require.Empty(t, s.Includers("viewer"))
+
+	s.DeleteRole("reader")
+	require.Empty(t, s.Included("editor"))
+	require.Empty(t, s.Clone().Includers("reader"))
+}
@@ -0,0 +1,257 @@
+package model
+
+import (
+	"sort"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+)
+
+// State is an in-memory view of roles, their permissions and the user assignments made by
+// migrations. It mirrors what the SuperTokens core would hold after applying a sequence of specs,
+// plus which roles include which (a model-only relation the core never sees).
+type State struct {
+	roles       map[string]map[string]struct{}
+	assignments map[executor.Assignment]struct{}
+	includes    map[string]map[string]struct{}
+}
+
+// New returns an empty state with no roles.
+func New() *State {
+	return &State{
+		roles:       map[string]map[string]struct{}{},
+		assignments: map[executor.Assignment]struct{}{},
+		includes:    map[string]map[string]struct{}{},
+	}
+}
+
+// FromMap builds a state from a role -> permissions map.
+func FromMap(roles map[string][]string) *State {
+	s := New()
+	for role, perms := range roles {
+		s.EnsureRole(role)
+		s.AddPermissions(role, perms)
+	}
+	return s
+}
+
+// EnsureRole creates the role if it does not exist.
+func (s *State) EnsureRole(role string) {
+	if _, ok := s.roles[role]; !ok {
+		s.roles[role] = map[string]struct{}{}
+	}
+}
+
+// DeleteRole removes the role, all of its permissions, every user assignment of it and its
+// include links in both directions.
+func (s *State) DeleteRole(role string) {
+	delete(s.roles, role)
+	for a := range s.assignments {
+		if a.Role == role {
+			delete(s.assignments, a)
+		}
+	}
+	delete(s.includes, role)
+	for _, included := range s.includes {
+		delete(included, role)
+	}
+}
+
+// RenameRole gives to the permissions, include links and users in tenants of from, then deletes
+// from (dropping its users in any other tenant). Unknown roles are ignored.
+func (s *State) RenameRole(from, to string, tenants []string) {
+	if !s.HasRole(from) {
+		return
+	}
+	s.AddPermissions(to, s.Permissions(from))
+	s.Include(to, s.Included(from))
+	for _, role := range s.Includers(from) {
+		if _, direct := s.includes[role][from]; direct {
+			s.Include(role, []string{to})
+		}
+	}
+	for _, tenant := range tenants {
+		for a := range s.assignments {
+			if a.Role == from && a.Tenant == tenant {
+				s.Assign(executor.Assignment{Tenant: tenant, User: a.User, Role: to})
+			}
+		}
+	}
+	s.DeleteRole(from)
+}
+
+// Assign records that a user holds a role in a tenant.
+func (s *State) Assign(a executor.Assignment) {
+	s.assignments[a] = struct{}{}
+}
+
+// Unassign removes a user's role in a tenant; unknown assignments are ignored.
+func (s *State) Unassign(a executor.Assignment) {
+	delete(s.assignments, a)
+}
+
+// HasAssignment reports whether the user holds the role in the tenant.
+func (s *State) HasAssignment(a executor.Assignment) bool {
+	_, ok := s.assignments[a]
+	return ok
+}
+
+// Assignments returns every assignment sorted by tenant, role and user.
+func (s *State) Assignments() []executor.Assignment {
+	out := make([]executor.Assignment, 0, len(s.assignments))
+	for a := range s.assignments {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Tenant != out[j].Tenant {
+			return out[i].Tenant < out[j].Tenant
+		}
+		if out[i].Role != out[j].Role {
+			return out[i].Role < out[j].Role
+		}
+		return out[i].User < out[j].User
+	})
+	return out
+}
+
+// AddPermissions attaches permissions to a role, creating the role if needed.
+func (s *State) AddPermissions(role string, perms []string) {
+	s.EnsureRole(role)
+	for _, p := range perms {
+		s.roles[role][p] = struct{}{}
+	}
+}
+
+// RemovePermissions detaches permissions from a role; unknown roles are ignored.
+func (s *State) RemovePermissions(role string, perms []string) {
+	set, ok := s.roles[role]
+	if !ok {
+		return
+	}
+	for _, p := range perms {
+		delete(set, p)
+	}
+}
+
+// HasRole reports whether the role exists.
+func (s *State) HasRole(role string) bool {
+	_, ok := s.roles[role]
+	return ok
+}
+
+// HasPermission reports whether the role exists and holds the permission.
+func (s *State) HasPermission(role, perm string) bool {
+	set, ok := s.roles[role]
+	if !ok {
+		return false
+	}
+	_, ok = set[perm]
+	return ok
+}
+
+// Roles returns all role names in sorted order.
+func (s *State) Roles() []string {
+	out := make([]string, 0, len(s.roles))
+	for role := range s.roles {
+		out = append(out, role)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Permissions returns the sorted permissions for a role (nil if the role is unknown).
+func (s *State) Permissions(role string) []string {
+	set, ok := s.roles[role]
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(set))
+	for p := range set {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Map returns a copy of the state as role -> sorted permissions.
+func (s *State) Map() map[string][]string {
+	out := make(map[string][]string, len(s.roles))
+	for role := range s.roles {
+		out[role] = s.Permissions(role)
+	}
+	return out
+}
+
+// Clone returns a deep copy of the state.
+func (s *State) Clone() *State {
+	out := FromMap(s.Map())
+	for a := range s.assignments {
+		out.assignments[a] = struct{}{}
+	}
+	for role := range s.includes {
+		out.Include(role, s.Included(role))
+	}
+	return out
+}
+
+// Apply executes a parsed spec against the state using SuperTokens semantics, expanding
+// includes and propagation as it goes (unknown included roles are skipped; see Expand).
+// Restore actions are skipped: the set they restore depends on the migration history.
+func (s *State) Apply(spec *schema.Spec) {
+	for _, action := range spec.Actions {
+		expanded, _ := s.expandAction(action)
+		for _, a := range expanded {
+			s.applyAction(a)
+		}
+	}
+}
+
+// applyAction applies one action that was already expanded.
+func (s *State) applyAction(action schema.Action) {
+	if action.Rename != nil {
+		s.RenameRole(action.Rename.From, action.Rename.To, RenameTenants(action.Rename))
+		return
+	}
+	switch action.Ensure {
+	case "present":
+		s.EnsureRole(action.Role)
+		s.AddPermissions(action.Role, action.Add)
+		s.RemovePermissions(action.Role, action.Remove)
+		s.Include(action.Role, action.Includes)
+		if action.Set != nil {
+			s.RemovePermissions(action.Role, s.Permissions(action.Role))
+			s.AddPermissions(action.Role, action.Set)
+		}
+		for _, a := range Assignments(action, action.Assign) {
+			s.Assign(a)
+		}
+		for _, a := range Assignments(action, action.Unassign) {
+			s.Unassign(a)
+		}
+	case "absent":
+		s.DeleteRole(action.Role)
+	}
+}
+
+// RenameTenants returns the tenants whose users a rename moves, defaulting to
+// executor.DefaultTenant.
+func RenameTenants(rename *schema.Rename) []string {
+	if len(rename.Tenants) == 0 {
+		return []string{executor.DefaultTenant}
+	}
+	return rename.Tenants
+}
+
+// Assignments expands users of an action's role into assignments in the action's tenant,
+// defaulting to executor.DefaultTenant.
+func Assignments(action schema.Action, users []string) []executor.Assignment {
+	tenant := action.Tenant
+	if tenant == "" {
+		tenant = executor.DefaultTenant
+	}
+	out := make([]executor.Assignment, 0, len(users))
+	for _, u := range users {
+		out = append(out, executor.Assignment{Tenant: tenant, User: u, Role: action.Role})
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateApplyFollowsSuperTokensSemantics(t *testing.T) {
+	s := New()
+	s.Apply(&schema.Spec{Actions: []schema.Action{
+		{Role: "admin", Ensure: "present", Add: []string{"a", "b"}},
+		{Role: "viewer", Ensure: "present", Add: []string{"read"}},
+		{Role: "admin", Ensure: "present", Remove: []string{"b"}},
+		{Role: "viewer", Ensure: "absent"},
+	}})
+
+	require.Equal(t, []string{"admin"}, s.Roles())
+	require.Equal(t, []string{"a"}, s.Permissions("admin"))
+	require.Nil(t, s.Permissions("viewer"))
+
+	// re-creating a deleted role must not resurrect its permissions
+	s.EnsureRole("viewer")
+	require.Empty(t, s.Permissions("viewer"))
+}
+
+func TestStateApplyReplacesExactSets(t *testing.T) {
+	s := New()
+	s.Apply(&schema.Spec{Actions: []schema.Action{
+		{Role: "admin", Ensure: "present", Add: []string{"a", "b"}},
+		{Role: "admin", Ensure: "present", Set: []string{"b", "c"}},
+		{Role: "viewer", Ensure: "present", Add: []string{"read"}},
+		{Role: "viewer", Ensure: "present", Set: []string{}},
+	}})
+
+	require.Equal(t, []string{"b", "c"}, s.Permissions("admin"))
+	require.True(t, s.HasRole("viewer"))
+	require.Empty(t, s.Permissions("viewer"))
+}
+
+func TestStateRenameRoleMovesPermissionsAndUsers(t *testing.T) {
+	s := FromMap(map[string][]string{"support": {"ticket:read"}})
+	s.Assign(executor.Assignment{Tenant: "public", User: "u1", Role: "support"})
+	s.Assign(executor.Assignment{Tenant: "t1", User: "u2", Role: "support"})
+	s.Apply(&schema.Spec{Actions: []schema.Action{{Rename: &schema.Rename{From: "support", To: "helpdesk"}}}})
+
+	require.Equal(t, map[string][]string{"helpdesk": {"ticket:read"}}, s.Map())
+	// users outside the renamed tenants lose the role with the old one
+	require.Equal(t, []executor.Assignment{{Tenant: "public", User: "u1", Role: "helpdesk"}}, s.Assignments())
+}
+
+func TestStateRemoveFromUnknownRoleIsIgnored(t *testing.T) {
+	s := New()
+	s.RemovePermissions("ghost", []string{"x"})
+	require.False(t, s.HasRole("ghost"))
+}
+
+func TestCompareReportsAllDifferences(t *testing.T) {
+	expected := FromMap(map[string][]string{
+		"admin":  {"a", "b"},
+		"editor": {"write"},
+	})
+	live := FromMap(map[string][]string{
+		"admin": {"a", "c"},
+		"rogue": {"x"},
+	})
+
+	d := Compare(expected, live)
+	require.False(t, d.Empty())
+	require.Equal(t, []string{"rogue"}, d.ExtraRoles)
+	require.Equal(t, []string{"editor"}, d.MissingRoles)
+	require.Equal(t, map[string][]string{"admin": {"c"}, "rogue": {"x"}}, d.ExtraPermissions)
+	require.Equal(t, map[string][]string{"admin": {"b"}, "editor": {"write"}}, d.MissingPermissions)
+	require.Equal(t, []string{"admin", "editor", "rogue"}, d.Roles())
+
+	require.True(t, Compare(expected, expected.Clone()).Empty())
+}
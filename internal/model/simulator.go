@@ -0,0 +1,175 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+)
+
+var (
+	_ executor.Executor   = (*Simulator)(nil)
+	_ executor.Reader     = (*Simulator)(nil)
+	_ executor.Assigner   = (*Simulator)(nil)
+	_ executor.UserLister = (*Simulator)(nil)
+)
+
+// Warning flags an operation that would be a no-op against the backend.
+type Warning struct {
+	Role    string `json:"role"`
+	Op      string `json:"op"`
+	Message string `json:"message"`
+}
+
+// Simulator executes role/permission operations against an in-memory State.
+// It satisfies the executor interfaces so dry runs can exercise the same code path
+// as real runs, and records no-op operations as warnings.
+type Simulator struct {
+	mu       sync.Mutex
+	state    *State
+	warnings []Warning
+}
+
+// NewSimulator wraps the given state; a nil state starts empty.
+func NewSimulator(state *State) *Simulator {
+	if state == nil {
+		state = New()
+	}
+	return &Simulator{state: state}
+}
+
+// State returns a copy of the simulated state.
+func (s *Simulator) State() *State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Clone()
+}
+
+// Warnings returns all warnings recorded so far.
+func (s *Simulator) Warnings() []Warning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Warning(nil), s.warnings...)
+}
+
+// TakeWarnings returns the warnings recorded since the previous call and clears them.
+func (s *Simulator) TakeWarnings() []Warning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := s.warnings
+	s.warnings = nil
+	return out
+}
+
+func (s *Simulator) EnsureRole(_ context.Context, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.EnsureRole(role)
+	return nil
+}
+
+func (s *Simulator) DeleteRole(_ context.Context, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.state.HasRole(role) {
+		s.warn(role, "delete_role", "role does not exist")
+		return nil
+	}
+	s.state.DeleteRole(role)
+	return nil
+}
+
+func (s *Simulator) AddPermissions(_ context.Context, role string, perms []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range perms {
+		if s.state.HasPermission(role, p) {
+			s.warn(role, "add_permission", "permission "+p+" already granted")
+		}
+	}
+	s.state.AddPermissions(role, perms)
+	return nil
+}
+
+func (s *Simulator) RemovePermissions(_ context.Context, role string, perms []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.state.HasRole(role) {
+		s.warn(role, "remove_permission", "role does not exist")
+		return nil
+	}
+	for _, p := range perms {
+		if !s.state.HasPermission(role, p) {
+			s.warn(role, "remove_permission", "permission "+p+" not granted")
+		}
+	}
+	s.state.RemovePermissions(role, perms)
+	return nil
+}
+
+// AssignRole fails for unknown roles, as the SuperTokens core does.
+func (s *Simulator) AssignRole(_ context.Context, tenantID, userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.state.HasRole(role) {
+		return fmt.Errorf("role %s does not exist", role)
+	}
+	a := executor.Assignment{Tenant: tenantID, User: userID, Role: role}
+	if s.state.HasAssignment(a) {
+		s.warn(role, "assign_role", "user "+userID+" already has the role in tenant "+tenantID)
+	}
+	s.state.Assign(a)
+	return nil
+}
+
+func (s *Simulator) UnassignRole(_ context.Context, tenantID, userID, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := executor.Assignment{Tenant: tenantID, User: userID, Role: role}
+	if !s.state.HasAssignment(a) {
+		s.warn(role, "unassign_role", "user "+userID+" does not have the role in tenant "+tenantID)
+		return nil
+	}
+	s.state.Unassign(a)
+	return nil
+}
+
+func (s *Simulator) ListUsersWithRole(_ context.Context, tenantID, role string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := []string{}
+	for _, a := range s.state.Assignments() {
+		if a.Tenant == tenantID && a.Role == role {
+			users = append(users, a.User)
+		}
+	}
+	return users, nil
+}
+
+func (s *Simulator) ListRoles(_ context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Roles(), nil
+}
+
+func (s *Simulator) ListPermissions(_ context.Context, role string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	perms := s.state.Permissions(role)
+	if perms == nil {
+		perms = []string{}
+	}
+	return perms, nil
+}
+
+// Include records that role includes the given roles, which the backend never sees.
+func (s *Simulator) Include(role string, included []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Include(role, included)
+}
+
+func (s *Simulator) warn(role, op, msg string) {
+	s.warnings = append(s.warnings, Warning{Role: role, Op: op, Message: msg})
+}
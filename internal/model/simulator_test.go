@@ -0,0 +1,70 @@
+package model
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulatorFlagsNoOps(t *testing.T) {
+	sim := NewSimulator(FromMap(map[string][]string{"admin": {"read"}}))
+	ctx := context.Background()
+
+	require.NoError(t, sim.EnsureRole(ctx, "admin"))
+	require.NoError(t, sim.AddPermissions(ctx, "admin", []string{"read", "write"}))
+	require.NoError(t, sim.RemovePermissions(ctx, "admin", []string{"delete"}))
+	require.NoError(t, sim.RemovePermissions(ctx, "ghost", []string{"x"}))
+	require.NoError(t, sim.DeleteRole(ctx, "ghost"))
+
+	warnings := sim.TakeWarnings()
+	require.Len(t, warnings, 4)
+	require.Equal(t, Warning{Role: "admin", Op: "add_permission", Message: "permission read already granted"}, warnings[0])
+	require.Equal(t, "remove_permission", warnings[1].Op)
+	require.Equal(t, "ghost", warnings[2].Role)
+	require.Equal(t, "delete_role", warnings[3].Op)
+	require.Empty(t, sim.Warnings())
+
+	perms, err := sim.ListPermissions(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, []string{"read", "write"}, perms)
+	roles, err := sim.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin"}, roles)
+}
+
+func TestSimulatorDeleteCascadesPermissions(t *testing.T) {
+	sim := NewSimulator(nil)
+	ctx := context.Background()
+
+	require.NoError(t, sim.AddPermissions(ctx, "editor", []string{"write"}))
+	require.NoError(t, sim.DeleteRole(ctx, "editor"))
+	require.NoError(t, sim.EnsureRole(ctx, "editor"))
+
+	require.Empty(t, sim.State().Permissions("editor"))
+	require.Empty(t, sim.Warnings())
+}
+
+func TestSimulatorTracksAssignments(t *testing.T) {
+	sim := NewSimulator(FromMap(map[string][]string{"admin": {}}))
+	ctx := context.Background()
+
+	require.ErrorContains(t, sim.AssignRole(ctx, "public", "u1", "ghost"), "role ghost does not exist")
+	require.NoError(t, sim.AssignRole(ctx, "public", "u1", "admin"))
+	require.NoError(t, sim.AssignRole(ctx, "public", "u1", "admin"))
+	require.NoError(t, sim.UnassignRole(ctx, "public", "u2", "admin"))
+	warnings := sim.TakeWarnings()
+	require.Len(t, warnings, 2)
+	require.Equal(t, "assign_role", warnings[0].Op)
+	require.Equal(t, "unassign_role", warnings[1].Op)
+
+	users, err := sim.ListUsersWithRole(ctx, "public", "admin")
+	require.NoError(t, err)
+	require.Equal(t, []string{"u1"}, users)
+
+	state := sim.State()
+	require.Equal(t, []executor.Assignment{{Tenant: "public", User: "u1", Role: "admin"}}, state.Assignments())
+	state.DeleteRole("admin")
+	require.Empty(t, state.Assignments())
+}
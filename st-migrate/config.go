@@ -0,0 +1,283 @@
+package stmigrate
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	filestore "github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/file"
+	"github.com/supertokens/supertokens-golang/recipe/userroles"
+	"github.com/supertokens/supertokens-golang/supertokens"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileName is the project config file discovered upward from the working directory.
+const ConfigFileName = ".st-migrate.yaml"
+
+// ErrConfigNotFound signals no project config file exists in the directory or any parent.
+var ErrConfigNotFound = errors.New("config file not found")
+
+// SuperTokensSettings holds the connection to a SuperTokens core.
+type SuperTokensSettings struct {
+	ConnectionURI string `yaml:"connection_uri"`
+	APIKey        string `yaml:"api_key"`
+}
+
+// Settings are the values a project config file (or one of its environments) can set.
+// Empty fields are unset and fall back to the next level of precedence.
+type Settings struct {
+	Source          string              `yaml:"source"`
+	Database        string              `yaml:"database"`
+	StateFile       string              `yaml:"state_file"`
+	MigrationsTable string              `yaml:"migrations_table"`
+	SuperTokens     SuperTokensSettings `yaml:"supertokens"`
+	// Protection is appended to (never replaced by) an environment's own list.
+	Protection Protection `yaml:"protection"`
+	// Protected marks an environment (typically production) where destructive
+	// CLI operations must name the environment explicitly with --confirm-env.
+	Protected bool `yaml:"protected"`
+	// Vars are substituted for ${name} in migration documents; an environment's values
+	// override the top-level ones key by key.
+	Vars map[string]string `yaml:"vars"`
+	// StrictVars fails on undefined variables; like Protected, an environment can only turn it on.
+	StrictVars bool `yaml:"strict_vars"`
+}
+
+// ProjectConfig is the decoded .st-migrate.yaml: top-level settings plus named environments
+// (for example dev, staging, prod) that override them.
+type ProjectConfig struct {
+	Settings     `yaml:",inline"`
+	DefaultEnv   string              `yaml:"default_env"`
+	Environments map[string]Settings `yaml:"environments"`
+	// Path is the file the config was read from.
+	Path string `yaml:"-"`
+}
+
+// FindConfigFile looks for ConfigFileName in dir and each of its parents.
+// It returns ErrConfigNotFound when no file exists up to the filesystem root.
+func FindConfigFile(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve config search dir: %w", err)
+	}
+	for {
+		candidate := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("stat %s: %w", candidate, err)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ErrConfigNotFound
+		}
+		dir = parent
+	}
+}
+
+// LoadProjectConfig reads and decodes a project config file.
+// Relative state_file paths and file:// sources are resolved against the directory holding the file.
+func LoadProjectConfig(path string) (*ProjectConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+	var cfg ProjectConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("decode config %s: %w", path, err)
+	}
+	cfg.Path = path
+	base := filepath.Dir(path)
+	cfg.Settings.StateFile = resolvePath(base, cfg.Settings.StateFile)
+	cfg.Settings.Source = resolveSource(base, cfg.Settings.Source)
+	for name, env := range cfg.Environments {
+		env.StateFile = resolvePath(base, env.StateFile)
+		env.Source = resolveSource(base, env.Source)
+		cfg.Environments[name] = env
+	}
+	return &cfg, nil
+}
+
+// Resolve merges the named environment over the top-level settings.
+// An empty name selects DefaultEnv; if that is empty too, only top-level settings apply.
+func (c *ProjectConfig) Resolve(env string) (Settings, error) {
+	if env == "" {
+		env = c.DefaultEnv
+	}
+	out := c.Settings
+	if env == "" {
+		return out, nil
+	}
+	override, ok := c.Environments[env]
+	if !ok {
+		return Settings{}, fmt.Errorf("environment %q not defined in %s (available: %v)", env, c.Path, c.EnvironmentNames())
+	}
+	out.merge(override)
+	return out, nil
+}
+
+// EnvironmentNames lists the defined environments in sorted order.
+func (c *ProjectConfig) EnvironmentNames() []string {
+	out := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (s *Settings) merge(o Settings) {
+	if o.Source != "" {
+		s.Source = o.Source
+	}
+	if o.Database != "" {
+		s.Database = o.Database
+	}
+	if o.StateFile != "" {
+		s.StateFile = o.StateFile
+	}
+	if o.MigrationsTable != "" {
+		s.MigrationsTable = o.MigrationsTable
+	}
+	if o.SuperTokens.ConnectionURI != "" {
+		s.SuperTokens.ConnectionURI = o.SuperTokens.ConnectionURI
+	}
+	if o.SuperTokens.APIKey != "" {
+		s.SuperTokens.APIKey = o.SuperTokens.APIKey
+	}
+	s.Protection.Roles = append(append([]string{}, s.Protection.Roles...), o.Protection.Roles...)
+	s.Protection.Permissions = append(append([]string{}, s.Protection.Permissions...), o.Protection.Permissions...)
+	// protection can be added by an environment but never lifted
+	s.Protected = s.Protected || o.Protected
+	if len(o.Vars) > 0 {
+		vars := make(map[string]string, len(s.Vars)+len(o.Vars))
+		for k, v := range s.Vars {
+			vars[k] = v
+		}
+		for k, v := range o.Vars {
+			vars[k] = v
+		}
+		s.Vars = vars
+	}
+	s.StrictVars = s.StrictVars || o.StrictVars
+}
+
+func resolvePath(base, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(base, p)
+}
+
+// resolveSource makes the directory of a relative file:// source absolute; other sources are
+// returned unchanged.
+func resolveSource(base, src string) string {
+	u, err := url.Parse(src)
+	if err != nil || u.Scheme != "file" {
+		return src
+	}
+	p := filePath(u)
+	if p == "" || filepath.IsAbs(p) {
+		return src
+	}
+	out := "file://" + filepath.ToSlash(filepath.Join(base, p))
+	if u.RawQuery != "" {
+		out += "?" + u.RawQuery
+	}
+	return out
+}
+
+// DatabaseURL returns a golang-migrate database URL that keeps its migration state in table,
+// by setting the x-migrations-table parameter the bundled drivers read. An empty table, or a
+// URL that already names one, is returned unchanged.
+func DatabaseURL(databaseURL, table string) (string, error) {
+	if table == "" {
+		return databaseURL, nil
+	}
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse database url: %w", err)
+	}
+	q := u.Query()
+	if q.Has("x-migrations-table") {
+		return databaseURL, nil
+	}
+	q.Set("x-migrations-table", table)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// LoadConfig reads the project config file at path (or discovers it from the working
+// directory when path is empty), resolves env and returns a Config ready for New.
+// The state store is opened from database (a golang-migrate URL) or state_file; the
+// caller owns it through the Runner's Close. SuperTokens is not initialized; use
+// LoadProjectConfig and InitSuperTokens if the application has not done so itself.
+func LoadConfig(path, env string) (Config, error) {
+	if path == "" {
+		found, err := FindConfigFile(".")
+		if err != nil {
+			return Config{}, err
+		}
+		path = found
+	}
+	project, err := LoadProjectConfig(path)
+	if err != nil {
+		return Config{}, err
+	}
+	settings, err := project.Resolve(env)
+	if err != nil {
+		return Config{}, err
+	}
+	return settings.Config()
+}
+
+// Config converts resolved settings into a runner Config, opening the state store.
+func (s Settings) Config() (Config, error) {
+	cfg := Config{SourceURL: s.Source, MigrationsTable: s.MigrationsTable, Protection: s.Protection, Vars: s.Vars, StrictVars: s.StrictVars}
+	switch {
+	case s.Database != "":
+		st, err := OpenDatabaseStore(s.Database, s.MigrationsTable, nil)
+		if err != nil {
+			return Config{}, err
+		}
+		cfg.Store = st
+	case s.StateFile != "":
+		st, err := filestore.New(s.StateFile)
+		if err != nil {
+			slog.Error("init state file", slog.String("state_file", s.StateFile), slog.Any("err", err))
+			return Config{}, fmt.Errorf("init state file: %w", err)
+		}
+		cfg.Store = st
+	}
+	return cfg, nil
+}
+
+// InitSuperTokens initializes the SuperTokens SDK with the user roles recipe so the default
+// executor can reach the core. It is a no-op when no connection URI is configured.
+func InitSuperTokens(s SuperTokensSettings) error {
+	if s.ConnectionURI == "" {
+		return nil
+	}
+	err := supertokens.Init(supertokens.TypeInput{
+		Supertokens: &supertokens.ConnectionInfo{
+			ConnectionURI: s.ConnectionURI,
+			APIKey:        s.APIKey,
+		},
+		AppInfo: supertokens.AppInfo{
+			AppName:       "st-migrate-go",
+			APIDomain:     "http://localhost",
+			WebsiteDomain: "http://localhost",
+		},
+		RecipeList: []supertokens.Recipe{userroles.Init(nil)},
+	})
+	if err != nil {
+		slog.Error("init supertokens", slog.String("connection_uri", s.ConnectionURI), slog.Any("err", err))
+		return fmt.Errorf("init supertokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package stmigrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/file"
+	"github.com/stretchr/testify/require"
+)
+
+const testProjectConfig = `
+source: file://migrations/auth
+state_file: .st-migrate/state.json
+default_env: dev
+protection:
+  roles: [admin]
+supertokens:
+  connection_uri: http://localhost:3567
+vars:
+  tenant: acme
+  region: eu
+environments:
+  dev: {}
+  prod:
+    protected: true
+    strict_vars: true
+    vars:
+      region: us
+    protection:
+      roles: ["super*"]
+      permissions: ["billing:*"]
+    state_file: /var/lib/st-migrate/prod.json
+    supertokens:
+      connection_uri: https://core.example.com
+      api_key: secret
+`
+
+func TestFindConfigFileWalksUpward(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(testProjectConfig), 0o644))
+	nested := filepath.Join(root, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+
+	found, err := FindConfigFile(nested)
+	require.NoError(t, err)
+	require.Equal(t, path, found)
+
+	_, err = FindConfigFile(t.TempDir())
+	require.ErrorIs(t, err, ErrConfigNotFound)
+}
+
+func TestProjectConfigResolvesEnvironments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(testProjectConfig), 0o644))
+	project, err := LoadProjectConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"dev", "prod"}, project.EnvironmentNames())
+
+	dev, err := project.Resolve("")
+	require.NoError(t, err)
+	require.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "migrations", "auth")), dev.Source)
+	require.Equal(t, filepath.Join(dir, ".st-migrate", "state.json"), dev.StateFile)
+	require.Equal(t, "http://localhost:3567", dev.SuperTokens.ConnectionURI)
+	require.False(t, dev.Protected)
+	require.Equal(t, map[string]string{"tenant": "acme", "region": "eu"}, dev.Vars)
+	require.False(t, dev.StrictVars)
+
+	prod, err := project.Resolve("prod")
+	require.NoError(t, err)
+	require.Equal(t, dev.Source, prod.Source)
+	require.Equal(t, "/var/lib/st-migrate/prod.json", prod.StateFile)
+	require.True(t, prod.Protected)
+	require.Equal(t, map[string]string{"tenant": "acme", "region": "us"}, prod.Vars)
+	require.True(t, prod.StrictVars)
+	require.Equal(t, Protection{Roles: []string{"admin", "super*"}, Permissions: []string{"billing:*"}}, prod.Protection)
+	require.Equal(t, SuperTokensSettings{ConnectionURI: "https://core.example.com", APIKey: "secret"}, prod.SuperTokens)
+
+	_, err = project.Resolve("qa")
+	require.ErrorContains(t, err, `environment "qa" not defined`)
+}
+
+func TestLoadConfigBuildsRunnerConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte("source: file://migrations\nstate_file: state.json\n"), 0o644))
+
+	cfg, err := LoadConfig(path, "")
+	require.NoError(t, err)
+	require.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "migrations")), cfg.SourceURL)
+	require.IsType(t, &file.Store{}, cfg.Store)
+}
+
+func TestLoadProjectConfigResolvesRelativeFileSources(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte("source: file://backend/migrations\nenvironments:\n  abs:\n    source: file:///srv/migrations\n  remote:\n    source: github://org/repo/migrations\n"), 0o644))
+
+	project, err := LoadProjectConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, "file://"+filepath.ToSlash(filepath.Join(dir, "backend", "migrations")), project.Source)
+	require.Equal(t, "file:///srv/migrations", project.Environments["abs"].Source)
+	require.Equal(t, "github://org/repo/migrations", project.Environments["remote"].Source)
+}
+
+func TestDatabaseURLSetsMigrationsTable(t *testing.T) {
+	got, err := DatabaseURL("postgres://u@db/auth?sslmode=require", "auth_roles")
+	require.NoError(t, err)
+	require.Equal(t, "postgres://u@db/auth?sslmode=require&x-migrations-table=auth_roles", got)
+
+	got, err = DatabaseURL("postgres://u@db/auth?x-migrations-table=mine", "auth_roles")
+	require.NoError(t, err)
+	require.Equal(t, "postgres://u@db/auth?x-migrations-table=mine", got, "an explicit table in the URL wins")
+
+	got, err = DatabaseURL("sqlite3://state.db", "")
+	require.NoError(t, err)
+	require.Equal(t, "sqlite3://state.db", got)
+}
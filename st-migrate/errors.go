@@ -0,0 +1,61 @@
+package stmigrate
+
+import (
+	"github.com/BeardedWonderDev/st-migrate-go/internal/migration"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+// Errors returned by the runner, loader, schema registry and stores.
+// Match them with errors.Is; use errors.As with the typed errors below for details.
+var (
+	// ErrDirty is matched by *DirtyError.
+	ErrDirty = migration.ErrDirty
+	// ErrLocked signals the state store is locked by another run.
+	ErrLocked = store.ErrLocked
+	// ErrVersionNotFound signals a target or recorded version has no migration in the source.
+	ErrVersionNotFound = migration.ErrVersionNotFound
+	// ErrUnsupportedSchema signals a document declares a schema version with no registered parser.
+	ErrUnsupportedSchema = schema.ErrUnsupportedSchema
+	// ErrUnknownField signals a migration document sets a key its schema does not declare.
+	ErrUnknownField = schema.ErrUnknownField
+	// ErrParse is matched by *ParseError.
+	ErrParse = migration.ErrParse
+	// ErrExecutor is matched by *ExecutorError.
+	ErrExecutor = executor.ErrExecutor
+	// ErrProtected is matched by *ProtectedError.
+	ErrProtected = migration.ErrProtected
+	// ErrRoleInUse is matched by *RoleInUseError.
+	ErrRoleInUse = migration.ErrRoleInUse
+	// ErrNoSeedFS signals a seed action while no filesystem holds the seed files.
+	ErrNoSeedFS = migration.ErrNoSeedFS
+)
+
+// DirtyError carries the version the store was left dirty at.
+type DirtyError = migration.DirtyError
+
+// ParseError carries the migration file, version and direction that failed to parse.
+type ParseError = migration.ParseError
+
+// DocumentError carries every problem of a migration document, each with its line and column.
+// A ParseError wraps it with the migration identifier and direction.
+type DocumentError = schema.DocumentError
+
+// DocumentProblem is one positioned problem of a DocumentError.
+type DocumentProblem = schema.Problem
+
+// ExecutorError carries the role and operation that failed in the backend.
+type ExecutorError = executor.Error
+
+// ProtectedError carries the migration and the protected role or permission it would remove.
+type ProtectedError = migration.ProtectedError
+
+// RoleInUseError carries the migration and the role it would delete while users still hold it.
+type RoleInUseError = migration.RoleInUseError
+
+// SeedError carries the seed file that stopped part way and how many rows it applied.
+type SeedError = migration.SeedError
+
+// RenameError carries the rename that stopped part way and whether a re-run resumes it.
+type RenameError = migration.RenameError
@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Operation names reported in Error.Op.
+const (
+	OpEnsureRole        = "ensure_role"
+	OpDeleteRole        = "delete_role"
+	OpAddPermissions    = "add_permissions"
+	OpRemovePermissions = "remove_permissions"
+	OpSetPermissions    = "set_permissions"
+	OpCountUsers        = "count_users"
+	OpSetMetadata       = "set_metadata"
+	OpAssignRole        = "assign_role"
+	OpUnassignRole      = "unassign_role"
+	OpListUsers         = "list_users"
+	OpRenameRole        = "rename_role"
+)
+
+// ErrExecutor signals a role/permission operation failed in the backend.
+// Use errors.As with *Error to get the role and operation.
+var ErrExecutor = errors.New("executor operation failed")
+
+// Error reports a failed executor operation.
+type Error struct {
+	Role string
+	Op   string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Op, e.Role, e.Err)
+}
+
+// Is matches ErrExecutor.
+func (e *Error) Is(target error) bool {
+	return target == ErrExecutor
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,70 @@
+// Package executor defines how migration actions reach a role/permission backend.
+// Implement Executor (and optionally Reader) to target a backend other than SuperTokens.
+package executor
+
+import "context"
+
+// Executor applies role/permission changes against a backend (e.g., SuperTokens).
+type Executor interface {
+	EnsureRole(ctx context.Context, role string) error
+	DeleteRole(ctx context.Context, role string) error
+	AddPermissions(ctx context.Context, role string, perms []string) error
+	RemovePermissions(ctx context.Context, role string, perms []string) error
+}
+
+// Reader is implemented by executors that can read the live role/permission state.
+// It is optional; features such as drift detection require it.
+type Reader interface {
+	ListRoles(ctx context.Context) ([]string, error)
+	ListPermissions(ctx context.Context, role string) ([]string, error)
+}
+
+// UserCounter is implemented by executors that can report how many users hold a role, across
+// every tenant. It is optional; the runner refuses to delete roles that are still assigned, and
+// without it (or UserLister and TenantLister) it refuses any deletion unless forced.
+type UserCounter interface {
+	CountUsersWithRole(ctx context.Context, role string) (int, error)
+}
+
+// RoleMetadata is the descriptive information schema v2 migrations attach to a role.
+// Permissions maps a permission name to its description.
+type RoleMetadata struct {
+	Description string
+	Owner       string
+	Tags        []string
+	Permissions map[string]string
+}
+
+// MetadataWriter is implemented by executors whose backend can store role metadata.
+// It is optional; the SuperTokens user roles API has nowhere to keep it, so metadata is
+// then only recorded in the migration files and surfaced in plans and reports.
+type MetadataWriter interface {
+	SetRoleMetadata(ctx context.Context, role string, meta RoleMetadata) error
+}
+
+// Assigner is implemented by executors that can assign roles to users. It is optional;
+// migrations with assign or unassign entries fail on executors without it.
+// The tenant is always explicit; migrations default it to DefaultTenant.
+type Assigner interface {
+	AssignRole(ctx context.Context, tenantID, userID, role string) error
+	UnassignRole(ctx context.Context, tenantID, userID, role string) error
+}
+
+// Assignment is a user holding a role within a tenant.
+type Assignment struct {
+	Tenant string `json:"tenant"`
+	User   string `json:"user"`
+	Role   string `json:"role"`
+}
+
+// UserLister is implemented by executors that can list the users holding a role in a tenant.
+// It is optional; rename actions need it to move users to the new role.
+type UserLister interface {
+	ListUsersWithRole(ctx context.Context, tenantID, role string) ([]string, error)
+}
+
+// TenantLister is implemented by executors that can list the tenants roles are assigned in.
+// It is optional; with UserLister it lets the runner see exactly who holds a role.
+type TenantLister interface {
+	ListTenants(ctx context.Context) ([]string, error)
+}
@@ -0,0 +1,278 @@
+package executor
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+)
+
+var (
+	_ Executor       = (*Mock)(nil)
+	_ Reader         = (*Mock)(nil)
+	_ UserCounter    = (*Mock)(nil)
+	_ MetadataWriter = (*Mock)(nil)
+	_ Assigner       = (*Mock)(nil)
+	_ UserLister     = (*Mock)(nil)
+	_ TenantLister   = (*Mock)(nil)
+)
+
+// Mock captures applied actions for testing.
+// Live tracks the resulting role -> permissions state so the mock can also act as a Reader;
+// tests may seed it directly to simulate out-of-band changes.
+// RoleUsers maps a role to the users holding it in DefaultTenant; Assignments covers every
+// tenant, and CountUsersWithRole counts both. Metadata holds the last metadata written per
+// role. Deleting a role clears its users, assignments and metadata.
+type Mock struct {
+	mu           sync.Mutex
+	RolesEnsured []string
+	RolesDeleted []string
+	PermsAdded   map[string][]string
+	PermsRemoved map[string][]string
+	Live         map[string][]string
+	RoleUsers    map[string][]string
+	Metadata     map[string]RoleMetadata
+	Assignments  []Assignment
+	FailWith     error
+}
+
+func NewMock() *Mock {
+	return &Mock{
+		PermsAdded:   map[string][]string{},
+		PermsRemoved: map[string][]string{},
+		Live:         map[string][]string{},
+		RoleUsers:    map[string][]string{},
+		Metadata:     map[string]RoleMetadata{},
+	}
+}
+
+func (m *Mock) EnsureRole(_ context.Context, role string) error {
+	if m.FailWith != nil {
+		return m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RolesEnsured = append(m.RolesEnsured, role)
+	m.ensureLive(role)
+	return nil
+}
+
+func (m *Mock) DeleteRole(_ context.Context, role string) error {
+	if m.FailWith != nil {
+		return m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RolesDeleted = append(m.RolesDeleted, role)
+	delete(m.Live, role)
+	delete(m.RoleUsers, role)
+	delete(m.Metadata, role)
+	kept := m.Assignments[:0]
+	for _, a := range m.Assignments {
+		if a.Role != role {
+			kept = append(kept, a)
+		}
+	}
+	m.Assignments = kept
+	return nil
+}
+
+func (m *Mock) AddPermissions(_ context.Context, role string, perms []string) error {
+	if m.FailWith != nil {
+		return m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.PermsAdded[role] = append(m.PermsAdded[role], perms...)
+	m.ensureLive(role)
+	for _, p := range perms {
+		if !contains(m.Live[role], p) {
+			m.Live[role] = append(m.Live[role], p)
+		}
+	}
+	return nil
+}
+
+func (m *Mock) RemovePermissions(_ context.Context, role string, perms []string) error {
+	if m.FailWith != nil {
+		return m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.PermsRemoved[role] = append(m.PermsRemoved[role], perms...)
+	current, ok := m.Live[role]
+	if !ok {
+		return nil
+	}
+	kept := make([]string, 0, len(current))
+	for _, p := range current {
+		if !contains(perms, p) {
+			kept = append(kept, p)
+		}
+	}
+	m.Live[role] = kept
+	return nil
+}
+
+func (m *Mock) ListRoles(_ context.Context) ([]string, error) {
+	if m.FailWith != nil {
+		return nil, m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	roles := make([]string, 0, len(m.Live))
+	for role := range m.Live {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles, nil
+}
+
+func (m *Mock) ListPermissions(_ context.Context, role string) ([]string, error) {
+	if m.FailWith != nil {
+		return nil, m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	perms := append([]string(nil), m.Live[role]...)
+	sort.Strings(perms)
+	return perms, nil
+}
+
+func (m *Mock) CountUsersWithRole(ctx context.Context, role string) (int, error) {
+	tenants, err := m.ListTenants(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, tenant := range tenants {
+		users, err := m.ListUsersWithRole(ctx, tenant, role)
+		if err != nil {
+			return 0, err
+		}
+		total += len(users)
+	}
+	return total, nil
+}
+
+// ListTenants reports DefaultTenant and every tenant in Assignments.
+func (m *Mock) ListTenants(_ context.Context) ([]string, error) {
+	if m.FailWith != nil {
+		return nil, m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenants := []string{DefaultTenant}
+	for _, a := range m.Assignments {
+		if !slices.Contains(tenants, a.Tenant) {
+			tenants = append(tenants, a.Tenant)
+		}
+	}
+	sort.Strings(tenants)
+	return tenants, nil
+}
+
+// ListUsersWithRole reports the users of role in Assignments (and, for DefaultTenant, RoleUsers).
+func (m *Mock) ListUsersWithRole(_ context.Context, tenantID, role string) ([]string, error) {
+	if m.FailWith != nil {
+		return nil, m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := map[string]struct{}{}
+	if tenantID == DefaultTenant {
+		for _, u := range m.RoleUsers[role] {
+			seen[u] = struct{}{}
+		}
+	}
+	for _, a := range m.Assignments {
+		if a.Tenant == tenantID && a.Role == role {
+			seen[a.User] = struct{}{}
+		}
+	}
+	users := make([]string, 0, len(seen))
+	for u := range seen {
+		users = append(users, u)
+	}
+	sort.Strings(users)
+	return users, nil
+}
+
+func (m *Mock) SetRoleMetadata(_ context.Context, role string, meta RoleMetadata) error {
+	if m.FailWith != nil {
+		return m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Metadata == nil {
+		m.Metadata = map[string]RoleMetadata{}
+	}
+	m.Metadata[role] = meta
+	return nil
+}
+
+func (m *Mock) AssignRole(_ context.Context, tenantID, userID, role string) error {
+	if m.FailWith != nil {
+		return m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := Assignment{Tenant: tenantID, User: userID, Role: role}
+	for _, existing := range m.Assignments {
+		if existing == a {
+			return nil
+		}
+	}
+	m.Assignments = append(m.Assignments, a)
+	if tenantID == DefaultTenant {
+		if m.RoleUsers == nil {
+			m.RoleUsers = map[string][]string{}
+		}
+		m.RoleUsers[role] = append(m.RoleUsers[role], userID)
+	}
+	return nil
+}
+
+func (m *Mock) UnassignRole(_ context.Context, tenantID, userID, role string) error {
+	if m.FailWith != nil {
+		return m.FailWith
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := Assignment{Tenant: tenantID, User: userID, Role: role}
+	kept := m.Assignments[:0]
+	for _, existing := range m.Assignments {
+		if existing != a {
+			kept = append(kept, existing)
+		}
+	}
+	m.Assignments = kept
+	if current, ok := m.RoleUsers[role]; ok && tenantID == DefaultTenant {
+		users := current[:0]
+		for _, u := range current {
+			if u != userID {
+				users = append(users, u)
+			}
+		}
+		m.RoleUsers[role] = users
+	}
+	return nil
+}
+
+func (m *Mock) ensureLive(role string) {
+	if m.Live == nil {
+		m.Live = map[string][]string{}
+	}
+	if _, ok := m.Live[role]; !ok {
+		m.Live[role] = []string{}
+	}
+}
+
+func contains(list []string, val string) bool {
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMockAccumulatesActions(t *testing.T) {
+	m := NewMock()
+	ctx := context.Background()
+
+	require.NoError(t, m.EnsureRole(ctx, "role1"))
+	require.NoError(t, m.DeleteRole(ctx, "role2"))
+	require.NoError(t, m.AddPermissions(ctx, "role1", []string{"a", "b"}))
+	require.NoError(t, m.RemovePermissions(ctx, "role1", []string{"c"}))
+
+	require.ElementsMatch(t, []string{"role1"}, m.RolesEnsured)
+	require.ElementsMatch(t, []string{"role2"}, m.RolesDeleted)
+	require.ElementsMatch(t, []string{"a", "b"}, m.PermsAdded["role1"])
+	require.ElementsMatch(t, []string{"c"}, m.PermsRemoved["role1"])
+}
+
+func TestMockFailure(t *testing.T) {
+	m := NewMock()
+	m.FailWith = context.Canceled
+	err := m.EnsureRole(context.Background(), "x")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestMockTracksLiveState(t *testing.T) {
+	m := NewMock()
+	ctx := context.Background()
+
+	require.NoError(t, m.AddPermissions(ctx, "editor", []string{"write", "read"}))
+	require.NoError(t, m.EnsureRole(ctx, "viewer"))
+	require.NoError(t, m.RemovePermissions(ctx, "editor", []string{"write"}))
+	require.NoError(t, m.RemovePermissions(ctx, "ghost", []string{"x"}))
+
+	roles, err := m.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"editor", "viewer"}, roles)
+
+	perms, err := m.ListPermissions(ctx, "editor")
+	require.NoError(t, err)
+	require.Equal(t, []string{"read"}, perms)
+
+	require.NoError(t, m.DeleteRole(ctx, "editor"))
+	roles, err = m.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"viewer"}, roles)
+}
+
+func TestMockCountsUsersWithRole(t *testing.T) {
+	m := NewMock()
+	ctx := context.Background()
+	m.RoleUsers["admin"] = []string{"u1", "u2"}
+
+	n, err := m.CountUsersWithRole(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	require.NoError(t, m.DeleteRole(ctx, "admin"))
+	n, err = m.CountUsersWithRole(ctx, "admin")
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestMockTracksAssignmentsPerTenant(t *testing.T) {
+	m := NewMock()
+	ctx := context.Background()
+
+	require.NoError(t, m.AssignRole(ctx, DefaultTenant, "u1", "admin"))
+	require.NoError(t, m.AssignRole(ctx, DefaultTenant, "u1", "admin"))
+	require.NoError(t, m.AssignRole(ctx, "tenant-a", "u2", "admin"))
+	require.Equal(t, []Assignment{{Tenant: DefaultTenant, User: "u1", Role: "admin"}, {Tenant: "tenant-a", User: "u2", Role: "admin"}}, m.Assignments)
+	n, err := m.CountUsersWithRole(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, 2, n, "holders are counted in every tenant")
+
+	require.NoError(t, m.UnassignRole(ctx, DefaultTenant, "u1", "admin"))
+	require.Equal(t, []Assignment{{Tenant: "tenant-a", User: "u2", Role: "admin"}}, m.Assignments)
+	require.Empty(t, m.RoleUsers["admin"])
+
+	users, err := m.ListUsersWithRole(ctx, "tenant-a", "admin")
+	require.NoError(t, err)
+	require.Equal(t, []string{"u2"}, users)
+
+	require.NoError(t, m.DeleteRole(ctx, "admin"))
+	require.Empty(t, m.Assignments)
+}
@@ -0,0 +1,316 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/supertokens/supertokens-golang/recipe/multitenancy"
+	"github.com/supertokens/supertokens-golang/recipe/multitenancy/multitenancymodels"
+	"github.com/supertokens/supertokens-golang/recipe/userroles"
+	userrolesmodels "github.com/supertokens/supertokens-golang/recipe/userroles/userrolesmodels"
+	supertokens "github.com/supertokens/supertokens-golang/supertokens"
+)
+
+// RolesClient abstracts the SuperTokens userroles functions for testability.
+type RolesClient interface {
+	CreateNewRoleOrAddPermissions(role string, perms []string, ctx supertokens.UserContext) (userrolesmodels.CreateNewRoleOrAddPermissionsResponse, error)
+	RemovePermissionsFromRole(role string, perms []string, ctx supertokens.UserContext) (userrolesmodels.RemovePermissionsFromRoleResponse, error)
+	DeleteRole(role string, ctx supertokens.UserContext) (userrolesmodels.DeleteRoleResponse, error)
+	GetAllRoles(ctx supertokens.UserContext) (userrolesmodels.GetAllRolesResponse, error)
+	GetPermissionsForRole(role string, ctx supertokens.UserContext) (userrolesmodels.GetPermissionsForRoleResponse, error)
+	GetUsersThatHaveRole(tenantID string, role string, ctx supertokens.UserContext) (userrolesmodels.GetUsersThatHaveRoleResponse, error)
+	AddRoleToUser(tenantID string, userID string, role string, ctx supertokens.UserContext) (userrolesmodels.AddRoleToUserResponse, error)
+	RemoveUserRole(tenantID string, userID string, role string, ctx supertokens.UserContext) (userrolesmodels.RemoveUserRoleResponse, error)
+}
+
+// TenantsClient abstracts the SuperTokens multitenancy listing. A RolesClient that does not
+// implement it is treated as having only DefaultTenant.
+type TenantsClient interface {
+	ListAllTenants(ctx supertokens.UserContext) (multitenancymodels.ListAllTenantsResponse, error)
+}
+
+type superTokensClient struct{}
+
+var (
+	createRoleOrAddPermissions = userroles.CreateNewRoleOrAddPermissions
+	removePermissionsFromRole  = userroles.RemovePermissionsFromRole
+	deleteRole                 = userroles.DeleteRole
+	getAllRoles                = userroles.GetAllRoles
+	getPermissionsForRole      = userroles.GetPermissionsForRole
+	getUsersThatHaveRole       = userroles.GetUsersThatHaveRole
+	addRoleToUser              = userroles.AddRoleToUser
+	removeUserRole             = userroles.RemoveUserRole
+	listAllTenants             = multitenancy.ListAllTenants
+)
+
+func (superTokensClient) CreateNewRoleOrAddPermissions(role string, perms []string, ctx supertokens.UserContext) (userrolesmodels.CreateNewRoleOrAddPermissionsResponse, error) {
+	return createRoleOrAddPermissions(role, perms, ctx)
+}
+func (superTokensClient) RemovePermissionsFromRole(role string, perms []string, ctx supertokens.UserContext) (userrolesmodels.RemovePermissionsFromRoleResponse, error) {
+	return removePermissionsFromRole(role, perms, ctx)
+}
+func (superTokensClient) DeleteRole(role string, ctx supertokens.UserContext) (userrolesmodels.DeleteRoleResponse, error) {
+	return deleteRole(role, ctx)
+}
+func (superTokensClient) GetAllRoles(ctx supertokens.UserContext) (userrolesmodels.GetAllRolesResponse, error) {
+	return getAllRoles(ctx)
+}
+func (superTokensClient) GetPermissionsForRole(role string, ctx supertokens.UserContext) (userrolesmodels.GetPermissionsForRoleResponse, error) {
+	return getPermissionsForRole(role, ctx)
+}
+
+func (superTokensClient) GetUsersThatHaveRole(tenantID string, role string, ctx supertokens.UserContext) (userrolesmodels.GetUsersThatHaveRoleResponse, error) {
+	return getUsersThatHaveRole(tenantID, role, ctx)
+}
+
+func (superTokensClient) AddRoleToUser(tenantID string, userID string, role string, ctx supertokens.UserContext) (userrolesmodels.AddRoleToUserResponse, error) {
+	return addRoleToUser(tenantID, userID, role, ctx)
+}
+func (superTokensClient) RemoveUserRole(tenantID string, userID string, role string, ctx supertokens.UserContext) (userrolesmodels.RemoveUserRoleResponse, error) {
+	return removeUserRole(tenantID, userID, role, ctx)
+}
+
+func (superTokensClient) ListAllTenants(ctx supertokens.UserContext) (multitenancymodels.ListAllTenantsResponse, error) {
+	return listAllTenants(ctx)
+}
+
+var rolesClient RolesClient = superTokensClient{}
+
+var (
+	_ Executor     = (*SuperTokensExecutor)(nil)
+	_ Reader       = (*SuperTokensExecutor)(nil)
+	_ UserCounter  = (*SuperTokensExecutor)(nil)
+	_ Assigner     = (*SuperTokensExecutor)(nil)
+	_ UserLister   = (*SuperTokensExecutor)(nil)
+	_ TenantLister = (*SuperTokensExecutor)(nil)
+)
+
+// DefaultTenant is the SuperTokens tenant queried for role assignments.
+const DefaultTenant = "public"
+
+// SuperTokensExecutor implements Executor using the SuperTokens roles/permissions API.
+type SuperTokensExecutor struct{}
+
+func NewSuperTokensExecutor() *SuperTokensExecutor {
+	return &SuperTokensExecutor{}
+}
+
+// OverrideRolesClient allows tests to substitute a mock client.
+func OverrideRolesClient(client RolesClient) {
+	if client == nil {
+		rolesClient = superTokensClient{}
+	} else {
+		rolesClient = client
+	}
+}
+
+func (s *SuperTokensExecutor) EnsureRole(_ context.Context, role string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(role) == "" {
+		return nil
+	}
+	_, err := rolesClient.CreateNewRoleOrAddPermissions(role, []string{}, nil)
+	if err != nil {
+		slog.Error("supertokens ensure role", slog.String("role", role), slog.Any("err", err))
+	} else {
+		slog.Info("role ensured", slog.String("role", role))
+	}
+	return err
+}
+
+func (s *SuperTokensExecutor) DeleteRole(_ context.Context, role string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(role) == "" {
+		return nil
+	}
+	resp, err := rolesClient.DeleteRole(role, nil)
+	if err != nil {
+		slog.Error("supertokens delete role", slog.String("role", role), slog.Any("err", err))
+		return err
+	}
+	if resp.OK != nil && !resp.OK.DidRoleExist {
+		slog.Info("role did not exist; nothing to delete", slog.String("role", role))
+		return nil
+	}
+	slog.Info("role deleted", slog.String("role", role))
+	return nil
+}
+
+func (s *SuperTokensExecutor) AddPermissions(_ context.Context, role string, perms []string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if len(perms) == 0 {
+		return nil
+	}
+	_, err := rolesClient.CreateNewRoleOrAddPermissions(role, perms, nil)
+	if err != nil {
+		slog.Error("supertokens add permissions", slog.String("role", role), slog.Any("err", err), slog.Int("count", len(perms)))
+	} else {
+		slog.Info("permissions added", slog.String("role", role), slog.Int("count", len(perms)))
+	}
+	return err
+}
+
+func (s *SuperTokensExecutor) RemovePermissions(_ context.Context, role string, perms []string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	if len(perms) == 0 {
+		return nil
+	}
+	_, err := rolesClient.RemovePermissionsFromRole(role, perms, nil)
+	if err != nil {
+		slog.Error("supertokens remove permissions", slog.String("role", role), slog.Any("err", err), slog.Int("count", len(perms)))
+	} else {
+		slog.Info("permissions removed", slog.String("role", role), slog.Int("count", len(perms)))
+	}
+	return err
+}
+
+func (s *SuperTokensExecutor) ListRoles(_ context.Context) ([]string, error) {
+	if err := ensureInitialized(); err != nil {
+		return nil, err
+	}
+	resp, err := rolesClient.GetAllRoles(nil)
+	if err != nil {
+		slog.Error("supertokens list roles", slog.Any("err", err))
+		return nil, err
+	}
+	if resp.OK == nil {
+		return []string{}, nil
+	}
+	return resp.OK.Roles, nil
+}
+
+func (s *SuperTokensExecutor) ListPermissions(_ context.Context, role string) ([]string, error) {
+	if err := ensureInitialized(); err != nil {
+		return nil, err
+	}
+	resp, err := rolesClient.GetPermissionsForRole(role, nil)
+	if err != nil {
+		slog.Error("supertokens list permissions", slog.String("role", role), slog.Any("err", err))
+		return nil, err
+	}
+	if resp.UnknownRoleError != nil || resp.OK == nil {
+		slog.Debug("role unknown; no permissions", slog.String("role", role))
+		return []string{}, nil
+	}
+	return resp.OK.Permissions, nil
+}
+
+// CountUsersWithRole returns how many users hold role, summed over every tenant; unknown
+// roles have none.
+func (s *SuperTokensExecutor) CountUsersWithRole(ctx context.Context, role string) (int, error) {
+	tenants, err := s.ListTenants(ctx)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, tenant := range tenants {
+		users, err := s.ListUsersWithRole(ctx, tenant, role)
+		if err != nil {
+			return 0, err
+		}
+		total += len(users)
+	}
+	return total, nil
+}
+
+// ListTenants returns the IDs of every tenant in the core, or just DefaultTenant when the
+// roles client cannot list tenants.
+func (s *SuperTokensExecutor) ListTenants(_ context.Context) ([]string, error) {
+	if err := ensureInitialized(); err != nil {
+		return nil, err
+	}
+	client, ok := rolesClient.(TenantsClient)
+	if !ok {
+		return []string{DefaultTenant}, nil
+	}
+	resp, err := client.ListAllTenants(nil)
+	if err != nil {
+		slog.Error("supertokens list tenants", slog.Any("err", err))
+		return nil, err
+	}
+	if resp.OK == nil || len(resp.OK.Tenants) == 0 {
+		return []string{DefaultTenant}, nil
+	}
+	tenants := make([]string, 0, len(resp.OK.Tenants))
+	for _, t := range resp.OK.Tenants {
+		tenants = append(tenants, t.TenantId)
+	}
+	return tenants, nil
+}
+
+// ListUsersWithRole returns the users holding role in tenantID; unknown roles have none.
+func (s *SuperTokensExecutor) ListUsersWithRole(_ context.Context, tenantID, role string) ([]string, error) {
+	if err := ensureInitialized(); err != nil {
+		return nil, err
+	}
+	resp, err := rolesClient.GetUsersThatHaveRole(tenantID, role, nil)
+	if err != nil {
+		slog.Error("supertokens get users that have role", slog.String("tenant", tenantID), slog.String("role", role), slog.Any("err", err))
+		return nil, err
+	}
+	if resp.UnknownRoleError != nil || resp.OK == nil {
+		return []string{}, nil
+	}
+	return resp.OK.Users, nil
+}
+
+// AssignRole gives userID the role in tenantID; the role must already exist.
+func (s *SuperTokensExecutor) AssignRole(_ context.Context, tenantID, userID, role string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	resp, err := rolesClient.AddRoleToUser(tenantID, userID, role, nil)
+	if err != nil {
+		slog.Error("supertokens add role to user", slog.String("tenant", tenantID), slog.String("user", userID), slog.String("role", role), slog.Any("err", err))
+		return err
+	}
+	if resp.UnknownRoleError != nil {
+		return fmt.Errorf("role %s does not exist", role)
+	}
+	if resp.OK != nil && resp.OK.DidUserAlreadyHaveRole {
+		slog.Info("user already has role", slog.String("tenant", tenantID), slog.String("user", userID), slog.String("role", role))
+		return nil
+	}
+	slog.Info("role assigned", slog.String("tenant", tenantID), slog.String("user", userID), slog.String("role", role))
+	return nil
+}
+
+// UnassignRole removes the role from userID in tenantID; unknown roles are a no-op.
+func (s *SuperTokensExecutor) UnassignRole(_ context.Context, tenantID, userID, role string) error {
+	if err := ensureInitialized(); err != nil {
+		return err
+	}
+	resp, err := rolesClient.RemoveUserRole(tenantID, userID, role, nil)
+	if err != nil {
+		slog.Error("supertokens remove user role", slog.String("tenant", tenantID), slog.String("user", userID), slog.String("role", role), slog.Any("err", err))
+		return err
+	}
+	if resp.UnknownRoleError != nil || (resp.OK != nil && !resp.OK.DidUserHaveRole) {
+		slog.Info("user did not have role; nothing to unassign", slog.String("tenant", tenantID), slog.String("user", userID), slog.String("role", role))
+		return nil
+	}
+	slog.Info("role unassigned", slog.String("tenant", tenantID), slog.String("user", userID), slog.String("role", role))
+	return nil
+}
+
+// ensureInitialized checks that supertokens.Init has been called; if not, returns a helpful error.
+func ensureInitialized() error {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("supertokens not initialized; call supertokens.Init before running st-migrate-go")
+		}
+	}()
+	// GetAllCORSHeaders reads the global config and will panic if uninitialized.
+	_ = supertokens.GetAllCORSHeaders()
+	return nil
+}
@@ -81,3 +81,29 @@ func TestSuperTokensClientPropagatesError(t *testing.T) {
 	_, err := client.CreateNewRoleOrAddPermissions("role", nil, nil)
 	require.ErrorIs(t, err, want)
 }
+
+func TestSuperTokensClientDelegatesReads(t *testing.T) {
+	prevAll := getAllRoles
+	prevPerms := getPermissionsForRole
+	t.Cleanup(func() {
+		getAllRoles = prevAll
+		getPermissionsForRole = prevPerms
+	})
+
+	getAllRoles = func(_ ...supertokens.UserContext) (userrolesmodels.GetAllRolesResponse, error) {
+		return userrolesmodels.GetAllRolesResponse{OK: &struct{ Roles []string }{Roles: []string{"admin"}}}, nil
+	}
+	var permsRole string
+	getPermissionsForRole = func(role string, _ ...supertokens.UserContext) (userrolesmodels.GetPermissionsForRoleResponse, error) {
+		permsRole = role
+		return userrolesmodels.GetPermissionsForRoleResponse{}, nil
+	}
+
+	client := superTokensClient{}
+	resp, err := client.GetAllRoles(nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin"}, resp.OK.Roles)
+	_, err = client.GetPermissionsForRole("admin", nil)
+	require.NoError(t, err)
+	require.Equal(t, "admin", permsRole)
+}
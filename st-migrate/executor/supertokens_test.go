@@ -0,0 +1,225 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/supertokens/supertokens-golang/recipe/multitenancy/multitenancymodels"
+	userrolesmodels "github.com/supertokens/supertokens-golang/recipe/userroles/userrolesmodels"
+	supertokens "github.com/supertokens/supertokens-golang/supertokens"
+)
+
+type mockRolesClient struct {
+	calls []string
+	fail  error
+	resp  userrolesmodels.DeleteRoleResponse
+	roles map[string][]string
+	users map[string][]string
+	// had reports whether the user already had (or still had) the role in assign/unassign responses.
+	had bool
+}
+
+func (m *mockRolesClient) CreateNewRoleOrAddPermissions(role string, perms []string, ctx supertokens.UserContext) (userrolesmodels.CreateNewRoleOrAddPermissionsResponse, error) {
+	m.calls = append(m.calls, "add:"+role)
+	return userrolesmodels.CreateNewRoleOrAddPermissionsResponse{}, m.fail
+}
+func (m *mockRolesClient) RemovePermissionsFromRole(role string, perms []string, ctx supertokens.UserContext) (userrolesmodels.RemovePermissionsFromRoleResponse, error) {
+	m.calls = append(m.calls, "remove:"+role)
+	return userrolesmodels.RemovePermissionsFromRoleResponse{}, m.fail
+}
+func (m *mockRolesClient) DeleteRole(role string, ctx supertokens.UserContext) (userrolesmodels.DeleteRoleResponse, error) {
+	m.calls = append(m.calls, "delete:"+role)
+	return m.resp, m.fail
+}
+func (m *mockRolesClient) GetAllRoles(ctx supertokens.UserContext) (userrolesmodels.GetAllRolesResponse, error) {
+	m.calls = append(m.calls, "list")
+	resp := userrolesmodels.GetAllRolesResponse{OK: &struct{ Roles []string }{}}
+	for role := range m.roles {
+		resp.OK.Roles = append(resp.OK.Roles, role)
+	}
+	return resp, m.fail
+}
+func (m *mockRolesClient) GetPermissionsForRole(role string, ctx supertokens.UserContext) (userrolesmodels.GetPermissionsForRoleResponse, error) {
+	m.calls = append(m.calls, "perms:"+role)
+	perms, ok := m.roles[role]
+	if !ok {
+		return userrolesmodels.GetPermissionsForRoleResponse{UnknownRoleError: &userrolesmodels.UnknownRoleError{}}, m.fail
+	}
+	return userrolesmodels.GetPermissionsForRoleResponse{OK: &struct{ Permissions []string }{Permissions: perms}}, m.fail
+}
+
+func (m *mockRolesClient) GetUsersThatHaveRole(tenantID string, role string, ctx supertokens.UserContext) (userrolesmodels.GetUsersThatHaveRoleResponse, error) {
+	m.calls = append(m.calls, "users:"+tenantID+":"+role)
+	users, ok := m.users[role]
+	if !ok {
+		return userrolesmodels.GetUsersThatHaveRoleResponse{UnknownRoleError: &userrolesmodels.UnknownRoleError{}}, m.fail
+	}
+	return userrolesmodels.GetUsersThatHaveRoleResponse{OK: &struct{ Users []string }{Users: users}}, m.fail
+}
+
+func (m *mockRolesClient) AddRoleToUser(tenantID string, userID string, role string, ctx supertokens.UserContext) (userrolesmodels.AddRoleToUserResponse, error) {
+	m.calls = append(m.calls, "assign:"+tenantID+":"+userID+":"+role)
+	if _, ok := m.roles[role]; !ok {
+		return userrolesmodels.AddRoleToUserResponse{UnknownRoleError: &userrolesmodels.UnknownRoleError{}}, m.fail
+	}
+	return userrolesmodels.AddRoleToUserResponse{OK: &struct{ DidUserAlreadyHaveRole bool }{DidUserAlreadyHaveRole: m.had}}, m.fail
+}
+func (m *mockRolesClient) RemoveUserRole(tenantID string, userID string, role string, ctx supertokens.UserContext) (userrolesmodels.RemoveUserRoleResponse, error) {
+	m.calls = append(m.calls, "unassign:"+tenantID+":"+userID+":"+role)
+	if _, ok := m.roles[role]; !ok {
+		return userrolesmodels.RemoveUserRoleResponse{UnknownRoleError: &userrolesmodels.UnknownRoleError{}}, m.fail
+	}
+	return userrolesmodels.RemoveUserRoleResponse{OK: &struct{ DidUserHaveRole bool }{DidUserHaveRole: m.had}}, m.fail
+}
+
+func TestSuperTokensExecutorAssignsRoles(t *testing.T) {
+	mock := &mockRolesClient{roles: map[string][]string{"admin": nil}}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+	exec := NewSuperTokensExecutor()
+	ctx := context.Background()
+
+	require.NoError(t, exec.AssignRole(ctx, "public", "u1", "admin"))
+	require.NoError(t, exec.UnassignRole(ctx, "tenant-a", "u1", "admin"))
+	require.ErrorContains(t, exec.AssignRole(ctx, "public", "u1", "ghost"), "role ghost does not exist")
+	require.NoError(t, exec.UnassignRole(ctx, "public", "u1", "ghost"))
+	require.Equal(t, []string{"assign:public:u1:admin", "unassign:tenant-a:u1:admin", "assign:public:u1:ghost", "unassign:public:u1:ghost"}, mock.calls)
+
+	mock.fail = errors.New("boom")
+	require.Error(t, exec.AssignRole(ctx, "public", "u1", "admin"))
+	require.Error(t, exec.UnassignRole(ctx, "public", "u1", "admin"))
+}
+
+func TestSuperTokensExecutorCountsUsersWithRole(t *testing.T) {
+	mock := &mockRolesClient{users: map[string][]string{"admin": {"u1", "u2"}}}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+	exec := NewSuperTokensExecutor()
+	ctx := context.Background()
+
+	n, err := exec.CountUsersWithRole(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	n, err = exec.CountUsersWithRole(ctx, "ghost")
+	require.NoError(t, err)
+	require.Zero(t, n)
+	require.Equal(t, []string{"users:public:admin", "users:public:ghost"}, mock.calls)
+
+	mock.fail = errors.New("boom")
+	_, err = exec.CountUsersWithRole(ctx, "admin")
+	require.Error(t, err)
+}
+
+func TestSuperTokensExecutorListsUsersWithRole(t *testing.T) {
+	mock := &mockRolesClient{users: map[string][]string{"admin": {"u1", "u2"}}}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+	exec := NewSuperTokensExecutor()
+	ctx := context.Background()
+
+	users, err := exec.ListUsersWithRole(ctx, "tenant-a", "admin")
+	require.NoError(t, err)
+	require.Equal(t, []string{"u1", "u2"}, users)
+	users, err = exec.ListUsersWithRole(ctx, "public", "ghost")
+	require.NoError(t, err)
+	require.Empty(t, users)
+	require.Equal(t, []string{"users:tenant-a:admin", "users:public:ghost"}, mock.calls)
+}
+
+func TestSuperTokensExecutorUsesClient(t *testing.T) {
+	mock := &mockRolesClient{resp: userrolesmodels.DeleteRoleResponse{OK: &struct{ DidRoleExist bool }{DidRoleExist: true}}}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+
+	exec := NewSuperTokensExecutor()
+	ctx := context.Background()
+
+	require.NoError(t, exec.EnsureRole(ctx, "role"))
+	require.NoError(t, exec.AddPermissions(ctx, "role", []string{"p"}))
+	require.NoError(t, exec.RemovePermissions(ctx, "role", []string{"p"}))
+	require.NoError(t, exec.DeleteRole(ctx, "role"))
+
+	require.Len(t, mock.calls, 4)
+}
+
+func TestSuperTokensExecutorHandlesEmptyInputs(t *testing.T) {
+	mock := &mockRolesClient{}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+	exec := NewSuperTokensExecutor()
+	ctx := context.Background()
+
+	require.NoError(t, exec.EnsureRole(ctx, ""))
+	require.NoError(t, exec.AddPermissions(ctx, "role", nil))
+	require.NoError(t, exec.RemovePermissions(ctx, "role", nil))
+}
+
+func TestSuperTokensExecutorPropagatesErrors(t *testing.T) {
+	mock := &mockRolesClient{fail: errors.New("boom")}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+	exec := NewSuperTokensExecutor()
+	err := exec.EnsureRole(context.Background(), "r")
+	require.Error(t, err)
+}
+
+func TestSuperTokensExecutorReadsLiveState(t *testing.T) {
+	mock := &mockRolesClient{roles: map[string][]string{"admin": {"a", "b"}}}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+	exec := NewSuperTokensExecutor()
+	ctx := context.Background()
+
+	roles, err := exec.ListRoles(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"admin"}, roles)
+
+	perms, err := exec.ListPermissions(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, perms)
+
+	perms, err = exec.ListPermissions(ctx, "ghost")
+	require.NoError(t, err)
+	require.Empty(t, perms)
+}
+
+// tenantRolesClient adds tenant listing to mockRolesClient; every tenant reports the same users.
+type tenantRolesClient struct {
+	*mockRolesClient
+	tenants []string
+}
+
+func (m *tenantRolesClient) ListAllTenants(ctx supertokens.UserContext) (multitenancymodels.ListAllTenantsResponse, error) {
+	m.calls = append(m.calls, "tenants")
+	resp := multitenancymodels.ListAllTenantsResponse{OK: &struct {
+		Tenants []multitenancymodels.Tenant `json:"tenants"`
+	}{}}
+	for _, id := range m.tenants {
+		resp.OK.Tenants = append(resp.OK.Tenants, multitenancymodels.Tenant{TenantId: id})
+	}
+	return resp, m.fail
+}
+
+func TestSuperTokensExecutorCountsUsersInEveryTenant(t *testing.T) {
+	mock := &tenantRolesClient{mockRolesClient: &mockRolesClient{users: map[string][]string{"admin": {"u1", "u2"}}}, tenants: []string{"public", "tenant-a"}}
+	OverrideRolesClient(mock)
+	defer OverrideRolesClient(nil)
+	exec := NewSuperTokensExecutor()
+	ctx := context.Background()
+
+	tenants, err := exec.ListTenants(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"public", "tenant-a"}, tenants)
+
+	mock.calls = nil
+	n, err := exec.CountUsersWithRole(ctx, "admin")
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, []string{"tenants", "users:public:admin", "users:tenant-a:admin"}, mock.calls)
+
+	mock.fail = errors.New("boom")
+	_, err = exec.ListTenants(ctx)
+	require.Error(t, err)
+}
@@ -2,25 +2,47 @@ package stmigrate
 
 import (
 	"database/sql"
+	"io/fs"
 	"log/slog"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/schema"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/state"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+	"github.com/golang-migrate/migrate/v4/source"
 )
 
 // Config drives construction of a Runner instance.
 type Config struct {
 	SourceURL string
-	Store     state.Store
-	Executor  executor.Executor
-	Logger    *slog.Logger
-	DryRun    bool
-	Registry  *schema.Registry
+	// Source, when set, is read instead of opening SourceURL, for example NewFSSource over an
+	// embed.FS. The runner closes it.
+	Source   source.Driver
+	Store    store.Store
+	Executor executor.Executor
+	Logger   *slog.Logger
+	DryRun   bool
+	Registry *schema.Registry
 	// Optional DB parameters to let the SDK build a dedicated migrate driver.
 	DB              *sql.DB
 	DBDriver        string // postgres | mysql | sqlite3
 	MigrationsTable string
+	// Protection lists role/permission glob patterns migrations must never delete or remove.
+	Protection Protection
+	// AllowProtected acknowledges migration versions whose allow_protected override may
+	// bypass Protection.
+	AllowProtected []uint
+	// Force deletes roles still held by users with a warning instead of refusing the migration.
+	Force bool
+	// SeedFS holds the CSV/JSON files referenced by seed actions and the shared _defs.yaml.
+	// When nil they are read through the source if it implements FileSource (file:// URLs
+	// and NewFSSource do).
+	SeedFS fs.FS
+	// Vars are substituted for ${name} references in migration documents before parsing.
+	// ${each.*} is reserved for for_each.
+	Vars map[string]string
+	// StrictVars fails loading when a document references an undefined variable instead of
+	// leaving the reference in place.
+	StrictVars bool
 	// SkipCloseDB prevents the runner from closing the store/driver when using a shared DB (primarily for sqlite3).
 	SkipCloseDB bool
 }
@@ -0,0 +1,233 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// eachRE matches a ${each.name} reference in a for_each template.
+	eachRE = regexp.MustCompile(`\$\{\s*each\.([A-Za-z0-9_-]*)\s*\}`)
+	// eachNameRE matches the names a for_each map may bind.
+	eachNameRE = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// decodeDocument decodes data into a node tree with every for_each action expanded. A list
+// (for_each: [a, b]) binds ${each.key} and ${each.value} to each item; a map of lists
+// (for_each: {resource: [a, b], level: [x, y]}) binds ${each.resource} and ${each.level} to
+// every combination, the first key varying slowest. Actions whose for_each fails are dropped
+// and reported; the root is nil only when data is not valid YAML.
+func decodeDocument(data []byte) (*yaml.Node, problems) {
+	var root yaml.Node
+	var probs problems
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		probs.addYAML(err)
+		return nil, probs
+	}
+	return &root, expandActions(&root)
+}
+
+// expandActions expands the for_each actions of a decoded document in place; see decodeDocument.
+func expandActions(root *yaml.Node) problems {
+	var probs problems
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+	actions := mappingValue(root.Content[0], "actions")
+	if actions == nil || actions.Kind != yaml.SequenceNode {
+		return nil
+	}
+	expanded := make([]*yaml.Node, 0, len(actions.Content))
+	for i, action := range actions.Content {
+		out, err := expandForEach(action)
+		if err != nil {
+			probs.add(action, fmt.Errorf("action %d: %w", i, err))
+			continue
+		}
+		expanded = append(expanded, out...)
+	}
+	actions.Content = expanded
+	return probs
+}
+
+// expandForEach returns the actions a template action expands to, or the action itself when
+// it has no for_each.
+func expandForEach(action *yaml.Node) ([]*yaml.Node, error) {
+	if action.Kind != yaml.MappingNode {
+		return []*yaml.Node{action}, nil
+	}
+	spec := mappingValue(action, "for_each")
+	if spec == nil {
+		return []*yaml.Node{action}, nil
+	}
+	template := &yaml.Node{Kind: yaml.MappingNode, Tag: action.Tag, Line: action.Line, Column: action.Column}
+	for i := 0; i+1 < len(action.Content); i += 2 {
+		if action.Content[i].Value != "for_each" {
+			template.Content = append(template.Content, action.Content[i], action.Content[i+1])
+		}
+	}
+	bindings, err := forEachBindings(spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEachRefs(template, bindings[0]); err != nil {
+		return nil, err
+	}
+	out := make([]*yaml.Node, 0, len(bindings))
+	seen := map[string]string{}
+	for _, b := range bindings {
+		node := substituteEach(template, b)
+		id, err := actionIdentity(node)
+		if err != nil {
+			return nil, err
+		}
+		if prev, dup := seen[id]; dup {
+			return nil, fmt.Errorf("for_each produces %s twice (%s and %s)", id, prev, describeBinding(b))
+		}
+		seen[id] = describeBinding(b)
+		out = append(out, node)
+	}
+	return out, nil
+}
+
+// binding maps each.* names to their values for one expansion, keeping the key order.
+type binding struct {
+	names  []string
+	values map[string]string
+}
+
+func forEachBindings(spec *yaml.Node) ([]binding, error) {
+	switch spec.Kind {
+	case yaml.SequenceNode:
+		items, err := forEachItems("for_each", spec)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]binding, 0, len(items))
+		for _, item := range items {
+			out = append(out, binding{names: []string{"key"}, values: map[string]string{"key": item, "value": item}})
+		}
+		return out, nil
+	case yaml.MappingNode:
+		out := []binding{{values: map[string]string{}}}
+		for i := 0; i+1 < len(spec.Content); i += 2 {
+			name := spec.Content[i].Value
+			if !eachNameRE.MatchString(name) || name == "key" || name == "value" {
+				return nil, atNode(spec.Content[i], fmt.Errorf("for_each key %q must use letters, digits, _ or - and cannot be key or value", name))
+			}
+			items, err := forEachItems("for_each."+name, spec.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			next := make([]binding, 0, len(out)*len(items))
+			for _, b := range out {
+				for _, item := range items {
+					values := make(map[string]string, len(b.values)+1)
+					for k, v := range b.values {
+						values[k] = v
+					}
+					values[name] = item
+					next = append(next, binding{names: append(append([]string{}, b.names...), name), values: values})
+				}
+			}
+			out = next
+		}
+		if len(out[0].names) == 0 {
+			return nil, atNode(spec, fmt.Errorf("for_each needs at least one list"))
+		}
+		return out, nil
+	}
+	return nil, atNode(spec, fmt.Errorf("for_each must be a list or a map of lists"))
+}
+
+// forEachItems returns the scalar items of a for_each list, rejecting empty lists and repeats.
+func forEachItems(field string, list *yaml.Node) ([]string, error) {
+	if list.Kind != yaml.SequenceNode {
+		return nil, atNode(list, fmt.Errorf("%s must be a list", field))
+	}
+	if len(list.Content) == 0 {
+		return nil, atNode(list, fmt.Errorf("%s is empty", field))
+	}
+	items := make([]string, 0, len(list.Content))
+	for _, n := range list.Content {
+		if n.Kind != yaml.ScalarNode {
+			return nil, atNode(n, fmt.Errorf("%s items must be scalars", field))
+		}
+		item := strings.TrimSpace(n.Value)
+		for _, prev := range items {
+			if prev == item {
+				return nil, atNode(n, fmt.Errorf("%s lists %q twice", field, item))
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// checkEachRefs rejects ${each.*} references to names the for_each does not bind.
+func checkEachRefs(n *yaml.Node, b binding) error {
+	if n.Kind == yaml.ScalarNode {
+		for _, m := range eachRE.FindAllStringSubmatch(n.Value, -1) {
+			if _, ok := b.values[m[1]]; !ok {
+				return atNode(n, fmt.Errorf("${each.%s} is not bound by for_each", m[1]))
+			}
+		}
+	}
+	for _, c := range n.Content {
+		if err := checkEachRefs(c, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// substituteEach returns a deep copy of n with ${each.*} references in scalars replaced.
+func substituteEach(n *yaml.Node, b binding) *yaml.Node {
+	out := *n
+	if n.Kind == yaml.ScalarNode {
+		out.Value = eachRE.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			return b.values[eachRE.FindStringSubmatch(ref)[1]]
+		})
+	}
+	if n.Content != nil {
+		out.Content = make([]*yaml.Node, len(n.Content))
+		for i, c := range n.Content {
+			out.Content[i] = substituteEach(c, b)
+		}
+	}
+	return &out
+}
+
+// actionIdentity names an expanded action for duplicate detection: its role, or for actions
+// without one (seed, rename) their full content.
+func actionIdentity(action *yaml.Node) (string, error) {
+	if role := mappingValue(action, "role"); role != nil && strings.TrimSpace(role.Value) != "" {
+		return "role " + strings.TrimSpace(role.Value), nil
+	}
+	data, err := yaml.Marshal(action)
+	if err != nil {
+		return "", err
+	}
+	return "action " + strings.TrimSpace(string(data)), nil
+}
+
+func describeBinding(b binding) string {
+	parts := make([]string, 0, len(b.names))
+	for _, name := range b.names {
+		parts = append(parts, "each."+name+"="+b.values[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsersExpandForEachLists(t *testing.T) {
+	doc := "version: 1\nactions:\n  - role: admin\n  - for_each: [projects, invoices]\n    role: ${each.key}:viewer\n    add: [\"${each.value}:read\"]\n"
+	spec, err := V1Parser{}.Parse([]byte(doc))
+	require.NoError(t, err)
+	require.Len(t, spec.Actions, 3)
+	require.Equal(t, "projects:viewer", spec.Actions[1].Role)
+	require.Equal(t, []string{"projects:read"}, spec.Actions[1].Add)
+	require.Equal(t, "invoices:viewer", spec.Actions[2].Role)
+	require.Equal(t, "present", spec.Actions[2].Ensure)
+}
+
+func TestParsersExpandForEachMatrix(t *testing.T) {
+	doc := "version: 2\nactions:\n  - for_each:\n      resource: [projects, invoices]\n      level: [viewer, editor]\n    role: ${each.resource}:${each.level}\n    description: ${each.level} of ${each.resource}\n    add:\n      - name: ${each.resource}:read\n        description: Read ${each.resource}\n"
+	spec, err := V2Parser{}.Parse([]byte(doc))
+	require.NoError(t, err)
+	roles := make([]string, 0, len(spec.Actions))
+	for _, a := range spec.Actions {
+		roles = append(roles, a.Role)
+	}
+	require.Equal(t, []string{"projects:viewer", "projects:editor", "invoices:viewer", "invoices:editor"}, roles)
+	require.Equal(t, "editor of invoices", spec.Actions[3].Description)
+	require.Equal(t, []Permission{{Name: "invoices:read", Description: "Read invoices"}}, spec.Actions[3].Permissions)
+}
+
+func TestParsersRejectInvalidForEach(t *testing.T) {
+	cases := map[string]string{
+		"version: 1\nactions:\n  - for_each: [a, b]\n    role: viewer\n":                         "line 3, column 5: action 0: for_each produces role viewer twice (each.key=a and each.key=b)",
+		"version: 1\nactions:\n  - for_each: {x: [a], y: [b, c]}\n    role: ${each.x}\n":         "for_each produces role a twice",
+		"version: 1\nactions:\n  - for_each: [a, a]\n    role: ${each.key}\n":                    `for_each lists "a" twice`,
+		"version: 1\nactions:\n  - for_each: []\n    role: ${each.key}\n":                        "for_each is empty",
+		"version: 1\nactions:\n  - for_each: {x: [a]}\n    role: ${each.y}\n":                    "line 4, column 11: action 0: ${each.y} is not bound by for_each",
+		"version: 2\nactions:\n  - for_each: a\n    role: ${each.key}\n":                         "for_each must be a list or a map of lists",
+		"version: 1\nactions:\n  - for_each: {key: [a]}\n    role: ${each.key}\n":                "cannot be key or value",
+		"version: 1\nactions:\n  - for_each: [a, b]\n    role: ${each.key}\n    ensure: maybe\n": `invalid ensure "maybe"`,
+	}
+	reg := DefaultRegistry()
+	for doc, want := range cases {
+		_, err := reg.Parse([]byte(doc))
+		require.ErrorContains(t, err, want, doc)
+	}
+}
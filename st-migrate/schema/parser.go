@@ -0,0 +1,141 @@
+// Package schema decodes migration documents into Specs, dispatching on the document's
+// schema version. Custom parsers can be registered on a Registry.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedSchema signals a document declares a schema version with no registered parser.
+var ErrUnsupportedSchema = errors.New("unsupported schema version")
+
+// Parser defines how to decode a schema version into an in-memory Spec.
+type Parser interface {
+	Parse(data []byte) (*Spec, error)
+}
+
+// NodeParser is implemented by parsers that can decode an already parsed document, which may
+// be rewritten in place. ParseWith uses it so problems keep their positions in the original text.
+type NodeParser interface {
+	ParseNode(root *yaml.Node) (*Spec, error)
+}
+
+var (
+	_ NodeParser = V1Parser{}
+	_ NodeParser = V2Parser{}
+)
+
+// Registry maps schema versions to parsers.
+type Registry struct {
+	parsers map[int]Parser
+}
+
+// NewRegistry builds an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{parsers: map[int]Parser{}}
+}
+
+// Register attaches a parser for a schema version.
+func (r *Registry) Register(version int, parser Parser) {
+	r.parsers[version] = parser
+}
+
+// Parse uses the registered parser for the schema version in the YAML payload.
+// If the version is missing or zero, schema version 1 is assumed. Failures are reported as a
+// *DocumentError positioned at the offending line and column.
+func (r *Registry) Parse(data []byte) (*Spec, error) {
+	schemaVersion, node, err := readSchemaVersion(data)
+	if err != nil {
+		slog.Error("parse schema metadata", slog.Any("err", err))
+		return nil, err
+	}
+	parser, ok := r.parsers[schemaVersion]
+	if !ok {
+		slog.Warn("unsupported schema version", slog.Int("version", schemaVersion))
+		var probs problems
+		probs.add(node, fmt.Errorf("%w %d", ErrUnsupportedSchema, schemaVersion))
+		return nil, probs.err(0)
+	}
+	return parser.Parse(data)
+}
+
+// ParseWith is Parse with transform applied to the decoded node tree first, for rewrites such
+// as expanding references that must not disturb the positions problems are reported at.
+// Parsers that do not implement NodeParser receive the transformed tree re-encoded.
+func (r *Registry) ParseWith(data []byte, transform func(root *yaml.Node) error) (*Spec, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		var probs problems
+		probs.addYAML(err)
+		slog.Error("parse schema metadata", slog.Any("err", err))
+		return nil, probs.err(0)
+	}
+	if err := transform(&root); err != nil {
+		return nil, err
+	}
+	schemaVersion, node, err := schemaVersionOf(&root)
+	if err != nil {
+		slog.Error("parse schema metadata", slog.Any("err", err))
+		return nil, err
+	}
+	parser, ok := r.parsers[schemaVersion]
+	if !ok {
+		slog.Warn("unsupported schema version", slog.Int("version", schemaVersion))
+		var probs problems
+		probs.add(node, fmt.Errorf("%w %d", ErrUnsupportedSchema, schemaVersion))
+		return nil, probs.err(0)
+	}
+	if np, ok := parser.(NodeParser); ok {
+		return np.ParseNode(&root)
+	}
+	out, err := yaml.Marshal(&root)
+	if err != nil {
+		return nil, fmt.Errorf("encode document: %w", err)
+	}
+	return parser.Parse(out)
+}
+
+// readSchemaVersion returns the document's schema version (1 when unset) and the node
+// declaring it, nil when absent.
+func readSchemaVersion(data []byte) (int, *yaml.Node, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		var probs problems
+		probs.addYAML(err)
+		return 0, nil, probs.err(0)
+	}
+	return schemaVersionOf(&root)
+}
+
+// schemaVersionOf reads the schema version of a decoded document; see readSchemaVersion.
+func schemaVersionOf(root *yaml.Node) (int, *yaml.Node, error) {
+	var probs problems
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return 1, nil, nil
+	}
+	node := mappingValue(root.Content[0], "version")
+	if node == nil {
+		return 1, nil, nil
+	}
+	var version int
+	if err := node.Decode(&version); err != nil {
+		probs.add(node, fmt.Errorf("version must be an integer, got %q", node.Value))
+		return 0, nil, probs.err(0)
+	}
+	if version == 0 {
+		version = 1
+	}
+	return version, node, nil
+}
+
+// DefaultRegistry returns a registry populated with built-in schema parsers.
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	r.Register(1, V1Parser{})
+	r.Register(2, V2Parser{})
+	return r
+}
@@ -0,0 +1,270 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownField signals a document sets a key its schema does not declare.
+var ErrUnknownField = errors.New("unknown field")
+
+// Problem is one issue found in a migration document. Line and Column are 1-based; Column is
+// zero when only the line is known and both are zero when the position is unknown.
+type Problem struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (p Problem) String() string {
+	switch {
+	case p.Line == 0:
+		return p.Err.Error()
+	case p.Column == 0:
+		return fmt.Sprintf("line %d: %v", p.Line, p.Err)
+	}
+	return fmt.Sprintf("line %d, column %d: %v", p.Line, p.Column, p.Err)
+}
+
+// DocumentError lists every problem found in a document, in document order. Schema is the
+// schema version being decoded, zero when the version itself could not be read. Use
+// errors.Is against ErrUnknownField or ErrUnsupportedSchema to test for a kind of problem.
+type DocumentError struct {
+	Schema   int
+	Problems []Problem
+}
+
+func (e *DocumentError) Error() string {
+	prefix := "schema"
+	if e.Schema > 0 {
+		prefix = fmt.Sprintf("schema v%d", e.Schema)
+	}
+	parts := make([]string, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		parts = append(parts, p.String())
+	}
+	if len(parts) == 1 {
+		return prefix + ": " + parts[0]
+	}
+	return fmt.Sprintf("%s: %d problems: %s", prefix, len(parts), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the error of every problem.
+func (e *DocumentError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Problems))
+	for _, p := range e.Problems {
+		errs = append(errs, p.Err)
+	}
+	return errs
+}
+
+// nodeError ties an error to the node it is about, so it is reported at that node's position
+// rather than the enclosing action's.
+type nodeError struct {
+	node *yaml.Node
+	err  error
+}
+
+func (e *nodeError) Error() string { return e.err.Error() }
+
+func (e *nodeError) Unwrap() error { return e.err }
+
+func atNode(n *yaml.Node, err error) error {
+	return &nodeError{node: n, err: err}
+}
+
+// problems collects the problems of one document.
+type problems []Problem
+
+// add records err at n, or at the node a wrapped nodeError names. A nil n leaves the position unknown.
+func (p *problems) add(n *yaml.Node, err error) {
+	var ne *nodeError
+	if errors.As(err, &ne) {
+		n = ne.node
+	}
+	problem := Problem{Err: err}
+	if n != nil {
+		problem.Line, problem.Column = n.Line, n.Column
+	}
+	*p = append(*p, problem)
+}
+
+// addYAML records the errors of the YAML decoder, which report only a line.
+func (p *problems) addYAML(err error) {
+	var typeErr *yaml.TypeError
+	msgs := []string{err.Error()}
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+	for _, msg := range msgs {
+		m := yamlLineRE.FindStringSubmatch(msg)
+		if m == nil {
+			*p = append(*p, Problem{Err: errors.New(strings.TrimPrefix(msg, "yaml: "))})
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		*p = append(*p, Problem{Line: line, Err: errors.New(m[2])})
+	}
+}
+
+// yamlLineRE splits a YAML decoder message into its line and text.
+var yamlLineRE = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// err returns the collected problems as a DocumentError sorted by position, or nil.
+// Repeats, such as a for_each template's problem in every expansion, are reported once.
+func (p problems) err(schemaVersion int) error {
+	if len(p) == 0 {
+		return nil
+	}
+	sort.SliceStable(p, func(i, j int) bool {
+		if p[i].Line != p[j].Line {
+			return p[i].Line < p[j].Line
+		}
+		return p[i].Column < p[j].Column
+	})
+	out := make([]Problem, 0, len(p))
+	seen := map[string]struct{}{}
+	for _, problem := range p {
+		key := problem.String()
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, problem)
+	}
+	return &DocumentError{Schema: schemaVersion, Problems: out}
+}
+
+// checkFields reports every mapping key under n that the yaml tags of t do not declare,
+// recursing into the fields it does declare.
+func (p *problems) checkFields(n *yaml.Node, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case n.Kind == yaml.DocumentNode:
+		for _, c := range n.Content {
+			p.checkFields(c, t)
+		}
+	case n.Kind == yaml.SequenceNode && t.Kind() == reflect.Slice:
+		for _, c := range n.Content {
+			p.checkFields(c, t.Elem())
+		}
+	case n.Kind == yaml.MappingNode && t.Kind() == reflect.Struct:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			ft, ok := fields[key.Value]
+			if !ok {
+				hint := suggest(key.Value, fields)
+				if v2, ok := v2Shapes[t]; ok {
+					if _, known := yamlFields(v2)[key.Value]; known {
+						hint = " (a schema version 2 field; set version: 2 to use it)"
+					}
+				}
+				p.add(key, fmt.Errorf("%w %q in %s%s", ErrUnknownField, key.Value, fieldContext(t), hint))
+				continue
+			}
+			p.checkFields(n.Content[i+1], ft)
+		}
+	}
+}
+
+// v2Shapes maps the version 1 document shapes to their version 2 counterparts, so a version 2
+// key in a version 1 document is reported as such.
+var v2Shapes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(v1Document{}): reflect.TypeOf(v2Document{}),
+	reflect.TypeOf(v1Action{}):   reflect.TypeOf(v2Action{}),
+}
+
+// yamlFields maps the keys a struct decodes, including inlined structs, to their field types.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",inline,") {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// fieldContext names where a key was found for unknown field messages.
+func fieldContext(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf(v1Document{}), reflect.TypeOf(v2Document{}):
+		return "document"
+	case reflect.TypeOf(Action{}), reflect.TypeOf(v1Action{}), reflect.TypeOf(v2Action{}):
+		return "action"
+	case reflect.TypeOf(v2Permission{}):
+		return "permission"
+	}
+	return strings.ToLower(t.Name())
+}
+
+// suggest returns a "did you mean" hint naming the closest declared key, if any is close.
+func suggest(key string, fields map[string]reflect.Type) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best, bestDist := "", 3
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDist && d < len(key) {
+			best, bestDist = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance is the optimal string alignment distance between a and b, counting a swap of
+// adjacent characters as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(strings.ToLower(a)), []rune(strings.ToLower(b))
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
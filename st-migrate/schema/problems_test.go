@@ -0,0 +1,101 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParsersRejectUnknownFields(t *testing.T) {
+	doc := "version: 1\nactions:\n  - role: admin\n    ensrue: absent\n    remvoe: [users:read]\n  - rename: {from: a, too: b}\n"
+	_, err := DefaultRegistry().Parse([]byte(doc))
+	require.ErrorIs(t, err, ErrUnknownField)
+
+	var docErr *DocumentError
+	require.True(t, errors.As(err, &docErr))
+	require.Equal(t, 1, docErr.Schema)
+	var got []string
+	for _, p := range docErr.Problems {
+		got = append(got, p.String())
+	}
+	require.Equal(t, []string{
+		`line 4, column 5: unknown field "ensrue" in action (did you mean "ensure"?)`,
+		`line 5, column 5: unknown field "remvoe" in action (did you mean "remove"?)`,
+		"line 6, column 5: action 1: rename needs both from and to",
+		`line 6, column 23: unknown field "too" in rename (did you mean "to"?)`,
+	}, got)
+}
+
+func TestV2ParserRejectsUnknownPermissionFields(t *testing.T) {
+	doc := "version: 2\nauthr: jane\nactions:\n  - role: support\n    add:\n      - name: ticket:read\n        descripton: Read tickets\n      - ticket:write\n"
+	_, err := V2Parser{}.Parse([]byte(doc))
+	require.EqualError(t, err, `schema v2: 2 problems: line 2, column 1: unknown field "authr" in document (did you mean "author"?); `+
+		`line 7, column 9: unknown field "descripton" in permission (did you mean "description"?)`)
+}
+
+func TestParsersReportEveryProblem(t *testing.T) {
+	doc := "version: 1\nactions:\n  - ensure: present\n  - role: admin\n    ensure: maybe\n  - role: viewer\n    set: [a]\n    add: [b]\n  - role: ok\n    add: oops\n"
+	_, err := V1Parser{}.Parse([]byte(doc))
+	require.EqualError(t, err, `schema v1: 4 problems: line 3, column 5: action 0 missing role; `+
+		`line 5, column 13: action admin has invalid ensure "maybe"; `+
+		`line 6, column 5: action viewer cannot combine set or restore with add or remove; `+
+		"line 10: cannot unmarshal !!str `oops` into []string")
+}
+
+func TestParsersReportForEachProblemsOnce(t *testing.T) {
+	doc := "version: 1\nactions:\n  - for_each: [a, b]\n    role: ${each.key}\n    ad: [x]\n  - for_each: []\n    role: ${each.key}\n"
+	_, err := V1Parser{}.Parse([]byte(doc))
+	require.EqualError(t, err, `schema v1: 2 problems: line 5, column 5: unknown field "ad" in action (did you mean "add"?); `+
+		"line 6, column 15: action 1: for_each is empty")
+}
+
+func TestRegistryPositionsMetadataErrors(t *testing.T) {
+	reg := DefaultRegistry()
+
+	_, err := reg.Parse([]byte("actions:\n  - role: a\n version: 9\n"))
+	require.EqualError(t, err, "schema: line 2: did not find expected key")
+
+	_, err = reg.Parse([]byte("# header\nversion: two\n"))
+	require.EqualError(t, err, `schema: line 2, column 10: version must be an integer, got "two"`)
+
+	_, err = reg.Parse([]byte("\nversion: 99\n"))
+	require.ErrorIs(t, err, ErrUnsupportedSchema)
+	require.EqualError(t, err, "schema: line 2, column 10: unsupported schema version 99")
+}
+
+func TestEditDistanceCountsSwapsOnce(t *testing.T) {
+	require.Equal(t, 1, editDistance("remvoe", "remove"))
+	require.Equal(t, 1, editDistance("ensrue", "ensure"))
+	require.Equal(t, 3, editDistance("abc", ""))
+	require.Equal(t, "", suggest("xyz", yamlFields(reflect.TypeOf(Action{}))))
+}
+
+// bytesParser only implements Parser, so ParseWith re-encodes the document for it.
+type bytesParser struct{}
+
+func (bytesParser) Parse(data []byte) (*Spec, error) { return V1Parser{}.Parse(data) }
+
+func TestRegistryParseWithKeepsPositions(t *testing.T) {
+	doc := "version: 1\n# comment\nactions:\n  - role: a\n    add: [x]\n  - role: b\n    ad: [y]\n"
+	grow := func(root *yaml.Node) error {
+		add := root.Content[0].Content[3].Content[0].Content[3]
+		add.Content = append(add.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "z", Line: 5, Column: 13})
+		return nil
+	}
+
+	spec, err := DefaultRegistry().ParseWith([]byte("version: 1\n# comment\nactions:\n  - role: a\n    add: [x]\n"), grow)
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "z"}, spec.Actions[0].Add)
+
+	_, err = DefaultRegistry().ParseWith([]byte(doc), grow)
+	require.EqualError(t, err, `schema v1: line 7, column 5: unknown field "ad" in action (did you mean "add"?)`)
+
+	reg := NewRegistry()
+	reg.Register(1, bytesParser{})
+	spec, err = reg.ParseWith([]byte("version: 1\nactions:\n  - role: a\n    add: [x]\n"), grow)
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "z"}, spec.Actions[0].Add)
+}
@@ -0,0 +1,47 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// normalizeRename trims the role names and tenants and defaults the page size.
+func normalizeRename(rename *Rename) error {
+	rename.From = strings.TrimSpace(rename.From)
+	rename.To = strings.TrimSpace(rename.To)
+	if rename.From == "" || rename.To == "" {
+		return fmt.Errorf("rename needs both from and to")
+	}
+	if rename.From == rename.To {
+		return fmt.Errorf("rename of %s has the same from and to", rename.From)
+	}
+	rename.Tenants = normalizeNames(rename.Tenants)
+	if rename.PageSize < 0 {
+		return fmt.Errorf("rename of %s has negative page_size", rename.From)
+	}
+	if rename.PageSize == 0 {
+		rename.PageSize = DefaultRenamePageSize
+	}
+	return nil
+}
+
+// Invert derives the spec that undoes s, for down files declaring inverse: true.
+// Only rename actions can be inverted; they are reversed and applied in reverse order.
+func (s *Spec) Invert() (*Spec, error) {
+	out := &Spec{Version: s.Version, Header: s.Header, Actions: make([]Action, 0, len(s.Actions))}
+	for i := len(s.Actions) - 1; i >= 0; i-- {
+		action := s.Actions[i]
+		if action.Rename == nil {
+			name := action.Role
+			if name == "" {
+				name = fmt.Sprintf("%d", i)
+			}
+			return nil, fmt.Errorf("action %s cannot be inverted automatically; write the down file by hand", name)
+		}
+		inverse := *action.Rename
+		inverse.From, inverse.To = action.Rename.To, action.Rename.From
+		inverse.Tenants = append([]string(nil), action.Rename.Tenants...)
+		out.Actions = append(out.Actions, Action{Rename: &inverse})
+	}
+	return out, nil
+}
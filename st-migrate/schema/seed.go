@@ -0,0 +1,42 @@
+package schema
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Seed file formats.
+const (
+	SeedFormatCSV  = "csv"
+	SeedFormatJSON = "json"
+)
+
+// normalizeSeed cleans the seed file path, infers its format and defaults the batch size.
+// The path must stay inside the migrations source.
+func normalizeSeed(seed *Seed) error {
+	file := strings.TrimSpace(seed.File)
+	if file == "" {
+		return fmt.Errorf("seed missing file")
+	}
+	file = path.Clean(file)
+	if !fs.ValidPath(file) {
+		return fmt.Errorf("seed file %q must be a relative path inside the migrations source", seed.File)
+	}
+	seed.File = file
+	seed.Format = strings.TrimSpace(strings.ToLower(seed.Format))
+	if seed.Format == "" {
+		seed.Format = strings.TrimPrefix(strings.ToLower(path.Ext(file)), ".")
+	}
+	if seed.Format != SeedFormatCSV && seed.Format != SeedFormatJSON {
+		return fmt.Errorf("seed file %s has unsupported format %q (want csv or json)", file, seed.Format)
+	}
+	if seed.BatchSize < 0 {
+		return fmt.Errorf("seed file %s has negative batch_size", file)
+	}
+	if seed.BatchSize == 0 {
+		seed.BatchSize = DefaultSeedBatchSize
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package schema
+
+// Action represents a single role/permission operation from a migration spec.
+// Set, when non-nil, lists the exact permissions the role must end up with; Restore (in down
+// files) puts back the exact set the up migration's Set replaced.
+// Assign and Unassign list user IDs gaining or losing the role in Tenant (the executor's
+// default tenant when empty). Description, Owner, Tags and Permissions are schema v2
+// metadata; V1Parser rejects them. A seed action sets only Seed; its roles come from the file.
+// A rename action sets only Rename. Includes lists roles whose permissions the role also
+// receives; Propagate makes the Add and Remove of this role apply to every role including it.
+// Both are resolved against the replayed model (see model.State.Expand), not by the parser.
+type Action struct {
+	Role        string       `yaml:"role"`
+	Ensure      string       `yaml:"ensure"`
+	Add         []string     `yaml:"add,omitempty"`
+	Remove      []string     `yaml:"remove,omitempty"`
+	Set         []string     `yaml:"set,omitempty" json:",omitempty"`
+	Restore     bool         `yaml:"restore,omitempty" json:",omitempty"`
+	Tenant      string       `yaml:"tenant,omitempty" json:",omitempty"`
+	Assign      []string     `yaml:"assign,omitempty" json:",omitempty"`
+	Unassign    []string     `yaml:"unassign,omitempty" json:",omitempty"`
+	Includes    []string     `yaml:"includes,omitempty" json:",omitempty"`
+	Propagate   bool         `yaml:"propagate,omitempty" json:",omitempty"`
+	Description string       `yaml:"description,omitempty" json:",omitempty"`
+	Owner       string       `yaml:"owner,omitempty" json:",omitempty"`
+	Tags        []string     `yaml:"tags,omitempty" json:",omitempty"`
+	Permissions []Permission `yaml:"-" json:",omitempty"`
+	Seed        *Seed        `yaml:"seed,omitempty" json:",omitempty"`
+	Rename      *Rename      `yaml:"rename,omitempty" json:",omitempty"`
+}
+
+// HasMetadata reports whether the action describes its role or permissions.
+func (a Action) HasMetadata() bool {
+	return a.Description != "" || a.Owner != "" || len(a.Tags) > 0 || len(a.Permissions) > 0
+}
+
+// HasSet reports whether the action replaces the role's permissions with an exact set,
+// either listed in Set or restored from before the up migration.
+func (a Action) HasSet() bool {
+	return a.Set != nil || a.Restore
+}
+
+// HasAssignments reports whether the action assigns or unassigns users.
+func (a Action) HasAssignments() bool {
+	return len(a.Assign) > 0 || len(a.Unassign) > 0
+}
+
+// HasIncludes reports whether the action includes other roles or propagates to roles including it.
+func (a Action) HasIncludes() bool {
+	return len(a.Includes) > 0 || a.Propagate
+}
+
+// DefaultSeedBatchSize is the number of seed rows applied between progress checkpoints.
+const DefaultSeedBatchSize = 100
+
+// Seed references a CSV or JSON file next to the migration listing user_id, role and
+// (optionally) tenant for each user to assign.
+type Seed struct {
+	File string `yaml:"file" json:"file"`
+	// Format is csv or json; it is inferred from the file extension when empty.
+	Format    string `yaml:"format,omitempty" json:"format,omitempty"`
+	BatchSize int    `yaml:"batch_size,omitempty" json:"batch_size,omitempty"`
+	// Unassign removes the listed roles instead of assigning them, typically in down files.
+	Unassign bool `yaml:"unassign,omitempty" json:"unassign,omitempty"`
+}
+
+// DefaultRenamePageSize is the number of users a rename moves between progress checkpoints.
+const DefaultRenamePageSize = 100
+
+// Rename moves a role to a new name: the new role gets the old role's permissions and users
+// (in Tenants, the executor's default tenant when empty), then the old role is deleted.
+type Rename struct {
+	From     string   `yaml:"from" json:"from"`
+	To       string   `yaml:"to" json:"to"`
+	Tenants  []string `yaml:"tenants,omitempty" json:"tenants,omitempty"`
+	PageSize int      `yaml:"page_size,omitempty" json:"page_size,omitempty"`
+}
+
+// Permission describes a permission added by a schema v2 action.
+type Permission struct {
+	Name        string `yaml:"name" json:"name"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Header is migration-level metadata declared by schema v2 documents.
+type Header struct {
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+	Author      string `yaml:"author,omitempty" json:"author,omitempty"`
+	Ticket      string `yaml:"ticket,omitempty" json:"ticket,omitempty"`
+}
+
+// Empty reports whether no header field is set.
+func (h Header) Empty() bool {
+	return h == Header{}
+}
+
+// Spec is the parsed YAML document for one migration direction.
+// Version is the schema version (not the migration filename version).
+type Spec struct {
+	Version int      `yaml:"version"`
+	Header  Header   `yaml:",inline"`
+	Actions []Action `yaml:"actions"`
+	// Inverse (down files only) derives the down actions from the up file; see Spec.Invert.
+	Inverse bool `yaml:"inverse,omitempty"`
+	// AllowProtected lets this migration delete or strip protected roles and permissions,
+	// provided the run explicitly acknowledges its version.
+	AllowProtected bool `yaml:"allow_protected,omitempty"`
+}
+
+// HasIncludes reports whether any action needs expanding against the model; see Action.HasIncludes.
+func (s *Spec) HasIncludes() bool {
+	for _, action := range s.Actions {
+		if action.HasIncludes() {
+			return true
+		}
+	}
+	return false
+}
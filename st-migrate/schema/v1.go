@@ -0,0 +1,306 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// V1Parser parses schema version 1 documents.
+type V1Parser struct{}
+
+// v1Document is the shape of a version 1 document; the version 2 metadata is not part of it.
+type v1Document struct {
+	Version        int        `yaml:"version"`
+	Actions        []v1Action `yaml:"actions"`
+	Inverse        bool       `yaml:"inverse"`
+	AllowProtected bool       `yaml:"allow_protected"`
+}
+
+type v1Action struct {
+	Role      string   `yaml:"role"`
+	Ensure    string   `yaml:"ensure"`
+	Add       []string `yaml:"add"`
+	Remove    []string `yaml:"remove"`
+	Set       []string `yaml:"set"`
+	Restore   bool     `yaml:"restore"`
+	Tenant    string   `yaml:"tenant"`
+	Assign    []string `yaml:"assign"`
+	Unassign  []string `yaml:"unassign"`
+	Includes  []string `yaml:"includes"`
+	Propagate bool     `yaml:"propagate"`
+	Seed      *Seed    `yaml:"seed"`
+	Rename    *Rename  `yaml:"rename"`
+}
+
+// spec maps a decoded version 1 document onto Spec.
+func (doc v1Document) spec() Spec {
+	spec := Spec{
+		Version:        doc.Version,
+		Actions:        make([]Action, 0, len(doc.Actions)),
+		Inverse:        doc.Inverse,
+		AllowProtected: doc.AllowProtected,
+	}
+	for _, in := range doc.Actions {
+		spec.Actions = append(spec.Actions, Action{
+			Role:      in.Role,
+			Ensure:    in.Ensure,
+			Add:       in.Add,
+			Remove:    in.Remove,
+			Set:       in.Set,
+			Restore:   in.Restore,
+			Tenant:    in.Tenant,
+			Assign:    in.Assign,
+			Unassign:  in.Unassign,
+			Includes:  in.Includes,
+			Propagate: in.Propagate,
+			Seed:      in.Seed,
+			Rename:    in.Rename,
+		})
+	}
+	return spec
+}
+
+func (p V1Parser) Parse(data []byte) (*Spec, error) {
+	root, probs := decodeDocument(data)
+	if root == nil {
+		return nil, probs.err(1)
+	}
+	return p.parse(root, probs)
+}
+
+// ParseNode decodes an already parsed document; see NodeParser.
+func (p V1Parser) ParseNode(root *yaml.Node) (*Spec, error) {
+	return p.parse(root, expandActions(root))
+}
+
+func (V1Parser) parse(root *yaml.Node, probs problems) (*Spec, error) {
+	var doc v1Document
+	probs.checkFields(root, reflect.TypeOf(doc))
+	if err := root.Decode(&doc); err != nil {
+		probs.addYAML(err)
+	}
+	spec := doc.spec()
+	if spec.Version == 0 {
+		spec.Version = 1
+	}
+	nodes := locate(root)
+	for i := range spec.Actions {
+		action := &spec.Actions[i]
+		action.Role = strings.TrimSpace(action.Role)
+		if action.Seed != nil || action.Rename != nil {
+			if err := normalizeStandalone(action); err != nil {
+				probs.add(nodes.action(i), fmt.Errorf("action %d: %w", i, err))
+			}
+			continue
+		}
+		if action.Role == "" {
+			probs.add(nodes.action(i), fmt.Errorf("action %d missing role", i))
+			continue
+		}
+		action.Ensure = normalizeEnsure(action.Ensure)
+		if action.Ensure != "present" && action.Ensure != "absent" {
+			probs.add(nodes.field(i, "ensure"), fmt.Errorf("action %s has invalid ensure %q", action.Role, action.Ensure))
+			continue
+		}
+		action.Add = normalizePermissions(action.Add)
+		action.Remove = normalizePermissions(action.Remove)
+		if err := normalizeSet(action); err != nil {
+			probs.add(nodes.action(i), err)
+		}
+		if err := normalizeAssignments(action); err != nil {
+			probs.add(nodes.action(i), err)
+		}
+		if err := normalizeIncludes(action); err != nil {
+			probs.add(nodes.action(i), err)
+		}
+	}
+	if err := checkInverse(&spec); err != nil {
+		probs.add(nodes.top("inverse"), err)
+	}
+	if err := probs.err(1); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+var (
+	errSeedRoleFields   = errors.New("seed action cannot set role fields; the seed file lists roles per user")
+	errRenameRoleFields = errors.New("rename action cannot set role fields; it names its roles in from and to")
+)
+
+// normalizeStandalone validates a seed or rename action, which names its roles itself and
+// may set nothing else.
+func normalizeStandalone(action *Action) error {
+	if action.Seed != nil && action.Rename != nil {
+		return fmt.Errorf("action cannot both seed and rename")
+	}
+	if action.Role != "" || strings.TrimSpace(action.Ensure) != "" || len(action.Add) > 0 || len(action.Remove) > 0 || action.HasSet() ||
+		action.Tenant != "" || action.HasAssignments() || action.HasMetadata() || action.HasIncludes() {
+		if action.Seed != nil {
+			return errSeedRoleFields
+		}
+		return errRenameRoleFields
+	}
+	if action.Seed != nil {
+		return normalizeSeed(action.Seed)
+	}
+	return normalizeRename(action.Rename)
+}
+
+// docNodes locates the parts of a decoded document that problems are reported at.
+type docNodes struct {
+	doc     *yaml.Node
+	actions []*yaml.Node
+}
+
+func locate(root *yaml.Node) docNodes {
+	var nodes docNodes
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nodes
+	}
+	nodes.doc = root.Content[0]
+	if actions := mappingValue(nodes.doc, "actions"); actions != nil && actions.Kind == yaml.SequenceNode {
+		nodes.actions = actions.Content
+	}
+	return nodes
+}
+
+// top returns the value of a top-level key, or the document when the key is absent.
+func (d docNodes) top(key string) *yaml.Node {
+	if d.doc == nil {
+		return nil
+	}
+	if n := mappingValue(d.doc, key); n != nil {
+		return n
+	}
+	return d.doc
+}
+
+// action returns the node of action i, or nil when it cannot be located.
+func (d docNodes) action(i int) *yaml.Node {
+	if i >= len(d.actions) {
+		return nil
+	}
+	return d.actions[i]
+}
+
+// field returns the value of key in action i, falling back to the action itself.
+func (d docNodes) field(i int, key string) *yaml.Node {
+	action := d.action(i)
+	if action == nil || action.Kind != yaml.MappingNode {
+		return action
+	}
+	if n := mappingValue(action, key); n != nil {
+		return n
+	}
+	return action
+}
+
+// checkInverse rejects inverse documents that also list actions.
+func checkInverse(spec *Spec) error {
+	if spec.Inverse && len(spec.Actions) > 0 {
+		return fmt.Errorf("an inverse document cannot list actions")
+	}
+	return nil
+}
+
+// normalizeSet normalizes an exact permission set, keeping an empty set (which strips every
+// permission) distinct from no set, and rejects combinations with incremental changes.
+func normalizeSet(action *Action) error {
+	if action.Set != nil {
+		action.Set = normalizePermissions(action.Set)
+	}
+	if !action.HasSet() {
+		return nil
+	}
+	switch {
+	case action.Set != nil && action.Restore:
+		return fmt.Errorf("action %s cannot both set and restore permissions", action.Role)
+	case len(action.Add) > 0 || len(action.Remove) > 0:
+		return fmt.Errorf("action %s cannot combine set or restore with add or remove", action.Role)
+	case action.Ensure == "absent":
+		return fmt.Errorf("action %s cannot set permissions of a role it deletes", action.Role)
+	}
+	return nil
+}
+
+// normalizeAssignments trims the tenant and user IDs (which are case-sensitive) and rejects
+// assignments on a role being deleted.
+func normalizeAssignments(action *Action) error {
+	action.Tenant = strings.TrimSpace(action.Tenant)
+	action.Assign = normalizeNames(action.Assign)
+	action.Unassign = normalizeNames(action.Unassign)
+	if action.Ensure == "absent" && action.HasAssignments() {
+		return fmt.Errorf("action %s cannot assign or unassign users of a role it deletes", action.Role)
+	}
+	return nil
+}
+
+// normalizeIncludes trims the included role names and rejects includes or propagation that
+// cannot apply to the action.
+func normalizeIncludes(action *Action) error {
+	action.Includes = normalizeNames(action.Includes)
+	if !action.HasIncludes() {
+		return nil
+	}
+	switch {
+	case action.Ensure == "absent":
+		return fmt.Errorf("action %s cannot include roles or propagate while deleting the role", action.Role)
+	case slices.Contains(action.Includes, action.Role):
+		return fmt.Errorf("action %s cannot include itself", action.Role)
+	case action.HasSet():
+		// set replaces the role's permissions, which would drop the included ones
+		return fmt.Errorf("action %s cannot combine includes or propagate with set or restore; use add or remove", action.Role)
+	case action.Propagate && len(action.Add) == 0 && len(action.Remove) == 0:
+		return fmt.Errorf("action %s propagates but neither adds nor removes permissions", action.Role)
+	}
+	return nil
+}
+
+// normalizeNames trims and de-duplicates case-sensitive names such as user IDs and tags.
+func normalizeNames(names []string) []string {
+	var out []string
+	seen := map[string]struct{}{}
+	for _, n := range names {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		if _, ok := seen[n]; ok {
+			continue
+		}
+		seen[n] = struct{}{}
+		out = append(out, n)
+	}
+	return out
+}
+
+func normalizeEnsure(val string) string {
+	val = strings.TrimSpace(strings.ToLower(val))
+	if val == "" {
+		return "present"
+	}
+	return val
+}
+
+func normalizePermissions(perms []string) []string {
+	seen := map[string]struct{}{}
+	out := make([]string, 0, len(perms))
+	for _, p := range perms {
+		p = strings.TrimSpace(strings.ToLower(p))
+		if p == "" {
+			continue
+		}
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	return out
+}
@@ -31,12 +31,12 @@ actions:
 func TestRegistryUnknownSchema(t *testing.T) {
 	reg := NewRegistry() // empty
 	_, err := reg.Parse([]byte(`version: 99`))
-	require.Error(t, err)
+	require.ErrorIs(t, err, ErrUnsupportedSchema)
 }
 
 func TestParserDefaultsSchemaVersionWhenMissing(t *testing.T) {
 	reg := DefaultRegistry()
-	spec, err := reg.Parse([]byte(`actions:\n  - role: a`))
+	spec, err := reg.Parse([]byte("actions:\n  - role: a\n"))
 	require.NoError(t, err)
 	require.Equal(t, 1, spec.Version)
 }
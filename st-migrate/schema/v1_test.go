@@ -0,0 +1,200 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestV1ParserNormalizes(t *testing.T) {
+	input := []byte(`
+version: 1
+actions:
+  - role:   " Example:Admin "
+    ensure: ""
+    add: ["Perm.One", "perm.one", "perm.two "]
+    remove: [" x ", "x"]
+`)
+	parser := V1Parser{}
+	spec, err := parser.Parse(input)
+	require.NoError(t, err)
+	require.Equal(t, 1, spec.Version)
+	require.Len(t, spec.Actions, 1)
+
+	act := spec.Actions[0]
+	require.Equal(t, "Example:Admin", act.Role)
+	require.Equal(t, "present", act.Ensure)
+	require.ElementsMatch(t, []string{"perm.one", "perm.two"}, act.Add)
+	require.ElementsMatch(t, []string{"x"}, act.Remove)
+}
+
+func TestV1ParserReadsAllowProtected(t *testing.T) {
+	spec, err := V1Parser{}.Parse([]byte("version: 1\nallow_protected: true\nactions:\n  - role: admin\n    ensure: absent\n"))
+	require.NoError(t, err)
+	require.True(t, spec.AllowProtected)
+}
+
+func TestV1ParserReadsAssignments(t *testing.T) {
+	spec, err := V1Parser{}.Parse([]byte("version: 1\nactions:\n  - role: admin\n    tenant: \" tenant-a \"\n    assign: [User-1, \" User-1 \", user-2]\n    unassign: [user-3]\n"))
+	require.NoError(t, err)
+	act := spec.Actions[0]
+	require.Equal(t, "tenant-a", act.Tenant)
+	require.Equal(t, []string{"User-1", "user-2"}, act.Assign)
+	require.Equal(t, []string{"user-3"}, act.Unassign)
+	require.True(t, act.HasAssignments())
+}
+
+func TestParsersRejectAssignmentsOnDeletedRole(t *testing.T) {
+	_, err := V1Parser{}.Parse([]byte("version: 1\nactions:\n  - role: admin\n    ensure: absent\n    unassign: [u1]\n"))
+	require.ErrorContains(t, err, "cannot assign or unassign")
+	_, err = V2Parser{}.Parse([]byte("version: 2\nactions:\n  - role: admin\n    ensure: absent\n    assign: [u1]\n"))
+	require.ErrorContains(t, err, "cannot assign or unassign")
+}
+
+func TestParsersReadSeedActions(t *testing.T) {
+	spec, err := V1Parser{}.Parse([]byte("version: 1\nactions:\n  - seed:\n      file: ./seeds/Users.CSV\n"))
+	require.NoError(t, err)
+	require.Equal(t, &Seed{File: "seeds/Users.CSV", Format: SeedFormatCSV, BatchSize: DefaultSeedBatchSize}, spec.Actions[0].Seed)
+	require.Empty(t, spec.Actions[0].Role)
+
+	spec, err = V2Parser{}.Parse([]byte("version: 2\nactions:\n  - seed: {file: users.data, format: JSON, batch_size: 5, unassign: true}\n"))
+	require.NoError(t, err)
+	require.Equal(t, &Seed{File: "users.data", Format: SeedFormatJSON, BatchSize: 5, Unassign: true}, spec.Actions[0].Seed)
+}
+
+func TestParsersRejectInvalidSeedActions(t *testing.T) {
+	cases := map[string]string{
+		"version: 1\nactions:\n  - role: admin\n    seed: {file: users.csv}\n": "cannot set role fields",
+		"version: 2\nactions:\n  - add: [a]\n    seed: {file: users.csv}\n":    "cannot set role fields",
+		"version: 1\nactions:\n  - seed: {file: ../users.csv}\n":               "inside the migrations source",
+		"version: 1\nactions:\n  - seed: {file: users.txt}\n":                  "unsupported format",
+		"version: 1\nactions:\n  - seed: {file: users.csv, batch_size: -1}\n":  "negative batch_size",
+	}
+	reg := DefaultRegistry()
+	for doc, want := range cases {
+		_, err := reg.Parse([]byte(doc))
+		require.ErrorContains(t, err, want, doc)
+	}
+}
+
+func TestParsersReadExactPermissionSets(t *testing.T) {
+	spec, err := V1Parser{}.Parse([]byte("version: 1\nactions:\n  - role: admin\n    set: [B, a, b]\n  - role: viewer\n    set: []\n  - role: auditor\n    restore: true\n  - role: guest\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"b", "a"}, spec.Actions[0].Set)
+	require.NotNil(t, spec.Actions[1].Set, "an empty set strips every permission")
+	require.True(t, spec.Actions[1].HasSet())
+	require.True(t, spec.Actions[2].HasSet())
+	require.False(t, spec.Actions[3].HasSet())
+
+	spec, err = V2Parser{}.Parse([]byte("version: 2\nactions:\n  - role: admin\n    set:\n      - name: a\n        description: Read A\n      - b\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, spec.Actions[0].Set)
+	require.Equal(t, []Permission{{Name: "a", Description: "Read A"}}, spec.Actions[0].Permissions)
+}
+
+func TestParsersRejectInvalidPermissionSets(t *testing.T) {
+	cases := map[string]string{
+		"version: 1\nactions:\n  - role: admin\n    set: [a]\n    add: [b]\n":            "cannot combine set or restore",
+		"version: 2\nactions:\n  - role: admin\n    set: [a]\n    restore: true\n":       "cannot both set and restore",
+		"version: 1\nactions:\n  - role: admin\n    ensure: absent\n    restore: true\n": "cannot set permissions of a role it deletes",
+		"version: 1\nactions:\n  - seed: {file: users.csv}\n    set: [a]\n":              "cannot set role fields",
+	}
+	reg := DefaultRegistry()
+	for doc, want := range cases {
+		_, err := reg.Parse([]byte(doc))
+		require.ErrorContains(t, err, want, doc)
+	}
+}
+
+func TestParsersReadRenameActions(t *testing.T) {
+	spec, err := V1Parser{}.Parse([]byte("version: 1\nactions:\n  - rename: {from: \" support \", to: helpdesk, tenants: [public, t1, t1]}\n"))
+	require.NoError(t, err)
+	require.Equal(t, &Rename{From: "support", To: "helpdesk", Tenants: []string{"public", "t1"}, PageSize: DefaultRenamePageSize}, spec.Actions[0].Rename)
+
+	spec, err = V2Parser{}.Parse([]byte("version: 2\nactions:\n  - rename: {from: support, to: helpdesk, page_size: 20}\n"))
+	require.NoError(t, err)
+	require.Equal(t, 20, spec.Actions[0].Rename.PageSize)
+
+	spec, err = V2Parser{}.Parse([]byte("version: 2\ninverse: true\n"))
+	require.NoError(t, err)
+	require.True(t, spec.Inverse)
+
+	cases := map[string]string{
+		"version: 1\nactions:\n  - role: admin\n    rename: {from: a, to: b}\n":         "rename action cannot set role fields",
+		"version: 2\nactions:\n  - add: [x]\n    rename: {from: a, to: b}\n":            "rename action cannot set role fields",
+		"version: 1\nactions:\n  - rename: {from: a, to: a}\n":                          "same from and to",
+		"version: 1\nactions:\n  - rename: {from: a}\n":                                 "needs both from and to",
+		"version: 1\nactions:\n  - rename: {from: a, to: b, page_size: -1}\n":           "negative page_size",
+		"version: 1\nactions:\n  - rename: {from: a, to: b}\n    seed: {file: u.csv}\n": "cannot both seed and rename",
+		"version: 1\ninverse: true\nactions:\n  - role: admin\n":                        "inverse document cannot list actions",
+	}
+	reg := DefaultRegistry()
+	for doc, want := range cases {
+		_, err := reg.Parse([]byte(doc))
+		require.ErrorContains(t, err, want, doc)
+	}
+}
+
+func TestSpecInvertReversesRenames(t *testing.T) {
+	spec := &Spec{Version: 1, Actions: []Action{
+		{Rename: &Rename{From: "a", To: "b"}},
+		{Rename: &Rename{From: "b", To: "c", Tenants: []string{"t1"}}},
+	}}
+	inverse, err := spec.Invert()
+	require.NoError(t, err)
+	require.Equal(t, []Action{
+		{Rename: &Rename{From: "c", To: "b", Tenants: []string{"t1"}}},
+		{Rename: &Rename{From: "b", To: "a"}},
+	}, inverse.Actions)
+
+	_, err = (&Spec{Actions: []Action{{Role: "admin", Ensure: "present"}}}).Invert()
+	require.ErrorContains(t, err, "action admin cannot be inverted automatically")
+}
+
+func TestParsersReadIncludes(t *testing.T) {
+	spec, err := V1Parser{}.Parse([]byte("version: 1\nactions:\n  - role: editor\n    includes: [\" viewer \", viewer]\n    add: [docs:write]\n  - role: viewer\n    add: [docs:export]\n    propagate: true\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"viewer"}, spec.Actions[0].Includes)
+	require.True(t, spec.Actions[1].Propagate)
+	require.True(t, spec.HasIncludes())
+
+	spec, err = V2Parser{}.Parse([]byte("version: 2\nactions:\n  - role: editor\n    includes: [viewer]\n"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"viewer"}, spec.Actions[0].Includes)
+
+	cases := map[string]string{
+		"version: 1\nactions:\n  - role: editor\n    includes: [editor]\n":                     "cannot include itself",
+		"version: 2\nactions:\n  - role: editor\n    ensure: absent\n    includes: [viewer]\n": "cannot include roles or propagate while deleting",
+		"version: 1\nactions:\n  - role: viewer\n    propagate: true\n":                        "neither adds nor removes",
+		"version: 1\nactions:\n  - role: viewer\n    set: [a]\n    propagate: true\n":          "cannot combine includes or propagate with set or restore",
+		"version: 1\nactions:\n  - role: editor\n    includes: [viewer]\n    set: [a]\n":       "cannot combine includes or propagate with set or restore",
+		"version: 2\nactions:\n  - role: editor\n    includes: [viewer]\n    restore: true\n":  "cannot combine includes or propagate with set or restore",
+		"version: 1\nactions:\n  - seed: {file: users.csv}\n    includes: [viewer]\n":          "cannot set role fields",
+	}
+	reg := DefaultRegistry()
+	for doc, want := range cases {
+		_, err := reg.Parse([]byte(doc))
+		require.ErrorContains(t, err, want, doc)
+	}
+}
+
+func TestV1ParserRejectsV2Metadata(t *testing.T) {
+	_, err := V1Parser{}.Parse([]byte("version: 1\ndescription: Support roles\nactions:\n  - role: support\n    owner: team-support\n"))
+	require.ErrorIs(t, err, ErrUnknownField)
+	require.ErrorContains(t, err, `line 2, column 1: unknown field "description" in document`)
+	require.ErrorContains(t, err, `line 5, column 5: unknown field "owner" in action`)
+}
+
+func TestV1ParserChecksTheV1FieldSet(t *testing.T) {
+	doc := "version: 1\nauthor: jane\nticket: AUTH-1\nactions:\n  - role: support\n    tags: [ops]\n    ensrue: present\n"
+	_, err := V1Parser{}.Parse([]byte(doc))
+	require.EqualError(t, err, "schema v1: 4 problems: "+
+		"line 2, column 1: unknown field \"author\" in document (a schema version 2 field; set version: 2 to use it); "+
+		"line 3, column 1: unknown field \"ticket\" in document (a schema version 2 field; set version: 2 to use it); "+
+		"line 6, column 5: unknown field \"tags\" in action (a schema version 2 field; set version: 2 to use it); "+
+		"line 7, column 5: unknown field \"ensrue\" in action (did you mean \"ensure\"?)")
+
+	_, err = V2Parser{}.Parse([]byte(strings.Replace(doc, "version: 1", "version: 2", 1)))
+	require.EqualError(t, err, "schema v2: line 7, column 5: unknown field \"ensrue\" in action (did you mean \"ensure\"?)")
+}
@@ -0,0 +1,198 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// V2Parser parses schema version 2 documents. Version 2 extends version 1 with a
+// migration header (description, author, ticket), role metadata (description, owner,
+// tags) and permission entries that may be objects with a name and description.
+type V2Parser struct{}
+
+type v2Document struct {
+	Version        int        `yaml:"version"`
+	Header         Header     `yaml:",inline"`
+	Actions        []v2Action `yaml:"actions"`
+	AllowProtected bool       `yaml:"allow_protected"`
+	Inverse        bool       `yaml:"inverse"`
+}
+
+type v2Action struct {
+	Role        string         `yaml:"role"`
+	Ensure      string         `yaml:"ensure"`
+	Description string         `yaml:"description"`
+	Owner       string         `yaml:"owner"`
+	Tags        []string       `yaml:"tags"`
+	Add         []v2Permission `yaml:"add"`
+	Remove      []v2Permission `yaml:"remove"`
+	Set         []v2Permission `yaml:"set"`
+	Restore     bool           `yaml:"restore"`
+	Tenant      string         `yaml:"tenant"`
+	Assign      []string       `yaml:"assign"`
+	Unassign    []string       `yaml:"unassign"`
+	Includes    []string       `yaml:"includes"`
+	Propagate   bool           `yaml:"propagate"`
+	Seed        *Seed          `yaml:"seed"`
+	Rename      *Rename        `yaml:"rename"`
+}
+
+// v2Permission accepts either a plain permission name or a {name, description} object.
+type v2Permission Permission
+
+func (p *v2Permission) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&p.Name)
+	}
+	return node.Decode((*Permission)(p))
+}
+
+func (p V2Parser) Parse(data []byte) (*Spec, error) {
+	root, probs := decodeDocument(data)
+	if root == nil {
+		return nil, probs.err(2)
+	}
+	return p.parse(root, probs)
+}
+
+// ParseNode decodes an already parsed document; see NodeParser.
+func (p V2Parser) ParseNode(root *yaml.Node) (*Spec, error) {
+	return p.parse(root, expandActions(root))
+}
+
+func (V2Parser) parse(root *yaml.Node, probs problems) (*Spec, error) {
+	var doc v2Document
+	probs.checkFields(root, reflect.TypeOf(doc))
+	if err := root.Decode(&doc); err != nil {
+		probs.addYAML(err)
+	}
+	nodes := locate(root)
+	spec := &Spec{
+		Version: doc.Version,
+		Header: Header{
+			Description: strings.TrimSpace(doc.Header.Description),
+			Author:      strings.TrimSpace(doc.Header.Author),
+			Ticket:      strings.TrimSpace(doc.Header.Ticket),
+		},
+		Actions:        make([]Action, 0, len(doc.Actions)),
+		AllowProtected: doc.AllowProtected,
+		Inverse:        doc.Inverse,
+	}
+	for i, in := range doc.Actions {
+		if in.Seed != nil || in.Rename != nil {
+			action, err := in.standaloneAction()
+			if err != nil {
+				probs.add(nodes.action(i), fmt.Errorf("action %d: %w", i, err))
+				continue
+			}
+			spec.Actions = append(spec.Actions, action)
+			continue
+		}
+		action := Action{
+			Role:        strings.TrimSpace(in.Role),
+			Ensure:      normalizeEnsure(in.Ensure),
+			Description: strings.TrimSpace(in.Description),
+			Owner:       strings.TrimSpace(in.Owner),
+			Tags:        normalizeNames(in.Tags),
+			Tenant:      in.Tenant,
+			Assign:      in.Assign,
+			Unassign:    in.Unassign,
+			Includes:    in.Includes,
+			Propagate:   in.Propagate,
+		}
+		if action.Role == "" {
+			probs.add(nodes.action(i), fmt.Errorf("action %d missing role", i))
+			continue
+		}
+		if action.Ensure != "present" && action.Ensure != "absent" {
+			probs.add(nodes.field(i, "ensure"), fmt.Errorf("action %s has invalid ensure %q", action.Role, action.Ensure))
+			continue
+		}
+		action.Add = normalizePermissions(permissionNames(in.Add))
+		action.Remove = normalizePermissions(permissionNames(in.Remove))
+		action.Set = in.setNames()
+		action.Restore = in.Restore
+		if err := normalizeSet(&action); err != nil {
+			probs.add(nodes.action(i), err)
+		}
+		action.Permissions = describePermissions(append(append([]v2Permission{}, in.Add...), in.Set...))
+		if err := normalizeAssignments(&action); err != nil {
+			probs.add(nodes.action(i), err)
+		}
+		if err := normalizeIncludes(&action); err != nil {
+			probs.add(nodes.action(i), err)
+		}
+		spec.Actions = append(spec.Actions, action)
+	}
+	if err := checkInverse(spec); err != nil {
+		probs.add(nodes.top("inverse"), err)
+	}
+	if err := probs.err(2); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+// standaloneAction converts a seed or rename action, which may set nothing else.
+func (in v2Action) standaloneAction() (Action, error) {
+	action := Action{
+		Role:        strings.TrimSpace(in.Role),
+		Ensure:      in.Ensure,
+		Description: in.Description,
+		Owner:       in.Owner,
+		Tags:        in.Tags,
+		Tenant:      in.Tenant,
+		Assign:      in.Assign,
+		Unassign:    in.Unassign,
+		Includes:    in.Includes,
+		Propagate:   in.Propagate,
+		Add:         permissionNames(in.Add),
+		Remove:      permissionNames(in.Remove),
+		Set:         in.setNames(),
+		Restore:     in.Restore,
+		Seed:        in.Seed,
+		Rename:      in.Rename,
+	}
+	if err := normalizeStandalone(&action); err != nil {
+		return Action{}, err
+	}
+	return action, nil
+}
+
+func permissionNames(perms []v2Permission) []string {
+	names := make([]string, 0, len(perms))
+	for _, p := range perms {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+// setNames keeps an absent set nil so it stays distinct from an empty one.
+func (in v2Action) setNames() []string {
+	if in.Set == nil {
+		return nil
+	}
+	return permissionNames(in.Set)
+}
+
+// describePermissions keeps the added (or set) permissions that carry a description, keyed by normalized name.
+func describePermissions(perms []v2Permission) []Permission {
+	var out []Permission
+	seen := map[string]struct{}{}
+	for _, p := range perms {
+		name := strings.TrimSpace(strings.ToLower(p.Name))
+		desc := strings.TrimSpace(p.Description)
+		if name == "" || desc == "" {
+			continue
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		out = append(out, Permission{Name: name, Description: desc})
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestV2ParserReadsMetadata(t *testing.T) {
+	input := []byte(`
+version: 2
+description: " Introduce support role "
+author: jane
+ticket: https://tracker.example.com/AUTH-12
+actions:
+  - role: app:support
+    description: Customer support agents
+    owner: support-team
+    tags: [support, internal, support]
+    add:
+      - name: Ticket:Read
+        description: Read support tickets
+      - ticket:write
+    remove:
+      - ticket:delete
+`)
+	spec, err := DefaultRegistry().Parse(input)
+	require.NoError(t, err)
+	require.Equal(t, 2, spec.Version)
+	require.Equal(t, Header{Description: "Introduce support role", Author: "jane", Ticket: "https://tracker.example.com/AUTH-12"}, spec.Header)
+
+	require.Len(t, spec.Actions, 1)
+	act := spec.Actions[0]
+	require.Equal(t, "present", act.Ensure)
+	require.Equal(t, "Customer support agents", act.Description)
+	require.Equal(t, "support-team", act.Owner)
+	require.Equal(t, []string{"support", "internal"}, act.Tags)
+	require.Equal(t, []string{"ticket:read", "ticket:write"}, act.Add)
+	require.Equal(t, []string{"ticket:delete"}, act.Remove)
+	require.Equal(t, []Permission{{Name: "ticket:read", Description: "Read support tickets"}}, act.Permissions)
+	require.True(t, act.HasMetadata())
+}
+
+func TestV2ParserRejectsInvalidActions(t *testing.T) {
+	_, err := V2Parser{}.Parse([]byte("version: 2\nactions:\n  - description: nameless\n"))
+	require.ErrorContains(t, err, "missing role")
+	_, err = V2Parser{}.Parse([]byte("version: 2\nactions:\n  - role: admin\n    ensure: maybe\n"))
+	require.ErrorContains(t, err, "invalid ensure")
+}
+
+func TestV1DocumentsHaveNoMetadata(t *testing.T) {
+	spec, err := DefaultRegistry().Parse([]byte("version: 1\nactions:\n  - role: admin\n    add: [a]\n"))
+	require.NoError(t, err)
+	require.True(t, spec.Header.Empty())
+	require.False(t, spec.Actions[0].HasMetadata())
+}
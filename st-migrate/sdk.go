@@ -3,21 +3,28 @@ package stmigrate
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
 	"github.com/BeardedWonderDev/st-migrate-go/internal/migration"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/schema"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/state"
-	"github.com/BeardedWonderDev/st-migrate-go/internal/state/memory"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/schema"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
+	gomysql "github.com/go-sql-driver/mysql"
+	migrate "github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database"
 	"github.com/golang-migrate/migrate/v4/database/mysql"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/database/sqlite3"
 	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
 
 	// register default file source driver
 	_ "github.com/golang-migrate/migrate/v4/source/file"
@@ -26,8 +33,67 @@ import (
 // Runner is the public API surface for applying migrations.
 type Runner struct {
 	inner *migration.Runner
+	// src stays open while seed files and _defs.yaml are read through it.
+	src source.Driver
 }
 
+// StatusReport lists every migration known to the source or the store.
+type StatusReport = migration.StatusReport
+
+// MigrationStatus describes a single migration within a StatusReport.
+type MigrationStatus = migration.MigrationStatus
+
+// Migration states reported in MigrationStatus.State.
+const (
+	StateApplied           = migration.StateApplied
+	StatePending           = migration.StatePending
+	StateMissingFromSource = migration.StateMissingFromSource
+)
+
+// Protection lists glob patterns for roles and permissions that migrations must never
+// delete or remove.
+type Protection = migration.Protection
+
+// RunReport summarizes the migrations executed by Up, Down or Migrate.
+// It is returned even when the run fails part way.
+type RunReport = migration.RunReport
+
+// RunEntry records the outcome of a single migration within a RunReport.
+type RunEntry = migration.RunEntry
+
+// Migration directions reported in RunEntry.Direction.
+const (
+	DirectionUp   = migration.DirectionUp
+	DirectionDown = migration.DirectionDown
+)
+
+// HistoryEntry is an applied migration as recorded by the state store.
+type HistoryEntry = migration.HistoryEntry
+
+// Header is the migration-level metadata (description, author, ticket) of schema v2 documents.
+type Header = schema.Header
+
+// Seed is a seed action's reference to a CSV/JSON file of user role assignments.
+type Seed = schema.Seed
+
+// Rename is a rename action moving a role, its permissions and its users to a new name.
+type Rename = schema.Rename
+
+// Plan is the simulated outcome of moving to a target version.
+type Plan = migration.Plan
+
+// PlanStep describes one migration within a Plan.
+type PlanStep = migration.PlanStep
+
+// RoundTripReport lists, per migration, whether its down file inverts its up file.
+type RoundTripReport = migration.RoundTripReport
+
+// RoundTripResult is the round-trip outcome for a single migration.
+type RoundTripResult = migration.RoundTripResult
+
+// DriftReport describes differences between the migration history and the live backend.
+type DriftReport = migration.DriftReport
+
 var defaultExecutorFactory = func() executor.Executor {
 	return executor.NewSuperTokensExecutor()
 }
@@ -42,7 +108,7 @@ func SetDefaultExecutorFactory(f func() executor.Executor) {
 // New constructs a Runner using the provided configuration.
 // If Store is nil, an in-memory store is used. If Executor is nil, SuperTokens is used.
 // If Registry is nil, the default schema registry is used.
-func New(cfg Config) (*Runner, error) {
+func New(cfg Config) (runner *Runner, err error) {
 	reg := cfg.Registry
 	if reg == nil {
 		reg = schema.DefaultRegistry()
@@ -58,10 +124,23 @@ func New(cfg Config) (*Runner, error) {
 		logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
 	}
 
-	store, err := resolveStore(cfg, logger)
+	st, err := resolveStore(cfg, logger)
 	if err != nil {
 		return nil, err
 	}
+	// the runner owns the store; close it when no runner is returned
+	defer func() {
+		if err != nil {
+			if cerr := st.Close(); cerr != nil {
+				logger.Warn("close store", slog.Any("err", cerr))
+			}
+		}
+	}()
+
+	if err := cfg.Protection.Validate(); err != nil {
+		logger.Error("invalid protection", slog.Any("err", err))
+		return nil, err
+	}
 
 	sourceURL := cfg.SourceURL
 	if sourceURL == "" {
@@ -69,27 +148,121 @@ func New(cfg Config) (*Runner, error) {
 		logger.Debug("no source url provided; using default", slog.String("source", sourceURL))
 	}
 
-	src, err := source.Open(sourceURL)
+	src, err := openSource(cfg, sourceURL)
 	if err != nil {
 		logger.Error("open source", slog.String("source", sourceURL), slog.Any("err", err))
 		return nil, fmt.Errorf("open source %s: %w", sourceURL, err)
 	}
 	migrations, err := migration.LoadAll(src, logger)
-	src.Close()
 	if err != nil {
+		src.Close()
 		logger.Error("load migrations", slog.String("source", sourceURL), slog.Any("err", err))
 		return nil, fmt.Errorf("load migrations: %w", err)
 	}
+	if err := migration.RenderVars(migrations, cfg.Vars, cfg.StrictVars, logger); err != nil {
+		src.Close()
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	seedFS := cfg.SeedFS
+	if seedFS == nil {
+		seedFS = migration.SourceFiles(src)
+	}
+	if seedFS == nil {
+		// nothing else is read from the source after loading
+		src.Close()
+		src = nil
+	}
+	defs, err := migration.LoadDefs(seedFS)
+	if err != nil {
+		closeSource(src)
+		logger.Error("load definitions", slog.String("source", sourceURL), slog.Any("err", err))
+		return nil, fmt.Errorf("load definitions: %w", err)
+	}
+	if err := migration.ExpandDefs(migrations, defs, logger); err != nil {
+		closeSource(src)
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
 
 	logger.Info("constructed runner",
 		slog.String("source", sourceURL),
 		slog.Bool("dry_run", cfg.DryRun),
 		slog.String("executor", fmt.Sprintf("%T", exec)),
-		slog.String("store", fmt.Sprintf("%T", store)),
+		slog.String("store", fmt.Sprintf("%T", st)),
 	)
 
-	r := migration.NewRunner(store, exec, reg, logger, cfg.DryRun, migrations)
-	return &Runner{inner: r}, nil
+	r := migration.NewRunner(st, exec, reg, logger, cfg.DryRun, migrations)
+	r.SetProtection(cfg.Protection, cfg.AllowProtected)
+	r.SetForce(cfg.Force)
+	r.SetSeedFS(seedFS)
+	return &Runner{inner: r, src: src}, nil
+}
+
+// openSource returns cfg.Source or opens sourceURL. file:// sources are extended to read the
+// files stored next to the migrations.
+func openSource(cfg Config, sourceURL string) (source.Driver, error) {
+	if cfg.Source != nil {
+		return cfg.Source, nil
+	}
+	src, err := source.Open(sourceURL)
+	if err != nil {
+		return nil, err
+	}
+	if files := fileSourceFS(sourceURL); files != nil {
+		return migration.WithFiles(src, files), nil
+	}
+	return src, nil
+}
+
+// NewFSSource returns a source reading migrations from dir in fsys (for example an embed.FS),
+// like golang-migrate's iofs driver. Seed files and _defs.yaml are read from the same
+// directory, so it can be passed as Config.Source without setting SeedFS.
+func NewFSSource(fsys fs.FS, dir string) (source.Driver, error) {
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := fs.Sub(fsys, dir)
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return migration.WithFiles(src, files), nil
+}
+
+// FileSource is implemented by source drivers that can also read the files stored next to
+// their migrations; seed files and _defs.yaml are then read through the source.
+type FileSource = migration.FileSource
+
+func closeSource(src source.Driver) {
+	if src != nil {
+		src.Close()
+	}
+}
+
+// fileSourceFS opens the directory of a file:// source URL, resolved the way the
+// golang-migrate file driver resolves it. Other sources return nil.
+func fileSourceFS(sourceURL string) fs.FS {
+	u, err := url.Parse(sourceURL)
+	if err != nil || u.Scheme != "file" {
+		return nil
+	}
+	p := filePath(u)
+	if p == "" {
+		p = "."
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return nil
+	}
+	return os.DirFS(abs)
+}
+
+// filePath returns the directory of a file:// URL as the golang-migrate file driver reads it.
+func filePath(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return u.Host + u.Path
 }
 
 const defaultMigrationsTable = "st_schema_migrations"
@@ -108,11 +281,11 @@ func NewWithWrappedDriver(cfg Config, driver database.Driver) (*Runner, error) {
 	if driver == nil {
 		return nil, fmt.Errorf("driver is nil")
 	}
-	cfg.Store = state.NewMigrateAdapter(driver)
+	cfg.Store = store.NewMigrateAdapter(driver)
 	return New(cfg)
 }
 
-func resolveStore(cfg Config, logger *slog.Logger) (state.Store, error) {
+func resolveStore(cfg Config, logger *slog.Logger) (store.Store, error) {
 	if cfg.Store != nil {
 		return cfg.Store, nil
 	}
@@ -124,20 +297,26 @@ func resolveStore(cfg Config, logger *slog.Logger) (state.Store, error) {
 		if table == "" {
 			table = defaultMigrationsTable
 		}
-		store, err := buildStoreFromDB(strings.ToLower(cfg.DBDriver), cfg.DB, table, cfg.SkipCloseDB, logger)
+		st, err := buildStoreFromDB(strings.ToLower(cfg.DBDriver), cfg.DB, table, cfg.SkipCloseDB, logger)
 		if err != nil {
 			return nil, err
 		}
-		return store, nil
+		return st, nil
 	}
 	return memory.New(), nil
 }
 
-func buildStoreFromDB(driverName string, db *sql.DB, table string, skipClose bool, logger *slog.Logger) (state.Store, error) {
+// buildStoreFromDB keeps the migration state in table and named values (seed checkpoints,
+// permission snapshots) in table_values.
+func buildStoreFromDB(driverName string, db *sql.DB, table string, skipClose bool, logger *slog.Logger) (store.Store, error) {
 	if db == nil {
 		return nil, fmt.Errorf("database handle is nil")
 	}
 	ctx := context.Background()
+	values, err := store.NewSQLValues(db, driverName, table+"_values")
+	if err != nil {
+		return nil, err
+	}
 
 	switch driverName {
 	case "postgres", "postgresql":
@@ -151,7 +330,7 @@ func buildStoreFromDB(driverName string, db *sql.DB, table string, skipClose boo
 			logger.Error("create postgres driver", slog.Any("err", err))
 			return nil, fmt.Errorf("create postgres driver: %w", err)
 		}
-		return state.NewMigrateAdapter(drv), nil
+		return store.NewMigrateValueAdapter(drv, values), nil
 	case "mysql":
 		conn, err := db.Conn(ctx)
 		if err != nil {
@@ -163,38 +342,179 @@ func buildStoreFromDB(driverName string, db *sql.DB, table string, skipClose boo
 			logger.Error("create mysql driver", slog.Any("err", err))
 			return nil, fmt.Errorf("create mysql driver: %w", err)
 		}
-		return state.NewMigrateAdapter(drv), nil
+		return store.NewMigrateValueAdapter(drv, values), nil
 	case "sqlite3":
 		drv, err := sqlite3.WithInstance(db, &sqlite3.Config{MigrationsTable: table})
 		if err != nil {
 			logger.Error("create sqlite driver", slog.Any("err", err))
 			return nil, fmt.Errorf("create sqlite driver: %w", err)
 		}
-		store := state.NewMigrateAdapter(drv)
+		adapter := store.NewMigrateValueAdapter(drv, values)
+
+		return store.WrapNoClose(adapter), nil
+	}
+	return nil, fmt.Errorf("unsupported driver %q", driverName)
+}
 
-		return state.WrapNoClose(store), nil
-	default:
-		return nil, fmt.Errorf("unsupported driver %q", driverName)
+// defaultDriverMigrationsTable is the table golang-migrate's drivers keep their state in
+// when the URL does not name one.
+const defaultDriverMigrationsTable = "schema_migrations"
+
+// OpenDatabaseStore opens the state store for a golang-migrate database URL, keeping the
+// migration state in table (the driver's default when empty). For postgres, mysql and
+// sqlite3 URLs, named values (seed checkpoints, permission snapshots, rename markers) are
+// kept in table_values through a second connection to the same database; other drivers
+// get a store without them. Closing the store closes both connections.
+func OpenDatabaseStore(databaseURL, table string, logger *slog.Logger) (store.Store, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	dbURL, err := DatabaseURL(databaseURL, table)
+	if err != nil {
+		return nil, err
+	}
+	drv, err := database.Open(dbURL)
+	if err != nil {
+		logger.Error("open database driver", slog.Any("err", err))
+		return nil, fmt.Errorf("open database driver: %w", err)
 	}
+	driverName, dsn, err := sqlDataSource(dbURL)
+	if err != nil {
+		logger.Warn("database store cannot keep values", slog.Any("err", err))
+		return store.NewMigrateAdapter(drv), nil
+	}
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		drv.Close()
+		logger.Error("open values connection", slog.String("driver", driverName), slog.Any("err", err))
+		return nil, fmt.Errorf("open values connection: %w", err)
+	}
+	values, err := store.NewSQLValues(db, driverName, migrationsTableOf(dbURL)+"_values")
+	if err != nil {
+		drv.Close()
+		db.Close()
+		return nil, err
+	}
+	return &databaseStore{MigrateValueAdapter: store.NewMigrateValueAdapter(drv, values), db: db}, nil
 }
 
-func (r *Runner) Up(ctx context.Context, target *uint) error {
+// databaseStore is the store OpenDatabaseStore opens; it owns the values connection.
+type databaseStore struct {
+	*store.MigrateValueAdapter
+	db *sql.DB
+}
+
+func (s *databaseStore) Close() error {
+	return errors.Join(s.MigrateValueAdapter.Close(), s.db.Close())
+}
+
+// sqlDataSource returns the database/sql driver name and data source name for a golang-migrate
+// URL, without the x- parameters only the migrate drivers understand.
+func sqlDataSource(dbURL string) (driverName, dsn string, err error) {
+	scheme, rest, _ := strings.Cut(dbURL, "://")
+	switch scheme {
+	case "postgres", "postgresql":
+		u, err := url.Parse(dbURL)
+		if err != nil {
+			return "", "", fmt.Errorf("parse database url: %w", err)
+		}
+		return "postgres", migrate.FilterCustomQuery(u).String(), nil
+	case "mysql":
+		cfg, err := gomysql.ParseDSN(rest)
+		if err != nil {
+			return "", "", fmt.Errorf("parse database url: %w", err)
+		}
+		for name := range cfg.Params {
+			if strings.HasPrefix(name, "x-") {
+				delete(cfg.Params, name)
+			}
+		}
+		return "mysql", cfg.FormatDSN(), nil
+	case "sqlite3":
+		u, err := url.Parse(dbURL)
+		if err != nil {
+			return "", "", fmt.Errorf("parse database url: %w", err)
+		}
+		return "sqlite3", strings.TrimPrefix(migrate.FilterCustomQuery(u).String(), "sqlite3://"), nil
+	}
+	return "", "", fmt.Errorf("unsupported driver %q", scheme)
+}
+
+// migrationsTableOf returns the x-migrations-table a database URL names, or the drivers' default.
+func migrationsTableOf(dbURL string) string {
+	if i := strings.LastIndex(dbURL, "?"); i >= 0 {
+		if q, err := url.ParseQuery(dbURL[i+1:]); err == nil && q.Get("x-migrations-table") != "" {
+			return q.Get("x-migrations-table")
+		}
+	}
+	return defaultDriverMigrationsTable
+}
+
+// Up applies pending migrations up to the optional target and reports what ran.
+func (r *Runner) Up(ctx context.Context, target *uint) (*RunReport, error) {
 	return r.inner.Up(ctx, target)
 }
 
-func (r *Runner) Down(ctx context.Context, steps int) error {
+// UpSteps applies the next steps pending migrations and reports what ran.
+func (r *Runner) UpSteps(ctx context.Context, steps int) (*RunReport, error) {
+	return r.inner.UpSteps(ctx, steps)
+}
+
+// Redo rolls back the latest applied migration and applies it again.
+func (r *Runner) Redo(ctx context.Context) (*RunReport, error) {
+	return r.inner.Redo(ctx)
+}
+
+// Reset rolls back every applied migration, leaving the store at version 0.
+func (r *Runner) Reset(ctx context.Context) (*RunReport, error) {
+	return r.inner.Reset(ctx)
+}
+
+// Down rolls back the given number of migrations and reports what ran.
+func (r *Runner) Down(ctx context.Context, steps int) (*RunReport, error) {
 	return r.inner.Down(ctx, steps)
 }
 
-func (r *Runner) Status(ctx context.Context) (int, []uint, error) {
+// Status reports the current version, dirty flag and the state of every known migration.
+func (r *Runner) Status(ctx context.Context) (*StatusReport, error) {
 	return r.inner.Status(ctx)
 }
 
+// History lists applied migrations in the order they were applied.
+// The store must keep history (the file and memory stores do).
+func (r *Runner) History(ctx context.Context) ([]HistoryEntry, error) {
+	return r.inner.History(ctx)
+}
+
 func (r *Runner) Close() error {
+	if r.src != nil {
+		if err := r.src.Close(); err != nil {
+			slog.Warn("close source", slog.Any("err", err))
+		}
+	}
 	return r.inner.Close()
 }
 
-// Migrate moves to the target version, applying up or down as needed.
-func (r *Runner) Migrate(ctx context.Context, target uint) error {
+// Migrate moves to the target version, applying up or down as needed, and reports what ran.
+func (r *Runner) Migrate(ctx context.Context, target uint) (*RunReport, error) {
 	return r.inner.Migrate(ctx, target)
 }
+
+// Drift compares the roles/permissions implied by applied migrations with the live backend.
+// The executor must be able to read live state (the default SuperTokens executor can).
+func (r *Runner) Drift(ctx context.Context) (*DriftReport, error) {
+	return r.inner.Drift(ctx)
+}
+
+// Plan simulates moving to the target version (latest when nil) against an in-memory model
+// of SuperTokens roles, without calling the executor or mutating state.
+func (r *Runner) Plan(ctx context.Context, target *uint) (*Plan, error) {
+	return r.inner.Plan(ctx, target)
+}
+
+// RoundTrip replays every migration through a simulated backend, applying up, down and up
+// again per version, and reports versions whose down file is not the inverse of the up file.
+// It never calls the executor or touches the state store.
+func (r *Runner) RoundTrip(ctx context.Context) (*RoundTripReport, error) {
+	return r.inner.RoundTrip(ctx)
+}
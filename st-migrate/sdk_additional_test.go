@@ -32,10 +32,10 @@ func TestNewWithWrappedDriverSuccess(t *testing.T) {
 	require.NoError(t, err)
 	t.Cleanup(func() { _ = r.Close() })
 
-	current, pending, err := r.Status(context.Background())
+	report, err := r.Status(context.Background())
 	require.NoError(t, err)
-	require.Equal(t, 0, current)
-	require.NotEmpty(t, pending)
+	require.Equal(t, 0, report.Current)
+	require.NotEmpty(t, report.Pending())
 }
 
 func TestNewErrorsWhenDBDriverWithoutDB(t *testing.T) {
@@ -2,10 +2,11 @@ package stmigrate
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
 	"github.com/stretchr/testify/require"
 )
 
@@ -18,22 +19,63 @@ func TestSDKUpDownStatus(t *testing.T) {
 	require.NoError(t, err)
 
 	ctx := context.Background()
-	current, pending, err := r.Status(ctx)
+	report, err := r.Status(ctx)
 	require.NoError(t, err)
-	require.Equal(t, 0, current)
-	require.ElementsMatch(t, []uint{1, 2}, pending)
+	require.Equal(t, 0, report.Current)
+	require.ElementsMatch(t, []uint{1, 2}, report.Pending())
 
-	require.NoError(t, r.Up(ctx, nil))
-	current, pending, err = r.Status(ctx)
+	_, err = r.Up(ctx, nil)
 	require.NoError(t, err)
-	require.Equal(t, 2, current)
-	require.Empty(t, pending)
+	report, err = r.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, report.Current)
+	require.Empty(t, report.Pending())
 
-	require.NoError(t, r.Down(ctx, 1))
-	current, pending, err = r.Status(ctx)
+	_, err = r.Down(ctx, 1)
+	require.NoError(t, err)
+	report, err = r.Status(ctx)
 	require.NoError(t, err)
-	require.Equal(t, 1, current)
-	require.ElementsMatch(t, []uint{2}, pending)
+	require.Equal(t, 1, report.Current)
+	require.ElementsMatch(t, []uint{2}, report.Pending())
 
 	require.NoError(t, r.Close())
 }
+
+func TestSDKDriftDetectsLiveChanges(t *testing.T) {
+	exec := executor.NewMock()
+	source := "file://" + filepath.Join("..", "testdata", "migrations")
+	r, err := New(Config{SourceURL: source, Executor: exec})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = r.Close() })
+
+	ctx := context.Background()
+	_, err = r.Up(ctx, nil)
+	require.NoError(t, err)
+	report, err := r.Drift(ctx)
+	require.NoError(t, err)
+	require.False(t, report.HasDrift())
+
+	exec.Live["dashboard:role"] = []string{"x"}
+	report, err = r.Drift(ctx)
+	require.NoError(t, err)
+	require.True(t, report.HasDrift())
+	require.Equal(t, []string{"dashboard:role"}, report.ExtraRoles)
+}
+
+func TestSDKExpandsSharedPermissionSets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "_defs.yaml"), []byte("permission_sets:\n  billing_all: [billing:read, billing:write]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_billing.up.yaml"), []byte("version: 1\nactions:\n  - role: billing\n    add: [$billing_all]\n"), 0o644))
+	exec := executor.NewMock()
+
+	r, err := New(Config{SourceURL: "file://" + dir, Executor: exec})
+	require.NoError(t, err)
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"billing:read", "billing:write"}, exec.Live["billing"])
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0002_typo.up.yaml"), []byte("version: 1\nactions:\n  - role: billing\n    remove: [$biling_all]\n"), 0o644))
+	_, err = New(Config{SourceURL: "file://" + dir, Executor: exec})
+	require.ErrorIs(t, err, ErrParse)
+	require.ErrorContains(t, err, "undefined permission set $biling_all")
+}
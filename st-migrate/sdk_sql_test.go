@@ -1,14 +1,17 @@
 package stmigrate
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"errors"
 	"io"
 	"log/slog"
+	"path/filepath"
 	"sync"
 	"testing"
 
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/require"
 )
@@ -80,3 +83,56 @@ func TestBuildStoreFromMySQLConnError(t *testing.T) {
 	_, err := buildStoreFromDB("mysql", db, "schema_migrations", false, logger)
 	require.Error(t, err)
 }
+
+func TestBuildStoreFromSQLiteKeepsValues(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	st, err := buildStoreFromDB("sqlite3", db, "schema_migrations", false, logger)
+	require.NoError(t, err)
+	values, ok := st.(store.ValueStore)
+	require.True(t, ok)
+	ctx := context.Background()
+	require.NoError(t, values.SetValue(ctx, "seed:assign:users.csv", "{}"))
+	v, ok, err := values.Value(ctx, "seed:assign:users.csv")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "{}", v)
+}
+
+func TestOpenDatabaseStoreKeepsValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+	st, err := OpenDatabaseStore("sqlite3://"+path, "auth_migrations", nil)
+	require.NoError(t, err)
+	values, ok := st.(store.ValueStore)
+	require.True(t, ok, "database stores checkpoint seeds, renames and permission snapshots")
+	ctx := context.Background()
+	require.NoError(t, st.SetVersion(ctx, 3, false))
+	require.NoError(t, values.SetValue(ctx, "seed:1:assign:users.csv", "{}"))
+	require.NoError(t, st.Close())
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	var data string
+	require.NoError(t, db.QueryRow("SELECT data FROM auth_migrations_values WHERE name = ?", "seed:1:assign:users.csv").Scan(&data))
+	require.Equal(t, "{}", data)
+}
+
+func TestSQLDataSourceDropsMigrateParameters(t *testing.T) {
+	cases := map[string][2]string{
+		"postgres://u:p@db:5432/app?sslmode=disable&x-migrations-table=m": {"postgres", "postgres://u:p@db:5432/app?sslmode=disable"},
+		"mysql://u:p@tcp(db:3306)/app?x-migrations-table=m":               {"mysql", "u:p@tcp(db:3306)/app"},
+		"sqlite3:///var/state.db?x-migrations-table=m":                    {"sqlite3", "/var/state.db"},
+	}
+	for in, want := range cases {
+		driverName, dsn, err := sqlDataSource(in)
+		require.NoError(t, err, in)
+		require.Equal(t, want[0], driverName, in)
+		require.Equal(t, want[1], dsn, in)
+	}
+	_, _, err := sqlDataSource("stub://")
+	require.ErrorContains(t, err, `unsupported driver "stub"`)
+}
@@ -5,8 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 
-	"github.com/BeardedWonderDev/st-migrate-go/internal/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store/memory"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,6 +18,24 @@ func TestNewFailsWithBadSource(t *testing.T) {
 	require.Error(t, err)
 }
 
+// closeRecorder records whether the wrapped store was closed.
+type closeRecorder struct {
+	store.Store
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return c.Store.Close()
+}
+
+func TestNewClosesStoreOnError(t *testing.T) {
+	st := &closeRecorder{Store: memory.New()}
+	_, err := New(Config{SourceURL: "file:///does/not/exist", Store: st, Executor: executor.NewMock()})
+	require.Error(t, err)
+	require.True(t, st.closed)
+}
+
 func TestSDKMigrateDelegates(t *testing.T) {
 	tmp := t.TempDir()
 	up := filepath.Join(tmp, "0001_test.up.yaml")
@@ -27,7 +48,8 @@ func TestSDKMigrateDelegates(t *testing.T) {
 
 	r, err := New(Config{SourceURL: "file://" + tmp})
 	require.NoError(t, err)
-	require.NoError(t, r.Migrate(context.Background(), 1))
+	_, err = r.Migrate(context.Background(), 1)
+	require.NoError(t, err)
 	require.NoError(t, r.Close())
 }
 
@@ -51,3 +73,39 @@ func TestNewUsesDefaultExecutorFactory(t *testing.T) {
 	require.Equal(t, 1, calls)
 	require.NoError(t, r.Close())
 }
+
+func TestNewReadsSeedsThroughFSSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0001_seed.up.yaml":   {Data: []byte("version: 1\nactions:\n  - role: admin\n  - seed: {file: seeds/users.csv}\n")},
+		"migrations/0001_seed.down.yaml": {Data: []byte("version: 1\nactions:\n  - seed: {file: seeds/users.csv, unassign: true}\n")},
+		"migrations/seeds/users.csv":     {Data: []byte("user_id,role\nu1,admin\n")},
+	}
+	src, err := NewFSSource(fsys, "migrations")
+	require.NoError(t, err)
+	mock := executor.NewMock()
+
+	r, err := New(Config{Source: src, Executor: mock})
+	require.NoError(t, err)
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"u1"}, mock.RoleUsers["admin"])
+	require.NoError(t, r.Close())
+}
+
+func TestNewReadsDefsThroughFSSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/_defs.yaml":          {Data: []byte("permission_sets:\n  docs: [docs:read, docs:write]\n")},
+		"migrations/0001_docs.up.yaml":   {Data: []byte("version: 1\nactions:\n  - role: editor\n    add: [$docs]\n")},
+		"migrations/0001_docs.down.yaml": {Data: []byte("version: 1\nactions:\n  - role: editor\n    ensure: absent\n")},
+	}
+	src, err := NewFSSource(fsys, "migrations")
+	require.NoError(t, err)
+	mock := executor.NewMock()
+
+	r, err := New(Config{Source: src, Executor: mock})
+	require.NoError(t, err)
+	_, err = r.Up(context.Background(), nil)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"docs:read", "docs:write"}, mock.Live["editor"])
+	require.NoError(t, r.Close())
+}
@@ -0,0 +1,36 @@
+// Package stmigratetest provides test helpers for projects that ship st-migrate-go migrations.
+package stmigratetest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	stmigrate "github.com/BeardedWonderDev/st-migrate-go/st-migrate"
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/executor"
+)
+
+// AssertRoundTrip fails the test if any migration in the source does not round-trip,
+// naming each version whose down file is not the inverse of its up file.
+// Migrations are only simulated; no backend or state store is touched.
+func AssertRoundTrip(t testing.TB, sourceURL string) {
+	t.Helper()
+	r, err := stmigrate.New(stmigrate.Config{
+		SourceURL: sourceURL,
+		Executor:  executor.NewMock(),
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("load migrations from %s: %v", sourceURL, err)
+	}
+	defer r.Close()
+
+	report, err := r.RoundTrip(context.Background())
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	for _, res := range report.Failed() {
+		t.Errorf("migration %d (%s) does not round-trip: %s", res.Version, res.Identifier, res.Error)
+	}
+}
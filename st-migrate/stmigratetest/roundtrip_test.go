@@ -0,0 +1,41 @@
+package stmigratetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingT struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recordingT) Helper() {}
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+func (r *recordingT) Fatalf(format string, args ...any) {
+	r.fatal = true
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertRoundTripPassesForFixtures(t *testing.T) {
+	AssertRoundTrip(t, "file://"+filepath.Join("..", "..", "testdata", "migrations"))
+}
+
+func TestAssertRoundTripReportsBrokenVersion(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_grant.up.yaml"), []byte("version: 1\nactions:\n  - role: admin\n    add: [a]\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_grant.down.yaml"), []byte("version: 1\nactions: []\n"), 0o644))
+
+	rec := &recordingT{TB: t}
+	AssertRoundTrip(rec, "file://"+dir)
+	require.False(t, rec.fatal)
+	require.Len(t, rec.errors, 1)
+	require.Contains(t, rec.errors[0], "migration 1 (grant)")
+}
@@ -0,0 +1,10 @@
+package store
+
+import "errors"
+
+var (
+	// ErrLocked signals the store is already locked by another run.
+	ErrLocked = errors.New("state store locked")
+	// ErrNotLocked signals an unlock without a prior lock.
+	ErrNotLocked = errors.New("state store not locked")
+)
@@ -0,0 +1,235 @@
+// Package file implements a JSON file-backed state store.
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+var (
+	// ErrLocked signals the store is already locked; it is store.ErrLocked.
+	ErrLocked = store.ErrLocked
+	// ErrNotLocked signals unlock without prior lock; it is store.ErrNotLocked.
+	ErrNotLocked = store.ErrNotLocked
+)
+
+var (
+	_ store.Store        = (*Store)(nil)
+	_ store.HistoryStore = (*Store)(nil)
+	_ store.ValueStore   = (*Store)(nil)
+)
+
+// Store persists migration state as JSON in a single file.
+type Store struct {
+	path    string
+	lockMu  sync.Mutex
+	locked  bool
+	stateMu sync.Mutex
+}
+
+type state struct {
+	Version int               `json:"version"`
+	Dirty   bool              `json:"dirty"`
+	Applied []store.Record    `json:"applied,omitempty"`
+	Values  map[string]string `json:"values,omitempty"`
+}
+
+// New creates a file-backed store. The path will be created if missing.
+func New(path string) (*Store, error) {
+	if path == "" {
+		path = ".st-migrate/state.json"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		slog.Error("create state directory", slog.String("path", path), slog.Any("err", err))
+		return nil, err
+	}
+	return &Store{path: path}, nil
+}
+
+func (s *Store) Version(_ context.Context) (int, bool, error) {
+	st, err := s.read()
+	if err != nil {
+		slog.Error("read state file", slog.String("path", s.path), slog.Any("err", err))
+		return 0, false, err
+	}
+	return st.Version, st.Dirty, nil
+}
+
+func (s *Store) SetVersion(_ context.Context, version int, dirty bool) error {
+	err := s.update(func(st *state) {
+		st.Version = version
+		st.Dirty = dirty
+	})
+	if err != nil {
+		slog.Error("write state file", slog.String("path", s.path), slog.Int("version", version), slog.Bool("dirty", dirty), slog.Any("err", err))
+		return err
+	}
+	slog.Info("state updated", slog.String("path", s.path), slog.Int("version", version), slog.Bool("dirty", dirty))
+	return nil
+}
+
+func (s *Store) Lock(_ context.Context) error {
+	s.lockMu.Lock()
+	defer s.lockMu.Unlock()
+	if s.locked {
+		slog.Warn("state lock already held", slog.String("path", s.path))
+		return ErrLocked
+	}
+	s.locked = true
+	slog.Debug("state lock acquired", slog.String("path", s.path))
+	return nil
+}
+
+func (s *Store) Unlock(_ context.Context) error {
+	s.lockMu.Lock()
+	defer s.lockMu.Unlock()
+	if !s.locked {
+		slog.Warn("state unlock requested when not locked", slog.String("path", s.path))
+		return ErrNotLocked
+	}
+	s.locked = false
+	slog.Debug("state lock released", slog.String("path", s.path))
+	return nil
+}
+
+func (s *Store) Close() error { return nil }
+
+func (s *Store) RecordApplied(_ context.Context, rec store.Record) error {
+	err := s.update(func(st *state) {
+		kept := make([]store.Record, 0, len(st.Applied)+1)
+		for _, r := range st.Applied {
+			if r.Version != rec.Version {
+				kept = append(kept, r)
+			}
+		}
+		st.Applied = sortRecords(append(kept, rec))
+	})
+	if err != nil {
+		slog.Error("record applied migration", slog.String("path", s.path), slog.Uint64("version", uint64(rec.Version)), slog.Any("err", err))
+	}
+	return err
+}
+
+func (s *Store) RemoveApplied(_ context.Context, version uint) error {
+	err := s.update(func(st *state) {
+		kept := make([]store.Record, 0, len(st.Applied))
+		for _, r := range st.Applied {
+			if r.Version != version {
+				kept = append(kept, r)
+			}
+		}
+		st.Applied = kept
+	})
+	if err != nil {
+		slog.Error("remove applied migration", slog.String("path", s.path), slog.Uint64("version", uint64(version)), slog.Any("err", err))
+	}
+	return err
+}
+
+func (s *Store) History(_ context.Context) ([]store.Record, error) {
+	st, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return sortRecords(st.Applied), nil
+}
+
+func (s *Store) Value(_ context.Context, key string) (string, bool, error) {
+	st, err := s.read()
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := st.Values[key]
+	return value, ok, nil
+}
+
+func (s *Store) SetValue(_ context.Context, key, value string) error {
+	err := s.update(func(st *state) {
+		if st.Values == nil {
+			st.Values = map[string]string{}
+		}
+		st.Values[key] = value
+	})
+	if err != nil {
+		slog.Error("store value", slog.String("path", s.path), slog.String("key", key), slog.Any("err", err))
+	}
+	return err
+}
+
+func (s *Store) DeleteValue(_ context.Context, key string) error {
+	err := s.update(func(st *state) {
+		delete(st.Values, key)
+	})
+	if err != nil {
+		slog.Error("delete value", slog.String("path", s.path), slog.String("key", key), slog.Any("err", err))
+	}
+	return err
+}
+
+func sortRecords(records []store.Record) []store.Record {
+	out := append([]store.Record{}, records...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// update applies fn to the persisted state under the state mutex.
+func (s *Store) update(fn func(*state)) error {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	st, err := s.readLocked()
+	if err != nil {
+		return err
+	}
+	fn(&st)
+	return s.writeLocked(st)
+}
+
+func (s *Store) read() (state, error) {
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+	return s.readLocked()
+}
+
+func (s *Store) readLocked() (state, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			slog.Debug("state file missing; defaulting", slog.String("path", s.path))
+			return state{Version: 0, Dirty: false}, nil
+		}
+		slog.Error("read state file", slog.String("path", s.path), slog.Any("err", err))
+		return state{}, err
+	}
+	var st state
+	if err := json.Unmarshal(data, &st); err != nil {
+		slog.Error("parse state file", slog.String("path", s.path), slog.Any("err", err))
+		return state{}, err
+	}
+	return st, nil
+}
+
+func (s *Store) writeLocked(st state) error {
+	tmp := s.path + ".tmp"
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		slog.Error("marshal state", slog.Any("err", err))
+		return err
+	}
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		slog.Error("write temp state file", slog.String("tmp", tmp), slog.Any("err", err))
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		slog.Error("rename temp state file", slog.String("tmp", tmp), slog.String("path", s.path), slog.Any("err", err))
+		return err
+	}
+	return nil
+}
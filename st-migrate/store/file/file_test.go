@@ -5,6 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
 
 	"github.com/stretchr/testify/require"
 )
@@ -71,3 +74,56 @@ func TestFileStoreWriteFailsOnDirectoryPath(t *testing.T) {
 	err = store.SetVersion(context.Background(), 1, false)
 	require.Error(t, err)
 }
+
+func TestFileStoreHistorySurvivesVersionUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := New(path)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	applied := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, s.RecordApplied(ctx, store.Record{Version: 2, AppliedAt: applied, Checksum: "bb"}))
+	require.NoError(t, s.RecordApplied(ctx, store.Record{Version: 1, AppliedAt: applied, Checksum: "aa"}))
+	require.NoError(t, s.RecordApplied(ctx, store.Record{Version: 2, AppliedAt: applied, Checksum: "cc"}))
+	require.NoError(t, s.SetVersion(ctx, 2, false))
+
+	reopened, err := New(path)
+	require.NoError(t, err)
+	history, err := reopened.History(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []store.Record{
+		{Version: 1, AppliedAt: applied, Checksum: "aa"},
+		{Version: 2, AppliedAt: applied, Checksum: "cc"},
+	}, history)
+
+	require.NoError(t, reopened.RemoveApplied(ctx, 2))
+	history, err = reopened.History(ctx)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	v, _, err := reopened.Version(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+}
+
+func TestFileStoreValuesPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	s, err := New(path)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, s.SetValue(ctx, "seed:users.csv", "40"))
+	require.NoError(t, s.SetVersion(ctx, 3, true))
+
+	reopened, err := New(path)
+	require.NoError(t, err)
+	value, ok, err := reopened.Value(ctx, "seed:users.csv")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "40", value)
+
+	require.NoError(t, reopened.DeleteValue(ctx, "seed:users.csv"))
+	require.NoError(t, reopened.DeleteValue(ctx, "missing"))
+	_, ok, err = reopened.Value(ctx, "seed:users.csv")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Record describes an applied migration as remembered by a HistoryStore.
+type Record struct {
+	Version   uint      `json:"version"`
+	AppliedAt time.Time `json:"applied_at"`
+	Checksum  string    `json:"checksum"`
+}
+
+// HistoryStore is implemented by stores that remember when each migration was applied
+// and with which content. It is optional; status reporting uses it when available.
+type HistoryStore interface {
+	// RecordApplied stores (or replaces) the record for rec.Version.
+	RecordApplied(ctx context.Context, rec Record) error
+	// RemoveApplied forgets the record for a rolled-back version.
+	RemoveApplied(ctx context.Context, version uint) error
+	// History returns records in ascending version order.
+	History(ctx context.Context) ([]Record, error)
+}
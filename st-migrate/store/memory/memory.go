@@ -0,0 +1,132 @@
+// Package memory implements an in-memory state store, mainly for tests and dry runs.
+package memory
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+)
+
+var (
+	// ErrLocked indicates the store is already locked; it is store.ErrLocked.
+	ErrLocked = store.ErrLocked
+	// ErrNotLocked indicates an unlock was attempted without a lock; it is store.ErrNotLocked.
+	ErrNotLocked = store.ErrNotLocked
+)
+
+var (
+	_ store.Store        = (*Store)(nil)
+	_ store.HistoryStore = (*Store)(nil)
+	_ store.ValueStore   = (*Store)(nil)
+)
+
+// Store is an in-memory implementation of the state store.
+type Store struct {
+	mu      sync.Mutex
+	locked  bool
+	version int
+	dirty   bool
+	applied map[uint]store.Record
+	values  map[string]string
+}
+
+// New creates a new in-memory store with version 0.
+func New() *Store {
+	return &Store{}
+}
+
+func (s *Store) Version(_ context.Context) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version, s.dirty, nil
+}
+
+func (s *Store) SetVersion(_ context.Context, version int, dirty bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+	s.dirty = dirty
+	slog.Info("state updated (memory)", slog.Int("version", version), slog.Bool("dirty", dirty))
+	return nil
+}
+
+func (s *Store) Lock(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked {
+		slog.Warn("state lock already held (memory)")
+		return ErrLocked
+	}
+	s.locked = true
+	slog.Debug("state lock acquired (memory)")
+	return nil
+}
+
+func (s *Store) Unlock(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.locked {
+		slog.Warn("state unlock requested when not locked (memory)")
+		return ErrNotLocked
+	}
+	s.locked = false
+	slog.Debug("state lock released (memory)")
+	return nil
+}
+
+func (s *Store) Close() error { return nil }
+
+func (s *Store) RecordApplied(_ context.Context, rec store.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.applied == nil {
+		s.applied = map[uint]store.Record{}
+	}
+	s.applied[rec.Version] = rec
+	return nil
+}
+
+func (s *Store) RemoveApplied(_ context.Context, version uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.applied, version)
+	return nil
+}
+
+func (s *Store) History(_ context.Context) ([]store.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]store.Record, 0, len(s.applied))
+	for _, rec := range s.applied {
+		out = append(out, rec)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func (s *Store) Value(_ context.Context, key string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *Store) SetValue(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = map[string]string{}
+	}
+	s.values[key] = value
+	return nil
+}
+
+func (s *Store) DeleteValue(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BeardedWonderDev/st-migrate-go/st-migrate/store"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreVersionAndLocks(t *testing.T) {
+	store := New()
+	v, dirty, err := store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, v)
+	require.False(t, dirty)
+
+	require.NoError(t, store.SetVersion(context.Background(), 2, true))
+	v, dirty, err = store.Version(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, v)
+	require.True(t, dirty)
+
+	require.NoError(t, store.Lock(context.Background()))
+	require.ErrorIs(t, store.Lock(context.Background()), ErrLocked)
+	require.NoError(t, store.Unlock(context.Background()))
+	require.ErrorIs(t, store.Unlock(context.Background()), ErrNotLocked)
+
+	require.NoError(t, store.Close())
+}
+
+func TestMemoryStoreHistory(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	require.NoError(t, s.RecordApplied(ctx, store.Record{Version: 3, Checksum: "c"}))
+	require.NoError(t, s.RecordApplied(ctx, store.Record{Version: 1, Checksum: "a"}))
+	history, err := s.History(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []store.Record{{Version: 1, Checksum: "a"}, {Version: 3, Checksum: "c"}}, history)
+
+	require.NoError(t, s.RemoveApplied(ctx, 3))
+	history, err = s.History(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []store.Record{{Version: 1, Checksum: "a"}}, history)
+}
+
+func TestMemoryStoreValues(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	_, ok, err := s.Value(ctx, "k")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.NoError(t, s.SetValue(ctx, "k", "v"))
+	value, ok, err := s.Value(ctx, "k")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "v", value)
+	require.NoError(t, s.DeleteValue(ctx, "k"))
+	_, ok, _ = s.Value(ctx, "k")
+	require.False(t, ok)
+}
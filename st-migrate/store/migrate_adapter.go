@@ -1,12 +1,16 @@
-package state
+package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 
 	"github.com/golang-migrate/migrate/v4/database"
 )
 
+var _ Store = (*MigrateAdapter)(nil)
+
 // MigrateAdapter wraps a golang-migrate database.Driver to satisfy the Store interface.
 // It delegates version/lock operations and ignores the Run/Drop methods since this
 // package handles execution outside of SQL.
@@ -43,6 +47,9 @@ func (m *MigrateAdapter) SetVersion(_ context.Context, version int, dirty bool)
 func (m *MigrateAdapter) Lock(_ context.Context) error {
 	if err := m.driver.Lock(); err != nil {
 		slog.Error("driver lock", slog.Any("err", err))
+		if errors.Is(err, database.ErrLocked) {
+			return fmt.Errorf("%w: %w", ErrLocked, err)
+		}
 		return err
 	}
 	slog.Debug("state lock acquired (migrate driver)")
@@ -52,6 +59,9 @@ func (m *MigrateAdapter) Lock(_ context.Context) error {
 func (m *MigrateAdapter) Unlock(_ context.Context) error {
 	if err := m.driver.Unlock(); err != nil {
 		slog.Error("driver unlock", slog.Any("err", err))
+		if errors.Is(err, database.ErrNotLocked) {
+			return fmt.Errorf("%w: %w", ErrNotLocked, err)
+		}
 		return err
 	}
 	slog.Debug("state lock released (migrate driver)")
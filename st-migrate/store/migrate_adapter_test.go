@@ -1,4 +1,4 @@
-package state
+package store
 
 import (
 	"context"
@@ -54,9 +54,13 @@ func TestMigrateAdapterDelegates(t *testing.T) {
 	require.True(t, dirty)
 
 	require.NoError(t, adapter.Lock(context.Background()))
-	require.ErrorIs(t, adapter.Lock(context.Background()), database.ErrLocked)
+	err = adapter.Lock(context.Background())
+	require.ErrorIs(t, err, database.ErrLocked)
+	require.ErrorIs(t, err, ErrLocked)
 	require.NoError(t, adapter.Unlock(context.Background()))
-	require.ErrorIs(t, adapter.Unlock(context.Background()), database.ErrNotLocked)
+	err = adapter.Unlock(context.Background())
+	require.ErrorIs(t, err, database.ErrNotLocked)
+	require.ErrorIs(t, err, ErrNotLocked)
 
 	require.NoError(t, adapter.Close())
 	require.Equal(t, 1, stub.closeCalls)
@@ -1,7 +1,9 @@
-package state
+package store
 
 import "context"
 
+var _ Store = NoCloseStore{}
+
 // NoCloseStore wraps a Store and makes Close a no-op (useful when the underlying
 // driver is shared and should be closed by the caller).
 type NoCloseStore struct {
@@ -26,9 +28,18 @@ func (n NoCloseStore) Unlock(ctx context.Context) error {
 
 func (n NoCloseStore) Close() error { return nil }
 
+// WrapNoClose wraps inner with a no-op Close. A ValueStore stays one.
 func WrapNoClose(inner Store) Store {
 	if inner == nil {
 		return nil
 	}
+	if values, ok := inner.(ValueStore); ok {
+		return noCloseValueStore{NoCloseStore: NoCloseStore{inner: inner}, ValueStore: values}
+	}
 	return NoCloseStore{inner: inner}
 }
+
+type noCloseValueStore struct {
+	NoCloseStore
+	ValueStore
+}
@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/golang-migrate/migrate/v4/database"
+)
+
+var (
+	_ ValueStore = (*SQLValues)(nil)
+	_ ValueStore = (*MigrateValueAdapter)(nil)
+)
+
+// SQLValues is a ValueStore keeping named values in a table of a database/sql database.
+// The table is created on first use.
+type SQLValues struct {
+	db     *sql.DB
+	table  string
+	dollar bool
+
+	mu      sync.Mutex
+	created bool
+}
+
+// NewSQLValues keeps values in table through db. driverName selects the placeholder style:
+// postgres (or postgresql), mysql or sqlite3.
+func NewSQLValues(db *sql.DB, driverName, table string) (*SQLValues, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database handle is nil")
+	}
+	switch driverName {
+	case "postgres", "postgresql":
+		return &SQLValues{db: db, table: table, dollar: true}, nil
+	case "mysql", "sqlite3":
+		return &SQLValues{db: db, table: table}, nil
+	}
+	return nil, fmt.Errorf("unsupported driver %q", driverName)
+}
+
+func (s *SQLValues) Value(ctx context.Context, key string) (string, bool, error) {
+	if err := s.ensureTable(ctx); err != nil {
+		return "", false, err
+	}
+	var value string
+	err := s.db.QueryRowContext(ctx, s.query("SELECT data FROM %s WHERE name = %s", 1), key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		slog.Error("read value", slog.String("table", s.table), slog.String("key", key), slog.Any("err", err))
+		return "", false, fmt.Errorf("read value %s: %w", key, err)
+	}
+	return value, true, nil
+}
+
+func (s *SQLValues) SetValue(ctx context.Context, key, value string) error {
+	if err := s.ensureTable(ctx); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		slog.Error("begin value transaction", slog.String("table", s.table), slog.Any("err", err))
+		return fmt.Errorf("set value %s: %w", key, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, s.query("DELETE FROM %s WHERE name = %s", 1), key); err != nil {
+		slog.Error("replace value", slog.String("table", s.table), slog.String("key", key), slog.Any("err", err))
+		return fmt.Errorf("set value %s: %w", key, err)
+	}
+	if _, err := tx.ExecContext(ctx, s.query("INSERT INTO %s (name, data) VALUES (%s, %s)", 2), key, value); err != nil {
+		slog.Error("write value", slog.String("table", s.table), slog.String("key", key), slog.Any("err", err))
+		return fmt.Errorf("set value %s: %w", key, err)
+	}
+	if err := tx.Commit(); err != nil {
+		slog.Error("commit value", slog.String("table", s.table), slog.String("key", key), slog.Any("err", err))
+		return fmt.Errorf("set value %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *SQLValues) DeleteValue(ctx context.Context, key string) error {
+	if err := s.ensureTable(ctx); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, s.query("DELETE FROM %s WHERE name = %s", 1), key); err != nil {
+		slog.Error("delete value", slog.String("table", s.table), slog.String("key", key), slog.Any("err", err))
+		return fmt.Errorf("delete value %s: %w", key, err)
+	}
+	return nil
+}
+
+func (s *SQLValues) ensureTable(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.created {
+		return nil
+	}
+	stmt := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (name VARCHAR(255) NOT NULL PRIMARY KEY, data TEXT NOT NULL)", s.table)
+	if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+		slog.Error("create values table", slog.String("table", s.table), slog.Any("err", err))
+		return fmt.Errorf("create values table %s: %w", s.table, err)
+	}
+	s.created = true
+	return nil
+}
+
+// query fills the table name and n placeholders into format.
+func (s *SQLValues) query(format string, n int) string {
+	args := []any{s.table}
+	for i := 1; i <= n; i++ {
+		if s.dollar {
+			args = append(args, fmt.Sprintf("$%d", i))
+		} else {
+			args = append(args, "?")
+		}
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// MigrateValueAdapter is a MigrateAdapter that also keeps named values, so migrate driver
+// backed stores can checkpoint seeds and snapshot permissions.
+type MigrateValueAdapter struct {
+	*MigrateAdapter
+	*SQLValues
+}
+
+// NewMigrateValueAdapter constructs a Store backed by a migrate database driver, keeping
+// values through values.
+func NewMigrateValueAdapter(driver database.Driver, values *SQLValues) *MigrateValueAdapter {
+	return &MigrateValueAdapter{MigrateAdapter: NewMigrateAdapter(driver), SQLValues: values}
+}
@@ -0,0 +1,63 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLValuesRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "state.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	values, err := NewSQLValues(db, "sqlite3", "schema_migrations_values")
+	require.NoError(t, err)
+
+	_, ok, err := values.Value(ctx, "seed:assign:users.csv")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, values.SetValue(ctx, "seed:assign:users.csv", `{"rows":10}`))
+	require.NoError(t, values.SetValue(ctx, "seed:assign:users.csv", `{"rows":20}`))
+	v, ok, err := values.Value(ctx, "seed:assign:users.csv")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, `{"rows":20}`, v)
+
+	require.NoError(t, values.DeleteValue(ctx, "seed:assign:users.csv"))
+	require.NoError(t, values.DeleteValue(ctx, "seed:assign:users.csv"))
+	_, ok, err = values.Value(ctx, "seed:assign:users.csv")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestSQLValuesPlaceholders(t *testing.T) {
+	pg, err := NewSQLValues(&sql.DB{}, "postgres", "t")
+	require.NoError(t, err)
+	require.Equal(t, "INSERT INTO t (name, data) VALUES ($1, $2)", pg.query("INSERT INTO %s (name, data) VALUES (%s, %s)", 2))
+	my, err := NewSQLValues(&sql.DB{}, "mysql", "t")
+	require.NoError(t, err)
+	require.Equal(t, "DELETE FROM t WHERE name = ?", my.query("DELETE FROM %s WHERE name = %s", 1))
+
+	_, err = NewSQLValues(&sql.DB{}, "oracle", "t")
+	require.Error(t, err)
+	_, err = NewSQLValues(nil, "mysql", "t")
+	require.Error(t, err)
+}
+
+func TestWrapNoCloseKeepsValueStore(t *testing.T) {
+	values, err := NewSQLValues(&sql.DB{}, "sqlite3", "t")
+	require.NoError(t, err)
+	wrapped := WrapNoClose(NewMigrateValueAdapter(&stubDriver{}, values))
+	_, ok := wrapped.(ValueStore)
+	require.True(t, ok)
+	require.NoError(t, wrapped.Close())
+
+	_, ok = WrapNoClose(&stubStore{}).(ValueStore)
+	require.False(t, ok)
+}
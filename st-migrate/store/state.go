@@ -1,4 +1,6 @@
-package state
+// Package store defines where migration state (current version, dirty flag) is tracked
+// and provides adapters for golang-migrate database drivers.
+package store
 
 import "context"
 
@@ -0,0 +1,15 @@
+package store
+
+import "context"
+
+// ValueStore is implemented by stores that can persist small named values next to the
+// migration state, such as progress checkpoints that let an interrupted seed resume.
+// It is optional; features needing it degrade gracefully when the store lacks it.
+type ValueStore interface {
+	// Value returns the value stored under key and whether it exists.
+	Value(ctx context.Context, key string) (string, bool, error)
+	// SetValue stores (or replaces) the value under key.
+	SetValue(ctx context.Context, key, value string) error
+	// DeleteValue removes key; removing a missing key is not an error.
+	DeleteValue(ctx context.Context, key string) error
+}